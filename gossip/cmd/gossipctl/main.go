@@ -0,0 +1,201 @@
+/*
+* gossip
+* Copyright (C) 2024 Fabio Gaiba and Lukas Heindl
+*
+* This program is free software: you can redistribute it and/or modify
+* it under the terms of the GNU General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* This program is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+* GNU General Public License for more details.
+*
+* You should have received a copy of the GNU General Public License
+* along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+// gossipctl inspects and manages a running gossip node via its admin
+// interface (see the admin_address option).
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"gossip/common"
+	gossip "gossip/main"
+	gs "gossip/strats"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	"github.com/alexflint/go-arg"
+)
+
+type peersCmd struct{}
+
+type disconnectCmd struct {
+	Id string `arg:"positional,required" help:"connection id of the peer (see peers)"`
+}
+
+type connectCmd struct {
+	Addr string `arg:"positional,required" help:"address of the new neighbor, ip:port"`
+}
+
+type cacheCmd struct{}
+
+type modulesCmd struct{}
+
+type loglevelCmd struct {
+	Level *string `arg:"positional" help:"new log level (e.g. DEBUG, INFO, WARN, ERROR), shows the current one if omitted"`
+}
+
+// Arguments read using go-arg https://github.com/alexflint/go-arg
+type ctlArgs struct {
+	Addr       string         `arg:"-a,--addr" default:"127.0.0.1:8001" help:"Address of the admin interface of the node, ip:port"`
+	Token      string         `arg:"-t,--token,env:GOSSIP_ADMIN_TOKEN" help:"Token for changing requests (admin_token of the node)"`
+	Peers      *peersCmd      `arg:"subcommand:peers" help:"list the peers"`
+	Disconnect *disconnectCmd `arg:"subcommand:disconnect" help:"disconnect a peer"`
+	Connect    *connectCmd    `arg:"subcommand:connect" help:"connect to a new neighbor"`
+	Cache      *cacheCmd      `arg:"subcommand:cache" help:"list the cached messages"`
+	Modules    *modulesCmd    `arg:"subcommand:modules" help:"list the registered modules"`
+	Loglevel   *loglevelCmd   `arg:"subcommand:loglevel" help:"show or change the log level"`
+}
+
+func main() {
+	var args ctlArgs
+	p := arg.MustParse(&args)
+	if p.Subcommand() == nil {
+		p.Fail("missing subcommand")
+	}
+
+	if err := run(args); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+// execute the subcommand
+func run(args ctlArgs) error {
+	base := "http://" + args.Addr
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	defer tw.Flush()
+
+	switch {
+	case args.Peers != nil:
+		var peers []gs.PeerInfo
+		if err := call(http.MethodGet, base+"/peers", args.Token, nil, &peers); err != nil {
+			return err
+		}
+		fmt.Fprintln(tw, "ID\tSTATE\tTIMESTAMP\tPOW REQUESTED\tOUTGOING\tRTT\tNODE ID")
+		for _, p := range peers {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%v\t%v\t%s\t%s\n", p.Id, p.State, formatTime(p.Timestamp), p.SentPowReq, p.Outgoing, formatRTT(p.RTT), p.NodeId)
+		}
+
+	case args.Disconnect != nil:
+		return call(http.MethodDelete, base+"/peers/"+url.PathEscape(args.Disconnect.Id), args.Token, nil, nil)
+
+	case args.Connect != nil:
+		return call(http.MethodPost, base+"/peers", args.Token, gossip.AddPeerRequest{Addr: args.Connect.Addr}, nil)
+
+	case args.Cache != nil:
+		var msgs []gs.CachedMessage
+		if err := call(http.MethodGet, base+"/cache", args.Token, nil, &msgs); err != nil {
+			return err
+		}
+		fmt.Fprintln(tw, "QUEUE\tID\tTYPE\tTTL\tSIZE\tSENDER\tRECEIVED")
+		for _, m := range msgs {
+			fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%s\t%s\n", m.Queue, m.MessageId, m.GossipType, m.TTL, m.Size, m.Sender, formatTime(m.Received))
+		}
+
+	case args.Modules != nil:
+		var mods map[common.GossipType][]common.ConnectionId
+		if err := call(http.MethodGet, base+"/modules", args.Token, nil, &mods); err != nil {
+			return err
+		}
+		types := make([]common.GossipType, 0, len(mods))
+		for t := range mods {
+			types = append(types, t)
+		}
+		slices.Sort(types)
+		fmt.Fprintln(tw, "TYPE\tMODULE")
+		for _, t := range types {
+			for _, id := range mods[t] {
+				fmt.Fprintf(tw, "%d\t%s\n", t, id)
+			}
+		}
+
+	case args.Loglevel != nil:
+		var level gossip.LogLevelMessage
+		var err error
+		if args.Loglevel.Level == nil {
+			err = call(http.MethodGet, base+"/loglevel", args.Token, nil, &level)
+		} else {
+			err = call(http.MethodPut, base+"/loglevel", args.Token, gossip.LogLevelMessage{Level: *args.Loglevel.Level}, &level)
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(tw, level.Level)
+	}
+	return nil
+}
+
+// perform a request with body (marshalled to json, if not nil) and
+// unmarshal the response to resp (if not nil). token (if not empty) is sent
+// as bearer token.
+func call(method string, url string, token string, body any, resp any) error {
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(b)
+	}
+	req, err := http.NewRequest(method, url, r)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 300 {
+		msg, _ := io.ReadAll(res.Body)
+		return fmt.Errorf("%s: %s", res.Status, bytes.TrimSpace(msg))
+	}
+	if resp == nil {
+		return nil
+	}
+	return json.NewDecoder(res.Body).Decode(resp)
+}
+
+// format a timestamp for the output, zero timestamps are shown as -
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Format(time.RFC3339)
+}
+
+// format a round trip time for the output, unmeasured ones are shown as -
+func formatRTT(d time.Duration) string {
+	if d == 0 {
+		return "-"
+	}
+	return d.Round(10 * time.Microsecond).String()
+}
@@ -0,0 +1,148 @@
+/*
+* gossip
+* Copyright (C) 2024 Fabio Gaiba and Lukas Heindl
+*
+* This program is free software: you can redistribute it and/or modify
+* it under the terms of the GNU General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* This program is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+* GNU General Public License for more details.
+*
+* You should have received a copy of the GNU General Public License
+* along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+// gossiptrace merges the trace files of multiple nodes (see the trace_file
+// option) and prints the propagation tree of each message.
+package main
+
+import (
+	"fmt"
+	"gossip/internal/trace"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/alexflint/go-arg"
+)
+
+// Arguments read using go-arg https://github.com/alexflint/go-arg
+type traceArgs struct {
+	Msg   []uint16 `arg:"-m,--msg" help:"only show the messages with these ids"`
+	Files []string `arg:"positional,required" help:"trace files of the nodes"`
+}
+
+func main() {
+	var args traceArgs
+	arg.MustParse(&args)
+
+	if err := run(os.Stdout, args); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+// read the trace files and print the propagation of the messages to w
+func run(w io.Writer, args traceArgs) error {
+	var events []trace.Event
+	for _, path := range args.Files {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		evs, err := trace.Read(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", path, err)
+		}
+		events = append(events, evs...)
+	}
+
+	// all nodes which wrote a trace
+	var allNodes []string
+	for _, ev := range events {
+		if !slices.Contains(allNodes, ev.Node) {
+			allNodes = append(allNodes, ev.Node)
+		}
+	}
+	slices.Sort(allNodes)
+
+	for _, p := range trace.Build(events) {
+		if len(args.Msg) > 0 && !slices.Contains(args.Msg, p.MessageId) {
+			continue
+		}
+		printPropagation(w, p, allNodes)
+	}
+	return nil
+}
+
+// print the propagation tree of a message
+func printPropagation(w io.Writer, p *trace.Propagation, allNodes []string) {
+	fmt.Fprintf(w, "message %d (type %d): reached %d of %d nodes\n", p.MessageId, p.GossipType, len(p.Nodes), len(allNodes))
+
+	var start time.Time
+	if len(p.Roots) > 0 {
+		start = p.Roots[0].Time
+	}
+	for _, root := range p.Roots {
+		printNode(w, root, start, "", "")
+	}
+	if len(p.Orphans) > 0 {
+		fmt.Fprintln(w, "received from a peer without trace:")
+		for _, n := range p.Orphans {
+			printNode(w, n, start, "  ", "  ")
+		}
+	}
+	for _, ev := range p.Dropped {
+		fmt.Fprintf(w, "dropped by %s (from %s) %s\n", ev.Node, ev.Peer, offset(ev.Time, start))
+	}
+
+	var missing []string
+	for _, n := range allNodes {
+		if _, ok := p.Nodes[n]; !ok {
+			missing = append(missing, n)
+		}
+	}
+	if len(missing) > 0 {
+		fmt.Fprintf(w, "not reached: %s\n", strings.Join(missing, ", "))
+	}
+	fmt.Fprintln(w)
+}
+
+// print a node of the tree and its children. prefix is printed in front of
+// the node, childPrefix in front of its children.
+func printNode(w io.Writer, n *trace.Node, start time.Time, prefix string, childPrefix string) {
+	validation := string(n.Validation)
+	if n.Kind == trace.Announced {
+		validation = "announced"
+	} else if validation == "" {
+		validation = "not validated"
+	}
+	fmt.Fprintf(w, "%s%s %s ttl %d %s, forwarded %dx", prefix, n.Node, offset(n.Time, start), n.TTL, validation, n.Forwarded)
+	if n.Duplicates > 0 {
+		fmt.Fprintf(w, ", %d duplicates", n.Duplicates)
+	}
+	fmt.Fprintln(w)
+
+	for i, c := range n.Children {
+		if i == len(n.Children)-1 {
+			printNode(w, c, start, childPrefix+"└── ", childPrefix+"    ")
+		} else {
+			printNode(w, c, start, childPrefix+"├── ", childPrefix+"│   ")
+		}
+	}
+}
+
+// format t relative to start
+func offset(t time.Time, start time.Time) string {
+	if start.IsZero() {
+		return t.Format(time.RFC3339Nano)
+	}
+	return "+" + t.Sub(start).String()
+}
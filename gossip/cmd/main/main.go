@@ -19,6 +19,8 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	gossip "gossip/main"
 	"os"
 	"os/signal"
@@ -27,19 +29,40 @@ import (
 
 func main() {
 	// init
-	m := gossip.NewMain()
+	m, err := gossip.NewMain()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
 
 	// run
 	initFin := make(chan error, 1)
 	go m.Run(initFin)
-	err := <-initFin
-	if err != nil {
-		panic(err)
+	if err := <-initFin; err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
 	}
 
+	// reload the configuration on SIGHUP
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			// errors are logged by main
+			_ = m.Reload()
+		}
+	}()
+
 	// teardown
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, syscall.SIGTERM, syscall.SIGINT)
 	<-c
-	m.Close()
+	// a second signal skips the rest of the drain
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-c
+		cancel()
+	}()
+	m.Shutdown(ctx)
+	cancel()
 }
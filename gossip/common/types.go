@@ -121,6 +121,10 @@ type GossipValidation struct {
 	Bitfield  uint16
 	// only for ease of use we extract this from the bitfield on Unmarshal
 	Valid bool
+	// set by main (never sent on the vertical api) if no module is registered
+	// for the type of the message. The message is dropped without rating the
+	// peer it was received from.
+	Unhandled bool
 }
 
 // Convenience function to set the valid flag on this message (sets .valid and adjusts the bitfield)
@@ -138,3 +142,47 @@ func (e GossipValidation) isFromVert() {}
 
 // Mark this type as toStrat
 func (e GossipValidation) isToStrat() {}
+
+// This type represents a GossipCatchup packet in the verticalApi.
+//
+// With this packet a module asks for the messages of DataType which were
+// already received before the module registered. Count limits the amount of
+// messages (0 = no limit), MaxAge the age of the messages in seconds (0 = no
+// limit).
+type GossipCatchup struct {
+	Count    uint16
+	DataType GossipType
+	MaxAge   uint32
+}
+
+// Wrapper for the GossipCatchup message which also includes the module which
+// asked for the messages
+type GossipCatchupRequest struct {
+	Data   GossipCatchup
+	Module *Conn[RegisteredModule]
+}
+
+// Mark this type as fromVert
+func (e GossipCatchupRequest) isFromVert() {}
+
+// Mark this type as toStrat
+func (e GossipCatchupRequest) isToStrat() {}
+
+// Answer of the gossip strategy to a [GossipCatchupRequest], contains the
+// messages which shall be replayed to the module (oldest first)
+type GossipCatchupReply struct {
+	Notifications []GossipNotification
+	Module        *Conn[RegisteredModule]
+}
+
+// Mark this type as fromStrat
+func (e GossipCatchupReply) isFromStrat() {}
+
+// Gossip types for which at least one module is registered, sent to the
+// gossip strategy whenever they change (sorted ascending)
+type GossipSubscriptions struct {
+	DataTypes []GossipType
+}
+
+// Mark this type as toStrat
+func (e GossipSubscriptions) isToStrat() {}
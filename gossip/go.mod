@@ -3,20 +3,20 @@ module gossip
 go 1.22.3
 
 require (
+	capnproto.org/go/capnp/v3 v3.0.1-alpha.2
+	github.com/alexflint/go-arg v1.5.0
+	github.com/jszwec/csvutil v1.10.0
 	github.com/lmittmann/tint v1.0.4
 	github.com/neilotoole/slogt v1.1.0
+	golang.org/x/crypto v0.26.0
+	golang.org/x/exp v0.0.0-20240823005443-9b4947da3948
 	gopkg.in/ini.v1 v1.67.0
 )
 
 require (
-	capnproto.org/go/capnp/v3 v3.0.1-alpha.2 // indirect
-	github.com/alexflint/go-arg v1.5.0 // indirect
 	github.com/alexflint/go-scalar v1.2.0 // indirect
 	github.com/colega/zeropool v0.0.0-20230505084239-6fb4a4f75381 // indirect
-	github.com/jszwec/csvutil v1.10.0 // indirect
 	github.com/stretchr/testify v1.9.0 // indirect
-	golang.org/x/crypto v0.26.0 // indirect
-	golang.org/x/exp v0.0.0-20240823005443-9b4947da3948 // indirect
 	golang.org/x/sync v0.8.0 // indirect
 	golang.org/x/sys v0.23.0 // indirect
 )
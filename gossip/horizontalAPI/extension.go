@@ -0,0 +1,99 @@
+/*
+* gossip
+* Copyright (C) 2024 Fabio Gaiba and Lukas Heindl
+*
+* This program is free software: you can redistribute it and/or modify
+* it under the terms of the GNU General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* This program is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+* GNU General Public License for more details.
+*
+* You should have received a copy of the GNU General Public License
+* along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package horizontalapi
+
+import "fmt"
+
+// Identifier of a registered kind of extension messages. Both peers need to
+// use the same kind for the same message, otherwise the message cannot be
+// decoded.
+type ExtensionKind uint16
+
+// A message of a kind registered via [HorizontalApi.RegisterExtension]. Its
+// encoding is up to the implementation, the horizontalApi only transports the
+// resulting bytes.
+type ExtensionMessage interface {
+	// kind under which the decoder for this message was registered
+	Kind() ExtensionKind
+	// encode the message so that it can be decoded by the registered
+	// decoder
+	MarshalBinary() ([]byte, error)
+}
+
+// An extension message which is control traffic like the PoW handshake (e.g.
+// a keepalive). Control messages are never delayed by the egress limits, see
+// [HorizontalApi.SetEgressLimits].
+type ControlMessage interface {
+	ExtensionMessage
+	// whether this message is control traffic
+	IsControl() bool
+}
+
+// Decodes the bytes of a received extension message. The data passed is owned
+// by the decoder and may be retained.
+type ExtensionDecoder func(data []byte) (ExtensionMessage, error)
+
+// Represents an Extension message from/to the horizontalApi. It wraps a
+// message of a kind which was registered at runtime.
+type Extension struct {
+	Id      ConnectionId
+	Message ExtensionMessage
+}
+
+// mark this type as being sendable via FromHz channels
+func (Extension) canFromHz() {}
+
+// mark this type as being sendable via ToHz channels
+func (Extension) canToHz() {}
+
+// extension messages are only control traffic if they say so (see
+// [ControlMessage])
+func (e Extension) isControl() bool {
+	c, ok := e.Message.(ControlMessage)
+	return ok && c.IsControl()
+}
+
+// Register a new kind of extension messages. Received messages of this kind
+// are decoded with decode and passed as [Extension] on the fromHz channel,
+// messages of other unknown kinds are dropped.
+//
+// Registering the same kind twice results in [ErrExtensionRegistered].
+func (hz *HorizontalApi) RegisterExtension(kind ExtensionKind, decode ExtensionDecoder) error {
+	hz.extensionsMutex.Lock()
+	defer hz.extensionsMutex.Unlock()
+
+	if _, ok := hz.extensions[kind]; ok {
+		return fmt.Errorf("register kind %d: %w", kind, ErrExtensionRegistered)
+	}
+	hz.extensions[kind] = decode
+	return nil
+}
+
+// decode the data of an extension message with the decoder registered for the
+// kind. Returns false if no decoder is registered for this kind.
+func (hz *HorizontalApi) decodeExtension(kind ExtensionKind, data []byte) (ExtensionMessage, bool, error) {
+	hz.extensionsMutex.RLock()
+	decode, ok := hz.extensions[kind]
+	hz.extensionsMutex.RUnlock()
+	if !ok {
+		return nil, false, nil
+	}
+	m, err := decode(data)
+	return m, true, err
+}
@@ -29,9 +29,11 @@ import (
 	"gossip/common"
 	hzTypes "gossip/horizontalAPI/types"
 	"gossip/internal/packetcounter"
+	"gossip/internal/ratelimit"
 	"io"
 	"log/slog"
 	"net"
+	"os"
 	"slices"
 	"sync"
 	"time"
@@ -44,18 +46,32 @@ type ConnectionId string
 
 // store arbitrary data along with the connection it belongs to
 type Conn[T any] struct {
-	Id    ConnectionId
+	Id ConnectionId
+	// local address of the connection (the peer identifies the connection
+	// by it)
+	Local ConnectionId
 	Data  T
 	Ctx   context.Context
 	Cfunc context.CancelFunc
 }
 
+// Used to establish the connections to neighbors (e.g. [net.Dialer])
+type Dialer interface {
+	Dial(network string, address string) (net.Conn, error)
+}
+
+// maximum amount of messages which are buffered while a message waits for the
+// egress limits. Further messages (but control messages) are dropped so that
+// the writer keeps reading.
+const MAX_WRITE_BACKLOG = 64
+
 // define errors
 var (
-	ErrTimeout error = errors.New("operation timed out")
+	ErrTimeout             error = errors.New("operation timed out")
+	ErrExtensionRegistered error = errors.New("extension kind is already registered")
 )
 
-//go:generate capnp compile -I $HOME/programme/go-capnp/std -ogo:./ types/message.capnp types/push.capnp types/conn_pow.capnp types/conn_request.capnp types/conn_challenge.capnp types/pow_pow.capnp types/pow_request.capnp types/pow_challenge.capnp
+//go:generate capnp compile -I $HOME/programme/go-capnp/std -ogo:./ types/message.capnp types/push.capnp types/conn_pow.capnp types/conn_request.capnp types/conn_challenge.capnp types/pow_pow.capnp types/pow_request.capnp types/pow_challenge.capnp types/ihave.capnp types/graft.capnp types/prune.capnp types/subscribe.capnp types/iwant.capnp types/extension.capnp
 
 //go-sumtype:decl FromHz
 
@@ -75,8 +91,9 @@ type ToHz interface {
 	// add a function to the interface to avoid that arbitrary types can be
 	// passed (accidentally) as ToHz
 	canToHz()
-	// serves as some kind of predicate over the different types
-	isPow() bool
+	// control traffic (the PoW handshake and the keepalive) is never delayed
+	// by the egress limits, see [HorizontalApi.SetEgressLimits]
+	isControl() bool
 }
 
 // Represents a push message from/to the horizontalApi
@@ -92,33 +109,39 @@ type Push struct {
 func (Push) canFromHz() {}
 
 // mark this type as being sendable via ToHz channels
-func (Push) canToHz()    {}
-func (Push) isPow() bool { return false }
+func (Push) canToHz()        {}
+func (Push) isControl() bool { return false }
 
 // Represent a ConnReq message from/to the horizontalApi
 type ConnReq struct {
 	Id ConnectionId
+	// random identifier of the requesting node, used to detect duplicate
+	// connections
+	NodeId []byte
 }
 
 // mark this type as being sendable via FromHz channels
 func (ConnReq) canFromHz() {}
 
 // mark this type as being sendable via ToHz channels
-func (ConnReq) canToHz()    {}
-func (ConnReq) isPow() bool { return false }
+func (ConnReq) canToHz()        {}
+func (ConnReq) isControl() bool { return true }
 
 // Represents a ConnChall message from/to the horizontalApi
 type ConnChall struct {
 	Id     ConnectionId
 	Cookie []byte
+	// random identifier of the responding node, used to detect duplicate
+	// connections
+	NodeId []byte
 }
 
 // mark this type as being sendable via FromHz channels
 func (ConnChall) canFromHz() {}
 
 // mark this type as being sendable via ToHz channels
-func (ConnChall) canToHz()    {}
-func (ConnChall) isPow() bool { return false }
+func (ConnChall) canToHz()        {}
+func (ConnChall) isControl() bool { return true }
 
 // Represents a ConnPoW message from/to the horizontalApi
 type ConnPoW struct {
@@ -131,8 +154,8 @@ type ConnPoW struct {
 func (ConnPoW) canFromHz() {}
 
 // mark this type as being sendable via ToHz channels
-func (ConnPoW) canToHz()    {}
-func (ConnPoW) isPow() bool { return false }
+func (ConnPoW) canToHz()        {}
+func (ConnPoW) isControl() bool { return true }
 
 // Represent a PowReq message from/to the horizontalApi (used to renew connections)
 type PowReq struct {
@@ -143,8 +166,8 @@ type PowReq struct {
 func (PowReq) canFromHz() {}
 
 // mark this type as being sendable via ToHz channels
-func (PowReq) canToHz()    {}
-func (PowReq) isPow() bool { return true }
+func (PowReq) canToHz()        {}
+func (PowReq) isControl() bool { return true }
 
 // Represents a PowChall message from/to the horizontalApi
 type PowChall struct {
@@ -156,8 +179,8 @@ type PowChall struct {
 func (PowChall) canFromHz() {}
 
 // mark this type as being sendable via ToHz channels
-func (PowChall) canToHz()    {}
-func (PowChall) isPow() bool { return true }
+func (PowChall) canToHz()        {}
+func (PowChall) isControl() bool { return true }
 
 // Represents a PowPoW message from/to the horizontalApi
 type PowPoW struct {
@@ -170,8 +193,78 @@ type PowPoW struct {
 func (PowPoW) canFromHz() {}
 
 // mark this type as being sendable via ToHz channels
-func (PowPoW) canToHz()    {}
-func (PowPoW) isPow() bool { return true }
+func (PowPoW) canToHz()        {}
+func (PowPoW) isControl() bool { return true }
+
+// Represents an IHave message from/to the horizontalApi. It announces messages
+// the sender has received without sending their payload (lazy push).
+type IHave struct {
+	Id         ConnectionId
+	MessageIds []uint16
+}
+
+// mark this type as being sendable via FromHz channels
+func (IHave) canFromHz() {}
+
+// mark this type as being sendable via ToHz channels
+func (IHave) canToHz()        {}
+func (IHave) isControl() bool { return false }
+
+// Represents a Graft message from/to the horizontalApi. The receiver should
+// add the connection to its broadcast tree and send the missing messages.
+type Graft struct {
+	Id         ConnectionId
+	MessageIds []uint16
+}
+
+// mark this type as being sendable via FromHz channels
+func (Graft) canFromHz() {}
+
+// mark this type as being sendable via ToHz channels
+func (Graft) canToHz()        {}
+func (Graft) isControl() bool { return false }
+
+// Represents a Prune message from/to the horizontalApi. The receiver should
+// remove the connection from its broadcast tree.
+type Prune struct {
+	Id ConnectionId
+}
+
+// mark this type as being sendable via FromHz channels
+func (Prune) canFromHz() {}
+
+// mark this type as being sendable via ToHz channels
+func (Prune) canToHz()        {}
+func (Prune) isControl() bool { return false }
+
+// Represents an IWant message from/to the horizontalApi. The receiver should
+// send the requested messages it announced before.
+type IWant struct {
+	Id         ConnectionId
+	MessageIds []uint16
+}
+
+// mark this type as being sendable via FromHz channels
+func (IWant) canFromHz() {}
+
+// mark this type as being sendable via ToHz channels
+func (IWant) canToHz()        {}
+func (IWant) isControl() bool { return false }
+
+// Represents a Subscribe message from/to the horizontalApi. It contains the
+// gossip types the modules of the sender are registered for, it replaces the
+// previously sent types.
+type Subscribe struct {
+	Id          ConnectionId
+	GossipTypes []common.GossipType
+}
+
+// mark this type as being sendable via FromHz channels
+func (Subscribe) canFromHz() {}
+
+// mark this type as being sendable via ToHz channels
+func (Subscribe) canToHz()        {}
+func (Subscribe) isControl() bool { return false }
 
 type Unregister ConnectionId
 
@@ -204,20 +297,36 @@ type HorizontalApi struct {
 	cancel context.CancelFunc
 	// internally uses a context to signal when the goroutines shall terminate
 	ctx context.Context
-	// store the listener so that it can be closed in the end
-	ln net.Listener
+	// store the listeners so that they can be closed in the end
+	lns []net.Listener
 	// store all open connections so that they can be closed in the end
 	conns      map[net.Conn]struct{}
 	connsMutex sync.Mutex
 	// channel on which data which was received is being passed
 	fromHzChan chan<- FromHz
+	// decoders of the registered extension message kinds
+	extensions      map[ExtensionKind]ExtensionDecoder
+	extensionsMutex sync.RWMutex
 	// logging for this module
 	log *slog.Logger
 	// waitgroup to wait for all goroutines to terminate in the end
 	wg sync.WaitGroup
+	// connections on which nothing was read (or whose writes are blocked)
+	// for this long are closed, 0 disables the timeout
+	idleTimeout time.Duration
+	// limit of the bytes per second sent on all connections together and on
+	// each connection (see [HorizontalApi.SetEgressLimits])
+	egress        *ratelimit.Bucket
+	egressPerConn uint
 	// keep some stats of sent packets
 	packetcounter       *packetcounter.Counter
 	packetcounterNonPow *packetcounter.Counter
+	// keep some stats of sent bytes
+	bytecounter       *packetcounter.Counter
+	bytecounterNonPow *packetcounter.Counter
+	// keep some stats of packets dropped since the backlog of the egress
+	// limits was full
+	dropcounter *packetcounter.Counter
 }
 
 // Use this function to instantiate the horizontal api
@@ -234,10 +343,12 @@ func NewHorizontalApi(log *slog.Logger, fromHz chan<- FromHz) *HorizontalApi {
 	hz := &HorizontalApi{
 		cancel:     cancel,
 		ctx:        ctx,
-		ln:         nil,
+		lns:        nil,
 		conns:      make(map[net.Conn]struct{}, 0),
 		fromHzChan: fromHz,
+		extensions: make(map[ExtensionKind]ExtensionDecoder),
 		log:        log.With("module", "horzAPI"),
+		egress:     ratelimit.NewBucket(0, 0),
 	}
 
 	hz.packetcounter = packetcounter.NewCounter(func(t time.Time, cnt uint) {
@@ -248,22 +359,62 @@ func NewHorizontalApi(log *slog.Logger, fromHz chan<- FromHz) *HorizontalApi {
 		hz.log.Log(context.Background(), common.LevelTest, "hz non-pow packet sent", "timeBucket", t, "cnt", cnt)
 	}, 1*time.Second)
 
+	hz.bytecounter = packetcounter.NewCounter(func(t time.Time, cnt uint) {
+		hz.log.Log(context.Background(), common.LevelTest, "hz bytes sent", "timeBucket", t, "cnt", cnt)
+	}, 1*time.Second)
+
+	hz.bytecounterNonPow = packetcounter.NewCounter(func(t time.Time, cnt uint) {
+		hz.log.Log(context.Background(), common.LevelTest, "hz non-pow bytes sent", "timeBucket", t, "cnt", cnt)
+	}, 1*time.Second)
+
+	hz.dropcounter = packetcounter.NewCounter(func(t time.Time, cnt uint) {
+		hz.log.Log(context.Background(), common.LevelTest, "hz packet dropped", "timeBucket", t, "cnt", cnt)
+	}, 1*time.Second)
+
 	return hz
 }
 
+// Close connections on which nothing was read for the duration d. Writes
+// which block for longer than d (e.g. because the peer vanished without
+// closing the connection) close the connection as well. 0 disables the
+// timeout.
+//
+// The peers need to send something regularly (e.g. the pings of the keepalive
+// of the strategy) so that their connections are not closed. Must be called
+// before any connection is established.
+func (hz *HorizontalApi) SetIdleTimeout(d time.Duration) {
+	hz.idleTimeout = d
+}
+
+// Limit the bytes sent per second on all connections together (rate) and on
+// each single connection (perConn). 0 disables the respective limit.
+//
+// Control messages (the PoW handshake and the keepalive) are never delayed,
+// they are sent even while another message waits for the limits, so that
+// connections don't time out because of the limits. Their bytes are still
+// accounted, so the other messages (e.g. [Push]) wait a bit longer instead.
+// At most MAX_WRITE_BACKLOG other messages are queued per connection while a
+// message waits, further ones are dropped. Must be called before any
+// connection is established.
+func (hz *HorizontalApi) SetEgressLimits(rate uint, perConn uint) {
+	hz.egress = ratelimit.NewBucket(float64(rate), 0)
+	hz.egressPerConn = perConn
+}
+
 // Listen on the specified address for incoming horizontal api connections.
 //
 // This function spawns a new goroutine accepting new connections and
-// terminates afterwards.
+// terminates afterwards. It can be called multiple times to listen on multiple
+// addresses (e.g. IPv4 and IPv6).
 //
 // On the channel passed as seccond argument, the horizontalApi advertises new
 // incoming connections
 func (hz *HorizontalApi) Listen(addr string, initFinished chan<- struct{}) error {
-	var err error
-	hz.ln, err = net.Listen("tcp", addr)
+	ln, err := net.Listen("tcp", addr)
 	if err != nil {
 		return fmt.Errorf("listen to port for horizontal API: %w", err)
 	}
+	hz.lns = append(hz.lns, ln)
 
 	initFinished <- struct{}{}
 
@@ -272,9 +423,12 @@ func (hz *HorizontalApi) Listen(addr string, initFinished chan<- struct{}) error
 	go func() {
 		defer hz.wg.Done()
 		for {
-			conn, err := hz.ln.Accept()
+			conn, err := ln.Accept()
 			if err != nil {
 				// check if shall terminate
+				if errors.Is(err, net.ErrClosed) {
+					return
+				}
 				select {
 				case <-hz.ctx.Done():
 					return
@@ -292,6 +446,7 @@ func (hz *HorizontalApi) Listen(addr string, initFinished chan<- struct{}) error
 			hz.fromHzChan <- NewConn{
 				Data:  toHz,
 				Id:    ConnectionId(conn.RemoteAddr().String()),
+				Local: ConnectionId(conn.LocalAddr().String()),
 				Ctx:   ctx,
 				Cfunc: cfunc,
 			}
@@ -302,8 +457,8 @@ func (hz *HorizontalApi) Listen(addr string, initFinished chan<- struct{}) error
 			hz.log.Info("Incoming connection from", "addr", conn.RemoteAddr().String())
 
 			hz.wg.Add(2)
-			go hz.handleConnection(conn, Conn[chan<- ToHz]{Data: toHz, Id: ConnectionId(conn.RemoteAddr().String()), Ctx: ctx, Cfunc: cfunc})
-			go hz.writeToConnection(conn, Conn[<-chan ToHz]{Data: toHz, Id: ConnectionId(conn.RemoteAddr().String()), Ctx: ctx, Cfunc: cfunc})
+			go hz.handleConnection(conn, Conn[chan<- ToHz]{Data: toHz, Id: ConnectionId(conn.RemoteAddr().String()), Local: ConnectionId(conn.LocalAddr().String()), Ctx: ctx, Cfunc: cfunc})
+			go hz.writeToConnection(conn, Conn[<-chan ToHz]{Data: toHz, Id: ConnectionId(conn.RemoteAddr().String()), Local: ConnectionId(conn.LocalAddr().String()), Ctx: ctx, Cfunc: cfunc})
 		}
 	}()
 	return nil
@@ -314,7 +469,7 @@ func (hz *HorizontalApi) Listen(addr string, initFinished chan<- struct{}) error
 // Returns a slice of channels (same ordering like the address-slice parameter)
 // on which the horizontalApi will send incoming packets on the connection to
 // the respective neighbor.
-func (hz *HorizontalApi) AddNeighbors(dialer *net.Dialer, addrs ...string) ([]Conn[chan<- ToHz], error) {
+func (hz *HorizontalApi) AddNeighbors(dialer Dialer, addrs ...string) ([]Conn[chan<- ToHz], error) {
 	var ret []Conn[chan<- ToHz]
 	for _, a := range addrs {
 		conn, err := dialer.Dial("tcp", a)
@@ -332,11 +487,11 @@ func (hz *HorizontalApi) AddNeighbors(dialer *net.Dialer, addrs ...string) ([]Co
 		ctx, cfunc := context.WithCancel(hz.ctx)
 
 		toHz := make(chan ToHz)
-		ret = append(ret, Conn[chan<- ToHz]{Data: toHz, Id: ConnectionId(conn.RemoteAddr().String()), Ctx: ctx, Cfunc: cfunc})
+		ret = append(ret, Conn[chan<- ToHz]{Data: toHz, Id: ConnectionId(conn.RemoteAddr().String()), Local: ConnectionId(conn.LocalAddr().String()), Ctx: ctx, Cfunc: cfunc})
 
 		hz.wg.Add(2)
-		go hz.handleConnection(conn, Conn[chan<- ToHz]{Data: toHz, Id: ConnectionId(conn.RemoteAddr().String()), Ctx: ctx, Cfunc: cfunc})
-		go hz.writeToConnection(conn, Conn[<-chan ToHz]{Data: toHz, Id: ConnectionId(conn.RemoteAddr().String()), Ctx: ctx, Cfunc: cfunc})
+		go hz.handleConnection(conn, Conn[chan<- ToHz]{Data: toHz, Id: ConnectionId(conn.RemoteAddr().String()), Local: ConnectionId(conn.LocalAddr().String()), Ctx: ctx, Cfunc: cfunc})
+		go hz.writeToConnection(conn, Conn[<-chan ToHz]{Data: toHz, Id: ConnectionId(conn.RemoteAddr().String()), Local: ConnectionId(conn.LocalAddr().String()), Ctx: ctx, Cfunc: cfunc})
 	}
 	return ret, nil
 }
@@ -366,6 +521,10 @@ func (hz *HorizontalApi) handleConnection(conn net.Conn, connData Conn[chan<- To
 	}(connData)
 	// close the connection
 	defer conn.Close()
+	// cancelling the context of the connection (e.g. when a peer gets
+	// dropped) must interrupt the blocking read as well
+	stop := context.AfterFunc(connData.Ctx, func() { conn.Close() })
+	defer stop()
 
 	// one global decoder suffices
 	decoder := capnp.NewDecoder(conn)
@@ -373,6 +532,10 @@ func (hz *HorizontalApi) handleConnection(conn net.Conn, connData Conn[chan<- To
 	// some cleanup can be done before actually continuing
 loop:
 	for {
+		// the peer must send something within the idle timeout
+		if hz.idleTimeout > 0 {
+			conn.SetReadDeadline(time.Now().Add(hz.idleTimeout))
+		}
 		// wait for new message
 		cmsg, err := decoder.Decode()
 		if err != nil {
@@ -385,6 +548,10 @@ loop:
 			if errors.Is(err, io.EOF) {
 				return
 			}
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				hz.log.Info("Closing idle connection", "addr", connData.Id, "timeout", hz.idleTimeout)
+				return
+			}
 			hz.log.Error("decoding the message failed", "err", err)
 			continue
 		}
@@ -430,7 +597,7 @@ loop:
 				hz.fromHzChan <- p
 			case msg.Body().HasConnReq():
 				// retrieve the ConnReq message
-				_, err := msg.Body().ConnReq()
+				req, err := msg.Body().ConnReq()
 				if err != nil {
 					hz.log.Error("read the ConnReq message failed", "err", err)
 					goto continue_read
@@ -438,6 +605,15 @@ loop:
 				p := ConnReq{
 					Id: connData.Id,
 				}
+				// node id is no scalar type -> retrival might error
+				p.NodeId, err = req.NodeId()
+				if err != nil {
+					hz.log.Error("obtaining the node id failed", "err", err)
+					goto continue_read
+				}
+				// p node id is still a "pointer" into the capnproto message ->
+				// empty if memory is freeed => make a copy of it
+				p.NodeId = slices.Clone(p.NodeId)
 				// send the connection request to the channel
 				hz.fromHzChan <- p
 			case msg.Body().HasConnChall():
@@ -462,6 +638,12 @@ loop:
 				// p cookie is still a "pointer" into the capnproto message ->
 				// empty if memory is freeed => make a copy of it
 				p.Cookie = slices.Clone(p.Cookie)
+				p.NodeId, err = chall.NodeId()
+				if err != nil {
+					hz.log.Error("obtaining the node id failed", "err", err)
+					goto continue_read
+				}
+				p.NodeId = slices.Clone(p.NodeId)
 				hz.fromHzChan <- p
 			case msg.Body().HasConnPoW():
 				// retrieve the ConnPoW message
@@ -546,7 +728,120 @@ loop:
 				// empty if memory is freeed => make a copy of it
 				p.Cookie = slices.Clone(p.Cookie)
 				hz.fromHzChan <- p
-
+			case msg.Body().HasIHave():
+				// retrieve the IHave message
+				ihave, err := msg.Body().IHave()
+				if err != nil {
+					hz.log.Error("read the IHave message failed", "err", err)
+					goto continue_read
+				}
+				p := IHave{
+					Id: connData.Id,
+				}
+				// message ids are no scalar type -> retrival might error
+				ids, err := ihave.MessageIds()
+				if err != nil {
+					hz.log.Error("obtaining the message ids failed", "err", err)
+					goto continue_read
+				}
+				p.MessageIds = readUInt16List(ids)
+				hz.fromHzChan <- p
+			case msg.Body().HasGraft():
+				// retrieve the Graft message
+				graft, err := msg.Body().Graft()
+				if err != nil {
+					hz.log.Error("read the Graft message failed", "err", err)
+					goto continue_read
+				}
+				p := Graft{
+					Id: connData.Id,
+				}
+				// message ids are no scalar type -> retrival might error
+				ids, err := graft.MessageIds()
+				if err != nil {
+					hz.log.Error("obtaining the message ids failed", "err", err)
+					goto continue_read
+				}
+				p.MessageIds = readUInt16List(ids)
+				hz.fromHzChan <- p
+			case msg.Body().HasPrune():
+				// retrieve the Prune message
+				_, err := msg.Body().Prune()
+				if err != nil {
+					hz.log.Error("read the Prune message failed", "err", err)
+					goto continue_read
+				}
+				p := Prune{
+					Id: connData.Id,
+				}
+				hz.fromHzChan <- p
+			case msg.Body().HasIWant():
+				// retrieve the IWant message
+				iwant, err := msg.Body().IWant()
+				if err != nil {
+					hz.log.Error("read the IWant message failed", "err", err)
+					goto continue_read
+				}
+				p := IWant{
+					Id: connData.Id,
+				}
+				// message ids are no scalar type -> retrival might error
+				ids, err := iwant.MessageIds()
+				if err != nil {
+					hz.log.Error("obtaining the message ids failed", "err", err)
+					goto continue_read
+				}
+				p.MessageIds = readUInt16List(ids)
+				hz.fromHzChan <- p
+			case msg.Body().HasSubscribe():
+				// retrieve the Subscribe message
+				sub, err := msg.Body().Subscribe()
+				if err != nil {
+					hz.log.Error("read the Subscribe message failed", "err", err)
+					goto continue_read
+				}
+				p := Subscribe{
+					Id: connData.Id,
+				}
+				// gossip types are no scalar type -> retrival might error
+				gtypes, err := sub.GossipTypes()
+				if err != nil {
+					hz.log.Error("obtaining the gossip types failed", "err", err)
+					goto continue_read
+				}
+				p.GossipTypes = make([]common.GossipType, 0, gtypes.Len())
+				for _, t := range readUInt16List(gtypes) {
+					p.GossipTypes = append(p.GossipTypes, common.GossipType(t))
+				}
+				hz.fromHzChan <- p
+			case msg.Body().HasExtension():
+				// retrieve the Extension message
+				ext, err := msg.Body().Extension()
+				if err != nil {
+					hz.log.Error("read the Extension message failed", "err", err)
+					goto continue_read
+				}
+				// data is no scalar type -> retrival might error
+				data, err := ext.Data()
+				if err != nil {
+					hz.log.Error("obtaining the extension data failed", "err", err)
+					goto continue_read
+				}
+				// copy the data as the decoder may retain it while cmsg
+				// gets released
+				m, ok, err := hz.decodeExtension(ExtensionKind(ext.Kind()), slices.Clone(data))
+				if !ok {
+					hz.log.Debug("dropping extension message of unknown kind", "kind", ext.Kind())
+					goto continue_read
+				}
+				if err != nil {
+					hz.log.Error("decoding the extension message failed", "kind", ext.Kind(), "err", err)
+					goto continue_read
+				}
+				hz.fromHzChan <- Extension{
+					Id:      connData.Id,
+					Message: m,
+				}
 			default:
 				hz.log.Error("no valid message was sent", "type was", msg.Body().Which().String())
 				goto continue_read
@@ -567,185 +862,414 @@ func (hz *HorizontalApi) writeToConnection(conn net.Conn, c Conn[<-chan ToHz]) {
 	// one global encoder and arena suffice
 	encoder := capnp.NewEncoder(conn)
 	arena := capnp.SingleSegment(nil)
-	// the following loop uses goto continue_write instead of continue so that
-	// some cleanup can be done before actually continuing
+	// limit of the bytes sent on this connection
+	egress := ratelimit.NewBucket(float64(hz.egressPerConn), 0)
+	// messages which were read while waiting for the egress limits, they are
+	// sent before any new message is read
+	backlog := make([]ToHz, 0)
 loop:
 	for {
-		select {
-		case <-c.Ctx.Done():
-			break loop
-
-		case rmsg := <-c.Data:
-			hz.log.Debug("instructed to send", "Message", rmsg)
-			// create a new capnproto message
-			cmsg, seg, err := capnp.NewMessage(arena)
-			if err != nil {
-				hz.log.Error("creating new message failed", "err", err)
-				goto continue_write
+		var rmsg ToHz
+		if len(backlog) > 0 {
+			rmsg, backlog = backlog[0], backlog[1:]
+		} else {
+			select {
+			case <-c.Ctx.Done():
+				break loop
+			case rmsg = <-c.Data:
 			}
-			// scoping needed for goto continue_read to ensure hm isn't used
-			// after goto
-			{
-				// create the actual message
-				msg, err := hzTypes.NewRootMessage(seg)
-				if err != nil {
-					hz.log.Error("creating new sending message failed", "err", err)
-					goto continue_write
-				}
-				// check of which type the remote message actually is
-				switch rmsg := rmsg.(type) {
-				case Push:
-					// create the push message
-					push, err := hzTypes.NewPushMsg(seg)
-					if err != nil {
-						hz.log.Error("creating new sending message failed", "err", err)
-						goto continue_write
-					}
-					// populate the push message
-					// setting scalar value cannot error
-					push.SetTtl(rmsg.TTL)
-					push.SetGossipType(uint16(rmsg.GossipType))
-					push.SetMessageID(rmsg.MessageID)
-					// payload is no scalar type -> setting might error
-					if err := push.SetPayload(rmsg.Payload); err != nil {
-						hz.log.Error("setting the payload for the push message failed", "err", err)
-						goto continue_write
-					}
-					// combine push and the message
-					if err := msg.Body().SetPush(push); err != nil {
-						hz.log.Error("setting sending message to push failed", "err", err)
-						goto continue_write
-					}
-				case ConnReq:
-					// create the ConnReq message
-					req, err := hzTypes.NewConnReq(seg)
-					if err != nil {
-						hz.log.Error("creating new sending message failed", "err", err)
-						goto continue_write
-					}
-
-					// combine ConnReq and the message
-					if err := msg.Body().SetConnReq(req); err != nil {
-						hz.log.Error("setting sending message to connReq failed", "err", err)
-						goto continue_write
-					}
-				case ConnChall:
-					// create the ConnChall message
-					chall, err := hzTypes.NewConnChall(seg)
-					if err != nil {
-						hz.log.Error("creating new ConnChall message failed", "err", err)
-						goto continue_write
-					}
-					// populate the message
-					// cookie is no scalar type -> setting might error
-					if err := chall.SetCookie(rmsg.Cookie); err != nil {
-						hz.log.Error("setting the cookie for the ConnChall message failed", "err", err)
-						goto continue_write
-					}
-					// combine connChall and the message
-					if err := msg.Body().SetConnChall(chall); err != nil {
-						hz.log.Error("setting sending message to ConnChall failed", "err", err)
-						goto continue_write
-					}
-				case ConnPoW:
-					// create the ConnPow message
-					pow, err := hzTypes.NewConnPoW(seg)
-					if err != nil {
-						hz.log.Error("creating new ConnPoW message failed", "err", err)
-						goto continue_write
-					}
-					// populate the message
-					// setting scalar value cannot lead to error
-					pow.SetNonce(rmsg.PowNonce)
-
-					// cookie is no scalar type -> setting might error
-					if err := pow.SetCookie(rmsg.Cookie); err != nil {
-						hz.log.Error("setting the cookie for the ConnPoW message failed", "err", err)
-						goto continue_write
-					}
-					// combine connChall and the message
-					if err := msg.Body().SetConnPoW(pow); err != nil {
-						hz.log.Error("setting sending message to ConnPow failed", "err", err)
-						goto continue_write
-					}
-
-				case PowReq:
-					// create the ConnReq message
-					req, err := hzTypes.NewPowReq(seg)
-					if err != nil {
-						hz.log.Error("creating new sending message failed", "err", err)
-						goto continue_write
-					}
+		}
 
-					// combine ConnReq and the message
-					if err := msg.Body().SetPowReq(req); err != nil {
-						hz.log.Error("setting sending message to connReq failed", "err", err)
-						goto continue_write
-					}
-				case PowChall:
-					// create the ConnChall message
-					chall, err := hzTypes.NewPowChall(seg)
-					if err != nil {
-						hz.log.Error("creating new PoWChall message failed", "err", err)
-						goto continue_write
-					}
-					// populate the message
-					// cookie is no scalar type -> setting might error
-					if err := chall.SetCookie(rmsg.Cookie); err != nil {
-						hz.log.Error("setting the cookie for the PoWChall message failed", "err", err)
-						goto continue_write
-					}
-					// combine connChall and the message
-					if err := msg.Body().SetPowChall(chall); err != nil {
-						hz.log.Error("setting sending message to PoWChall failed", "err", err)
-						goto continue_write
-					}
-				case PowPoW:
-					// create the ConnPow message
-					pow, err := hzTypes.NewPowPoW(seg)
-					if err != nil {
-						hz.log.Error("creating new PoWPoW message failed", "err", err)
-						goto continue_write
-					}
-					// populate the message
-					// setting scalar value cannot lead to error
-					pow.SetNonce(rmsg.PowNonce)
-
-					// cookie is no scalar type -> setting might error
-					if err := pow.SetCookie(rmsg.Cookie); err != nil {
-						hz.log.Error("setting the cookie for the PoWPoW message failed", "err", err)
-						goto continue_write
-					}
-					// combine connChall and the message
-					if err := msg.Body().SetPowPoW(pow); err != nil {
-						hz.log.Error("setting sending message to PowPoW failed", "err", err)
-						goto continue_write
-					}
-				}
-				if !rmsg.isPow() {
-					hz.packetcounterNonPow.Add(1)
-				}
-			}
-			// sent the message on the channel
-			hz.packetcounter.Add(1)
-			if err := encoder.Encode(cmsg); err != nil {
-				// might error because the connection has closed -> check if should
-				// terminate
+		cmsg, wait, ok := hz.prepareMessage(arena, egress, rmsg)
+		if !ok {
+			continue
+		}
+		// only messages which are no control traffic wait for the limits.
+		// Control messages which are to be sent in the meantime (e.g. a
+		// PowPoW or a ping) are sent right away, so that the connection does
+		// not time out while a large Push waits.
+		if wait > 0 && !rmsg.isControl() {
+			timer := time.NewTimer(wait)
+		waiting:
+			for {
 				select {
+				case <-timer.C:
+					break waiting
 				case <-c.Ctx.Done():
+					timer.Stop()
 					break loop
-				default:
+				case m := <-c.Data:
+					if !m.isControl() {
+						if len(backlog) >= MAX_WRITE_BACKLOG {
+							// keep reading so that neither the strategy nor
+							// the control messages queued behind m block
+							hz.log.Debug("Dropping message since too many messages wait for the egress limits", "addr", c.Id, "Message", m)
+							hz.dropcounter.Add(1)
+							continue
+						}
+						backlog = append(backlog, m)
+						continue
+					}
+					// the arena is still in use by the waiting message
+					ctrl, _, ok := hz.prepareMessage(capnp.SingleSegment(nil), egress, m)
+					if !ok {
+						continue
+					}
+					written := hz.writeMessage(conn, encoder, c, ctrl)
+					ctrl.Release()
+					if !written {
+						timer.Stop()
+						break loop
+					}
 				}
-				hz.log.Error("encoding the message failed", "err", err)
-				goto continue_write
 			}
-		continue_write:
-			// reset the arena to free memory used by the last encoded message
-			cmsg.Release()
+		}
+		written := hz.writeMessage(conn, encoder, c, cmsg)
+		// reset the arena to free memory used by the last encoded message
+		cmsg.Release()
+		if !written {
+			break loop
 		}
 	}
 }
 
+// Encode rmsg into a new capnproto message allocated in arena. The size of
+// the message is charged on the global and the connection's (egress) limit.
+//
+// Returns the message, how long it has to wait for the limits and whether
+// encoding succeeded.
+func (hz *HorizontalApi) prepareMessage(arena capnp.Arena, egress *ratelimit.Bucket, rmsg ToHz) (*capnp.Message, time.Duration, bool) {
+	hz.log.Debug("instructed to send", "Message", rmsg)
+	// create a new capnproto message
+	cmsg, seg, err := capnp.NewMessage(arena)
+	if err != nil {
+		hz.log.Error("creating new message failed", "err", err)
+		return nil, 0, false
+	}
+	// create the actual message
+	msg, err := hzTypes.NewRootMessage(seg)
+	if err != nil {
+		hz.log.Error("creating new sending message failed", "err", err)
+		cmsg.Release()
+		return nil, 0, false
+	}
+	if !hz.fillMessage(msg, seg, rmsg) {
+		cmsg.Release()
+		return nil, 0, false
+	}
+	if !rmsg.isControl() {
+		hz.packetcounterNonPow.Add(1)
+	}
+
+	// size of the message on the wire
+	size, err := cmsg.TotalSize()
+	if err != nil {
+		hz.log.Error("computing the size of the message failed", "err", err)
+		cmsg.Release()
+		return nil, 0, false
+	}
+	// the bytes are charged on both limits, also for control traffic
+	now := time.Now()
+	wait := max(hz.egress.TakeN(now, float64(size)), egress.TakeN(now, float64(size)))
+	hz.bytecounter.Add(uint(size))
+	if !rmsg.isControl() {
+		hz.bytecounterNonPow.Add(uint(size))
+	}
+	return cmsg, wait, true
+}
+
+// Set the body of msg (allocated in seg) to rmsg. Returns false if this
+// failed.
+func (hz *HorizontalApi) fillMessage(msg hzTypes.Message, seg *capnp.Segment, rmsg ToHz) bool {
+	// check of which type the remote message actually is
+	switch rmsg := rmsg.(type) {
+	case Push:
+		// create the push message
+		push, err := hzTypes.NewPushMsg(seg)
+		if err != nil {
+			hz.log.Error("creating new sending message failed", "err", err)
+			return false
+		}
+		// populate the push message
+		// setting scalar value cannot error
+		push.SetTtl(rmsg.TTL)
+		push.SetGossipType(uint16(rmsg.GossipType))
+		push.SetMessageID(rmsg.MessageID)
+		// payload is no scalar type -> setting might error
+		if err := push.SetPayload(rmsg.Payload); err != nil {
+			hz.log.Error("setting the payload for the push message failed", "err", err)
+			return false
+		}
+		// combine push and the message
+		if err := msg.Body().SetPush(push); err != nil {
+			hz.log.Error("setting sending message to push failed", "err", err)
+			return false
+		}
+	case ConnReq:
+		// create the ConnReq message
+		req, err := hzTypes.NewConnReq(seg)
+		if err != nil {
+			hz.log.Error("creating new sending message failed", "err", err)
+			return false
+		}
+		// populate the message
+		// node id is no scalar type -> setting might error
+		if err := req.SetNodeId(rmsg.NodeId); err != nil {
+			hz.log.Error("setting the node id for the ConnReq message failed", "err", err)
+			return false
+		}
+
+		// combine ConnReq and the message
+		if err := msg.Body().SetConnReq(req); err != nil {
+			hz.log.Error("setting sending message to connReq failed", "err", err)
+			return false
+		}
+	case ConnChall:
+		// create the ConnChall message
+		chall, err := hzTypes.NewConnChall(seg)
+		if err != nil {
+			hz.log.Error("creating new ConnChall message failed", "err", err)
+			return false
+		}
+		// populate the message
+		// cookie is no scalar type -> setting might error
+		if err := chall.SetCookie(rmsg.Cookie); err != nil {
+			hz.log.Error("setting the cookie for the ConnChall message failed", "err", err)
+			return false
+		}
+		if err := chall.SetNodeId(rmsg.NodeId); err != nil {
+			hz.log.Error("setting the node id for the ConnChall message failed", "err", err)
+			return false
+		}
+		// combine connChall and the message
+		if err := msg.Body().SetConnChall(chall); err != nil {
+			hz.log.Error("setting sending message to ConnChall failed", "err", err)
+			return false
+		}
+	case ConnPoW:
+		// create the ConnPow message
+		pow, err := hzTypes.NewConnPoW(seg)
+		if err != nil {
+			hz.log.Error("creating new ConnPoW message failed", "err", err)
+			return false
+		}
+		// populate the message
+		// setting scalar value cannot lead to error
+		pow.SetNonce(rmsg.PowNonce)
+
+		// cookie is no scalar type -> setting might error
+		if err := pow.SetCookie(rmsg.Cookie); err != nil {
+			hz.log.Error("setting the cookie for the ConnPoW message failed", "err", err)
+			return false
+		}
+		// combine connChall and the message
+		if err := msg.Body().SetConnPoW(pow); err != nil {
+			hz.log.Error("setting sending message to ConnPow failed", "err", err)
+			return false
+		}
+
+	case PowReq:
+		// create the ConnReq message
+		req, err := hzTypes.NewPowReq(seg)
+		if err != nil {
+			hz.log.Error("creating new sending message failed", "err", err)
+			return false
+		}
+
+		// combine ConnReq and the message
+		if err := msg.Body().SetPowReq(req); err != nil {
+			hz.log.Error("setting sending message to connReq failed", "err", err)
+			return false
+		}
+	case PowChall:
+		// create the ConnChall message
+		chall, err := hzTypes.NewPowChall(seg)
+		if err != nil {
+			hz.log.Error("creating new PoWChall message failed", "err", err)
+			return false
+		}
+		// populate the message
+		// cookie is no scalar type -> setting might error
+		if err := chall.SetCookie(rmsg.Cookie); err != nil {
+			hz.log.Error("setting the cookie for the PoWChall message failed", "err", err)
+			return false
+		}
+		// combine connChall and the message
+		if err := msg.Body().SetPowChall(chall); err != nil {
+			hz.log.Error("setting sending message to PoWChall failed", "err", err)
+			return false
+		}
+	case PowPoW:
+		// create the ConnPow message
+		pow, err := hzTypes.NewPowPoW(seg)
+		if err != nil {
+			hz.log.Error("creating new PoWPoW message failed", "err", err)
+			return false
+		}
+		// populate the message
+		// setting scalar value cannot lead to error
+		pow.SetNonce(rmsg.PowNonce)
+
+		// cookie is no scalar type -> setting might error
+		if err := pow.SetCookie(rmsg.Cookie); err != nil {
+			hz.log.Error("setting the cookie for the PoWPoW message failed", "err", err)
+			return false
+		}
+		// combine connChall and the message
+		if err := msg.Body().SetPowPoW(pow); err != nil {
+			hz.log.Error("setting sending message to PowPoW failed", "err", err)
+			return false
+		}
+	case IHave:
+		// create the IHave message
+		ihave, err := hzTypes.NewIHave(seg)
+		if err != nil {
+			hz.log.Error("creating new IHave message failed", "err", err)
+			return false
+		}
+		// populate the message
+		// message ids are no scalar type -> setting might error
+		if err := writeUInt16List(ihave.NewMessageIds, rmsg.MessageIds); err != nil {
+			hz.log.Error("setting the message ids for the IHave message failed", "err", err)
+			return false
+		}
+		// combine ihave and the message
+		if err := msg.Body().SetIHave(ihave); err != nil {
+			hz.log.Error("setting sending message to IHave failed", "err", err)
+			return false
+		}
+	case Graft:
+		// create the Graft message
+		graft, err := hzTypes.NewGraft(seg)
+		if err != nil {
+			hz.log.Error("creating new Graft message failed", "err", err)
+			return false
+		}
+		// populate the message
+		// message ids are no scalar type -> setting might error
+		if err := writeUInt16List(graft.NewMessageIds, rmsg.MessageIds); err != nil {
+			hz.log.Error("setting the message ids for the Graft message failed", "err", err)
+			return false
+		}
+		// combine graft and the message
+		if err := msg.Body().SetGraft(graft); err != nil {
+			hz.log.Error("setting sending message to Graft failed", "err", err)
+			return false
+		}
+	case Prune:
+		// create the Prune message
+		prune, err := hzTypes.NewPrune(seg)
+		if err != nil {
+			hz.log.Error("creating new Prune message failed", "err", err)
+			return false
+		}
+
+		// combine prune and the message
+		if err := msg.Body().SetPrune(prune); err != nil {
+			hz.log.Error("setting sending message to Prune failed", "err", err)
+			return false
+		}
+	case IWant:
+		// create the IWant message
+		iwant, err := hzTypes.NewIWant(seg)
+		if err != nil {
+			hz.log.Error("creating new IWant message failed", "err", err)
+			return false
+		}
+		// populate the message
+		// message ids are no scalar type -> setting might error
+		if err := writeUInt16List(iwant.NewMessageIds, rmsg.MessageIds); err != nil {
+			hz.log.Error("setting the message ids for the IWant message failed", "err", err)
+			return false
+		}
+		// combine iwant and the message
+		if err := msg.Body().SetIWant(iwant); err != nil {
+			hz.log.Error("setting sending message to IWant failed", "err", err)
+			return false
+		}
+	case Subscribe:
+		// create the Subscribe message
+		sub, err := hzTypes.NewSubscribe(seg)
+		if err != nil {
+			hz.log.Error("creating new Subscribe message failed", "err", err)
+			return false
+		}
+		// populate the message
+		// gossip types are no scalar type -> setting might error
+		gtypes := make([]uint16, 0, len(rmsg.GossipTypes))
+		for _, t := range rmsg.GossipTypes {
+			gtypes = append(gtypes, uint16(t))
+		}
+		if err := writeUInt16List(sub.NewGossipTypes, gtypes); err != nil {
+			hz.log.Error("setting the gossip types for the Subscribe message failed", "err", err)
+			return false
+		}
+		// combine subscribe and the message
+		if err := msg.Body().SetSubscribe(sub); err != nil {
+			hz.log.Error("setting sending message to Subscribe failed", "err", err)
+			return false
+		}
+	case Extension:
+		// encode the wrapped message
+		data, err := rmsg.Message.MarshalBinary()
+		if err != nil {
+			hz.log.Error("encoding the extension message failed", "kind", rmsg.Message.Kind(), "err", err)
+			return false
+		}
+		// create the Extension message
+		ext, err := hzTypes.NewExtension(seg)
+		if err != nil {
+			hz.log.Error("creating new Extension message failed", "err", err)
+			return false
+		}
+		// populate the message
+		ext.SetKind(uint16(rmsg.Message.Kind()))
+		// data is no scalar type -> setting might error
+		if err := ext.SetData(data); err != nil {
+			hz.log.Error("setting the data for the Extension message failed", "err", err)
+			return false
+		}
+		// combine extension and the message
+		if err := msg.Body().SetExtension(ext); err != nil {
+			hz.log.Error("setting sending message to Extension failed", "err", err)
+			return false
+		}
+	}
+	return true
+}
+
+// Write cmsg to the connection.
+//
+// Returns false if the writer has to terminate (the connection was closed or
+// writing timed out).
+func (hz *HorizontalApi) writeMessage(conn net.Conn, encoder *capnp.Encoder, c Conn[<-chan ToHz], cmsg *capnp.Message) bool {
+	// a peer which does not read anymore must not block the writer
+	// forever
+	if hz.idleTimeout > 0 {
+		conn.SetWriteDeadline(time.Now().Add(hz.idleTimeout))
+	}
+	// sent the message on the channel
+	hz.packetcounter.Add(1)
+	if err := encoder.Encode(cmsg); err != nil {
+		// might error because the connection has closed -> check if should
+		// terminate
+		select {
+		case <-c.Ctx.Done():
+			return false
+		default:
+		}
+		// the message might have been written partially, so the
+		// connection is unusable
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			hz.log.Info("Closing connection since writing timed out", "addr", c.Id, "timeout", hz.idleTimeout)
+			c.Cfunc()
+			return false
+		}
+		hz.log.Error("encoding the message failed", "err", err)
+	}
+	return true
+}
+
 // Close the horizontal api
 //
 // Always tries to close all the connections. If multiple
@@ -760,8 +1284,10 @@ func (hz *HorizontalApi) Close() error {
 	// signal to connection goroutines that they should terminate
 	hz.cancel()
 
-	// close the listener
-	hz.ln.Close()
+	// close the listeners
+	for _, ln := range hz.lns {
+		ln.Close()
+	}
 
 	hz.connsMutex.Lock()
 	for c := range hz.conns {
@@ -774,6 +1300,9 @@ func (hz *HorizontalApi) Close() error {
 
 	hz.packetcounter.Finalize()
 	hz.packetcounterNonPow.Finalize()
+	hz.bytecounter.Finalize()
+	hz.bytecounterNonPow.Finalize()
+	hz.dropcounter.Finalize()
 
 	//
 	fin := make(chan struct{})
@@ -789,3 +1318,24 @@ func (hz *HorizontalApi) Close() error {
 		return ErrTimeout
 	}
 }
+
+// copy a capnproto uint16 list to a slice
+func readUInt16List(l capnp.UInt16List) []uint16 {
+	ret := make([]uint16, 0, l.Len())
+	for i := 0; i < l.Len(); i++ {
+		ret = append(ret, l.At(i))
+	}
+	return ret
+}
+
+// allocate a capnproto uint16 list via newList and fill it with the values
+func writeUInt16List(newList func(n int32) (capnp.UInt16List, error), vals []uint16) error {
+	l, err := newList(int32(len(vals)))
+	if err != nil {
+		return err
+	}
+	for i, v := range vals {
+		l.Set(i, v)
+	}
+	return nil
+}
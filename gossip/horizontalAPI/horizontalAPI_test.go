@@ -20,6 +20,8 @@ package horizontalapi
 
 import (
 	"context"
+	"errors"
+	"gossip/common"
 	"log/slog"
 	"net"
 	"reflect"
@@ -78,6 +80,246 @@ func TestGorizontalApiWithPipe(test *testing.T) {
 		if !reflect.DeepEqual(t, u) {
 			test.Fatalf("didn't reveice the message previously sent. Sent %+v rcved%+v", t, u)
 		}
+	default:
+		test.Fatalf("received message is of wrong type")
+	}
+
+	for _, sh := range []ToHz{
+		ConnReq{NodeId: []byte{0x01, 0x02, 0x03, 0x04}},
+		ConnChall{Cookie: []byte{0x05, 0x06}, NodeId: []byte{0x01, 0x02, 0x03, 0x04}},
+		IHave{MessageIds: []uint16{1, 65535}},
+		Graft{MessageIds: []uint16{42}},
+		Prune{},
+		IWant{MessageIds: []uint16{7, 8}},
+		Subscribe{GossipTypes: []common.GossipType{1, 1337}},
+	} {
+		toHz <- sh
+		select {
+		case u = <-fromHz:
+		case <-time.After(5 * time.Second):
+			test.Fatalf("timeout for reading the to be received message after 5 seconds")
+		}
+		if !reflect.DeepEqual(u, sh) {
+			test.Fatalf("didn't reveice the message previously sent. Sent %+v rcved%+v", sh, u)
+		}
+	}
+}
+
+func TestIdleTimeout(test *testing.T) {
+	// use this for logging so that messages are not shown in general,
+	// only if the test fails
+	var testLog *slog.Logger = slogt.New(test)
+
+	fromHz := make(chan FromHz, 1)
+	hz := NewHorizontalApi(testLog, fromHz)
+	hz.SetIdleTimeout(50 * time.Millisecond)
+	defer func() {
+		hz.cancel()
+		hz.wg.Wait()
+	}()
+
+	cWrite, cRead := net.Pipe()
+	defer cWrite.Close()
+	ctx, cfunc := context.WithCancel(context.Background())
+	defer cfunc()
+
+	hz.wg.Add(1)
+	go hz.handleConnection(cRead, Conn[chan<- ToHz]{Id: "a", Ctx: ctx, Cfunc: cfunc})
+
+	// the peer never sends anything
+	select {
+	case u := <-fromHz:
+		if !reflect.DeepEqual(u, FromHz(Unregister("a"))) {
+			test.Fatalf("received %+v instead of the unregister of the connection", u)
+		}
+	case <-time.After(5 * time.Second):
+		test.Fatalf("idle connection was not closed after 5 seconds")
+	}
+}
+
+func TestEgressLimit(test *testing.T) {
+	// use this for logging so that messages are not shown in general,
+	// only if the test fails
+	var testLog *slog.Logger = slogt.New(test)
+
+	toHz := make(chan ToHz, 1)
+	fromHz := make(chan FromHz, 1)
+	hz := NewHorizontalApi(testLog, fromHz)
+	hz.SetEgressLimits(0, 10000)
+	defer func() {
+		hz.cancel()
+		hz.wg.Wait()
+	}()
+
+	cWrite, cRead := net.Pipe()
+	defer cRead.Close()
+	ctx, cfunc := context.WithCancel(context.Background())
+	defer cfunc()
+
+	hz.wg.Add(2)
+	go hz.handleConnection(cRead, Conn[chan<- ToHz]{Data: toHz, Ctx: ctx, Cfunc: cfunc})
+	go hz.writeToConnection(cWrite, Conn[<-chan ToHz]{Data: toHz, Ctx: ctx, Cfunc: cfunc})
+
+	// measure how long it takes until msg is received
+	transmit := func(msg ToHz) time.Duration {
+		test.Helper()
+		start := time.Now()
+		toHz <- msg
+		select {
+		case <-fromHz:
+		case <-time.After(5 * time.Second):
+			test.Fatalf("timeout for reading the to be received message after 5 seconds")
+		}
+		return time.Since(start)
+	}
+
+	// 15000 bytes exceed the limit by about 5000 bytes -> 0.5s
+	if d := transmit(Push{Payload: make([]byte, 15000)}); d < 400*time.Millisecond {
+		test.Fatalf("push exceeding the limit was sent after %v", d)
+	}
+	// PoW messages are not delayed
+	if d := transmit(PowPoW{Cookie: make([]byte, 10000)}); d > 200*time.Millisecond {
+		test.Fatalf("PoW message was delayed by %v", d)
+	}
+	// but the other messages have to wait for its bytes -> 1s
+	if d := transmit(Push{}); d < 800*time.Millisecond {
+		test.Fatalf("push after the PoW message was sent after %v", d)
+	}
+	// control messages are sent while a push waits for the limits
+	toHz <- Push{Payload: make([]byte, 15000)}
+	if d := transmit(PowReq{}); d > 200*time.Millisecond {
+		test.Fatalf("PoW request behind a waiting push was delayed by %v", d)
+	}
+	select {
+	case m := <-fromHz:
+		if _, ok := m.(Push); !ok {
+			test.Fatalf("received %+v instead of the waiting push", m)
+		}
+	case <-time.After(5 * time.Second):
+		test.Fatalf("waiting push was not received after 5 seconds")
+	}
+}
+
+func TestEgressBacklog(test *testing.T) {
+	// use this for logging so that messages are not shown in general,
+	// only if the test fails
+	var testLog *slog.Logger = slogt.New(test)
+
+	toHz := make(chan ToHz, 1)
+	fromHz := make(chan FromHz, 1)
+	hz := NewHorizontalApi(testLog, fromHz)
+	hz.SetEgressLimits(0, 10000)
+	defer func() {
+		hz.cancel()
+		hz.wg.Wait()
+	}()
+
+	cWrite, cRead := net.Pipe()
+	defer cRead.Close()
+	ctx, cfunc := context.WithCancel(context.Background())
+	defer cfunc()
+
+	hz.wg.Add(2)
+	go hz.handleConnection(cRead, Conn[chan<- ToHz]{Data: toHz, Ctx: ctx, Cfunc: cfunc})
+	go hz.writeToConnection(cWrite, Conn[<-chan ToHz]{Data: toHz, Ctx: ctx, Cfunc: cfunc})
+
+	// the writer keeps reading while a push waits for the limits, even once
+	// the backlog is full
+	toHz <- Push{Payload: make([]byte, 15000)}
+	for i := 0; i < MAX_WRITE_BACKLOG+10; i++ {
+		select {
+		case toHz <- Push{MessageID: uint16(i)}:
+		case <-time.After(time.Second):
+			test.Fatalf("writer stopped reading after %d pushes", i)
+		}
+	}
+	toHz <- PowReq{}
+	select {
+	case m := <-fromHz:
+		if _, ok := m.(PowReq); !ok {
+			test.Fatalf("received %+v instead of the PoW request", m)
+		}
+	case <-time.After(time.Second):
+		test.Fatalf("PoW request behind a full backlog was not received")
+	}
+
+	// the pushes exceeding the backlog are dropped
+	pushes := 0
+counting:
+	for {
+		select {
+		case <-fromHz:
+			pushes++
+		case <-time.After(time.Second):
+			break counting
+		}
+	}
+	if pushes != MAX_WRITE_BACKLOG+1 {
+		test.Fatalf("%d pushes were sent, want %d", pushes, MAX_WRITE_BACKLOG+1)
+	}
+}
+
+// extension message used for testing, the kind is encoded in the first byte
+type testExtension []byte
+
+func (e testExtension) Kind() ExtensionKind            { return ExtensionKind(e[0]) }
+func (e testExtension) MarshalBinary() ([]byte, error) { return e, nil }
+
+// extension message used for testing which is control traffic
+type testControlExtension struct{ testExtension }
+
+func (testControlExtension) IsControl() bool { return true }
+
+func TestExtension(test *testing.T) {
+	// use this for logging so that messages are not shown in general,
+	// only if the test fails
+	var testLog *slog.Logger = slogt.New(test)
+
+	toHz := make(chan ToHz, 1)
+	fromHz := make(chan FromHz, 1)
+	hz := NewHorizontalApi(testLog, fromHz)
+	defer func() {
+		hz.cancel()
+		hz.wg.Wait()
+	}()
+
+	decode := func(data []byte) (ExtensionMessage, error) {
+		return testExtension(data), nil
+	}
+	if err := hz.RegisterExtension(1, decode); err != nil {
+		test.Fatalf("registering the extension failed with %v", err)
+	}
+	if err := hz.RegisterExtension(1, decode); !errors.Is(err, ErrExtensionRegistered) {
+		test.Fatalf("registering the extension twice returned %v", err)
+	}
+
+	cWrite, cRead := net.Pipe()
+	defer cRead.Close()
+	ctx, cfunc := context.WithCancel(context.Background())
+	defer cfunc()
+
+	hz.wg.Add(2)
+	go hz.handleConnection(cRead, Conn[chan<- ToHz]{Data: toHz, Ctx: ctx, Cfunc: cfunc})
+	go hz.writeToConnection(cWrite, Conn[<-chan ToHz]{Data: toHz, Ctx: ctx, Cfunc: cfunc})
+
+	// the unknown kind must be dropped so that only the known one is received
+	toHz <- Extension{Message: testExtension{2, 0x20}}
+	e := Extension{Message: testExtension{1, 0x40, 0x41}}
+	toHz <- e
+
+	var u FromHz
+	select {
+	case u = <-fromHz:
+	case <-time.After(5 * time.Second):
+		test.Fatalf("timeout for reading the to be received message after 5 seconds")
+	}
+	if !reflect.DeepEqual(u, FromHz(e)) {
+		test.Fatalf("didn't reveice the message previously sent. Sent %+v rcved%+v", e, u)
+	}
+
+	// only extensions which say so are control traffic
+	if e.isControl() || !(Extension{Message: testControlExtension{e.Message.(testExtension)}}).isControl() {
+		test.Fatalf("extensions are control traffic regardless of their message")
 	}
 }
 
@@ -14,12 +14,12 @@ type ConnChall capnp.Struct
 const ConnChall_TypeID = 0xa38eefc82dcb0278
 
 func NewConnChall(s *capnp.Segment) (ConnChall, error) {
-	st, err := capnp.NewStruct(s, capnp.ObjectSize{DataSize: 0, PointerCount: 1})
+	st, err := capnp.NewStruct(s, capnp.ObjectSize{DataSize: 0, PointerCount: 2})
 	return ConnChall(st), err
 }
 
 func NewRootConnChall(s *capnp.Segment) (ConnChall, error) {
-	st, err := capnp.NewRootStruct(s, capnp.ObjectSize{DataSize: 0, PointerCount: 1})
+	st, err := capnp.NewRootStruct(s, capnp.ObjectSize{DataSize: 0, PointerCount: 2})
 	return ConnChall(st), err
 }
 
@@ -68,12 +68,25 @@ func (s ConnChall) SetCookie(v []byte) error {
 	return capnp.Struct(s).SetData(0, v)
 }
 
+func (s ConnChall) NodeId() ([]byte, error) {
+	p, err := capnp.Struct(s).Ptr(1)
+	return []byte(p.Data()), err
+}
+
+func (s ConnChall) HasNodeId() bool {
+	return capnp.Struct(s).HasPtr(1)
+}
+
+func (s ConnChall) SetNodeId(v []byte) error {
+	return capnp.Struct(s).SetData(1, v)
+}
+
 // ConnChall_List is a list of ConnChall.
 type ConnChall_List = capnp.StructList[ConnChall]
 
 // NewConnChall creates a new list of ConnChall.
 func NewConnChall_List(s *capnp.Segment, sz int32) (ConnChall_List, error) {
-	l, err := capnp.NewCompositeList(s, capnp.ObjectSize{DataSize: 0, PointerCount: 1}, sz)
+	l, err := capnp.NewCompositeList(s, capnp.ObjectSize{DataSize: 0, PointerCount: 2}, sz)
 	return capnp.StructList[ConnChall](l), err
 }
 
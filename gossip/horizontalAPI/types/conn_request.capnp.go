@@ -14,12 +14,12 @@ type ConnReq capnp.Struct
 const ConnReq_TypeID = 0xe56584347df7156c
 
 func NewConnReq(s *capnp.Segment) (ConnReq, error) {
-	st, err := capnp.NewStruct(s, capnp.ObjectSize{DataSize: 0, PointerCount: 0})
+	st, err := capnp.NewStruct(s, capnp.ObjectSize{DataSize: 0, PointerCount: 1})
 	return ConnReq(st), err
 }
 
 func NewRootConnReq(s *capnp.Segment) (ConnReq, error) {
-	st, err := capnp.NewRootStruct(s, capnp.ObjectSize{DataSize: 0, PointerCount: 0})
+	st, err := capnp.NewRootStruct(s, capnp.ObjectSize{DataSize: 0, PointerCount: 1})
 	return ConnReq(st), err
 }
 
@@ -55,13 +55,25 @@ func (s ConnReq) Message() *capnp.Message {
 func (s ConnReq) Segment() *capnp.Segment {
 	return capnp.Struct(s).Segment()
 }
+func (s ConnReq) NodeId() ([]byte, error) {
+	p, err := capnp.Struct(s).Ptr(0)
+	return []byte(p.Data()), err
+}
+
+func (s ConnReq) HasNodeId() bool {
+	return capnp.Struct(s).HasPtr(0)
+}
+
+func (s ConnReq) SetNodeId(v []byte) error {
+	return capnp.Struct(s).SetData(0, v)
+}
 
 // ConnReq_List is a list of ConnReq.
 type ConnReq_List = capnp.StructList[ConnReq]
 
 // NewConnReq creates a new list of ConnReq.
 func NewConnReq_List(s *capnp.Segment, sz int32) (ConnReq_List, error) {
-	l, err := capnp.NewCompositeList(s, capnp.ObjectSize{DataSize: 0, PointerCount: 0}, sz)
+	l, err := capnp.NewCompositeList(s, capnp.ObjectSize{DataSize: 0, PointerCount: 1}, sz)
 	return capnp.StructList[ConnReq](l), err
 }
 
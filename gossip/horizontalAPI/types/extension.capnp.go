@@ -0,0 +1,94 @@
+// Code generated by capnpc-go. DO NOT EDIT.
+
+package types
+
+import (
+	capnp "capnproto.org/go/capnp/v3"
+	text "capnproto.org/go/capnp/v3/encoding/text"
+)
+
+// Envelope for messages of kinds registered at runtime on the horizontalApi.
+type Extension capnp.Struct
+
+// Extension_TypeID is the unique identifier for the type Extension.
+const Extension_TypeID = 0xae11f5d8b7e133f2
+
+func NewExtension(s *capnp.Segment) (Extension, error) {
+	st, err := capnp.NewStruct(s, capnp.ObjectSize{DataSize: 8, PointerCount: 1})
+	return Extension(st), err
+}
+
+func NewRootExtension(s *capnp.Segment) (Extension, error) {
+	st, err := capnp.NewRootStruct(s, capnp.ObjectSize{DataSize: 8, PointerCount: 1})
+	return Extension(st), err
+}
+
+func ReadRootExtension(msg *capnp.Message) (Extension, error) {
+	root, err := msg.Root()
+	return Extension(root.Struct()), err
+}
+
+func (s Extension) String() string {
+	str, _ := text.Marshal(0xae11f5d8b7e133f2, capnp.Struct(s))
+	return str
+}
+
+func (s Extension) EncodeAsPtr(seg *capnp.Segment) capnp.Ptr {
+	return capnp.Struct(s).EncodeAsPtr(seg)
+}
+
+func (Extension) DecodeFromPtr(p capnp.Ptr) Extension {
+	return Extension(capnp.Struct{}.DecodeFromPtr(p))
+}
+
+func (s Extension) ToPtr() capnp.Ptr {
+	return capnp.Struct(s).ToPtr()
+}
+func (s Extension) IsValid() bool {
+	return capnp.Struct(s).IsValid()
+}
+
+func (s Extension) Message() *capnp.Message {
+	return capnp.Struct(s).Message()
+}
+
+func (s Extension) Segment() *capnp.Segment {
+	return capnp.Struct(s).Segment()
+}
+func (s Extension) Kind() uint16 {
+	return capnp.Struct(s).Uint16(0)
+}
+
+func (s Extension) SetKind(v uint16) {
+	capnp.Struct(s).SetUint16(0, v)
+}
+
+func (s Extension) Data() ([]byte, error) {
+	p, err := capnp.Struct(s).Ptr(0)
+	return []byte(p.Data()), err
+}
+
+func (s Extension) HasData() bool {
+	return capnp.Struct(s).HasPtr(0)
+}
+
+func (s Extension) SetData(v []byte) error {
+	return capnp.Struct(s).SetData(0, v)
+}
+
+// Extension_List is a list of Extension.
+type Extension_List = capnp.StructList[Extension]
+
+// NewExtension creates a new list of Extension.
+func NewExtension_List(s *capnp.Segment, sz int32) (Extension_List, error) {
+	l, err := capnp.NewCompositeList(s, capnp.ObjectSize{DataSize: 8, PointerCount: 1}, sz)
+	return capnp.StructList[Extension](l), err
+}
+
+// Extension_Future is a wrapper for a Extension promised by a client call.
+type Extension_Future struct{ *capnp.Future }
+
+func (f Extension_Future) Struct() (Extension, error) {
+	p, err := f.Future.Ptr()
+	return Extension(p.Struct()), err
+}
@@ -0,0 +1,97 @@
+// Code generated by capnpc-go. DO NOT EDIT.
+
+package types
+
+import (
+	capnp "capnproto.org/go/capnp/v3"
+	text "capnproto.org/go/capnp/v3/encoding/text"
+)
+
+// Request to add the connection to the broadcast tree (Plumtree) on the horizontalApi.
+type Graft capnp.Struct
+
+// Graft_TypeID is the unique identifier for the type Graft.
+const Graft_TypeID = 0x9e6fb35beb97f175
+
+func NewGraft(s *capnp.Segment) (Graft, error) {
+	st, err := capnp.NewStruct(s, capnp.ObjectSize{DataSize: 0, PointerCount: 1})
+	return Graft(st), err
+}
+
+func NewRootGraft(s *capnp.Segment) (Graft, error) {
+	st, err := capnp.NewRootStruct(s, capnp.ObjectSize{DataSize: 0, PointerCount: 1})
+	return Graft(st), err
+}
+
+func ReadRootGraft(msg *capnp.Message) (Graft, error) {
+	root, err := msg.Root()
+	return Graft(root.Struct()), err
+}
+
+func (s Graft) String() string {
+	str, _ := text.Marshal(0x9e6fb35beb97f175, capnp.Struct(s))
+	return str
+}
+
+func (s Graft) EncodeAsPtr(seg *capnp.Segment) capnp.Ptr {
+	return capnp.Struct(s).EncodeAsPtr(seg)
+}
+
+func (Graft) DecodeFromPtr(p capnp.Ptr) Graft {
+	return Graft(capnp.Struct{}.DecodeFromPtr(p))
+}
+
+func (s Graft) ToPtr() capnp.Ptr {
+	return capnp.Struct(s).ToPtr()
+}
+func (s Graft) IsValid() bool {
+	return capnp.Struct(s).IsValid()
+}
+
+func (s Graft) Message() *capnp.Message {
+	return capnp.Struct(s).Message()
+}
+
+func (s Graft) Segment() *capnp.Segment {
+	return capnp.Struct(s).Segment()
+}
+func (s Graft) MessageIds() (capnp.UInt16List, error) {
+	p, err := capnp.Struct(s).Ptr(0)
+	return capnp.UInt16List(p.List()), err
+}
+
+func (s Graft) HasMessageIds() bool {
+	return capnp.Struct(s).HasPtr(0)
+}
+
+func (s Graft) SetMessageIds(v capnp.UInt16List) error {
+	return capnp.Struct(s).SetPtr(0, v.ToPtr())
+}
+
+// NewMessageIds sets the messageIds field to a newly
+// allocated capnp.UInt16List, preferring placement in s's segment.
+func (s Graft) NewMessageIds(n int32) (capnp.UInt16List, error) {
+	l, err := capnp.NewUInt16List(capnp.Struct(s).Segment(), n)
+	if err != nil {
+		return capnp.UInt16List{}, err
+	}
+	err = capnp.Struct(s).SetPtr(0, l.ToPtr())
+	return l, err
+}
+
+// Graft_List is a list of Graft.
+type Graft_List = capnp.StructList[Graft]
+
+// NewGraft creates a new list of Graft.
+func NewGraft_List(s *capnp.Segment, sz int32) (Graft_List, error) {
+	l, err := capnp.NewCompositeList(s, capnp.ObjectSize{DataSize: 0, PointerCount: 1}, sz)
+	return capnp.StructList[Graft](l), err
+}
+
+// Graft_Future is a wrapper for a Graft promised by a client call.
+type Graft_Future struct{ *capnp.Future }
+
+func (f Graft_Future) Struct() (Graft, error) {
+	p, err := f.Future.Ptr()
+	return Graft(p.Struct()), err
+}
@@ -0,0 +1,97 @@
+// Code generated by capnpc-go. DO NOT EDIT.
+
+package types
+
+import (
+	capnp "capnproto.org/go/capnp/v3"
+	text "capnproto.org/go/capnp/v3/encoding/text"
+)
+
+// Announcement of messages the sender has received (lazy push) on the horizontalApi.
+type IHave capnp.Struct
+
+// IHave_TypeID is the unique identifier for the type IHave.
+const IHave_TypeID = 0xc88a6b346673aaac
+
+func NewIHave(s *capnp.Segment) (IHave, error) {
+	st, err := capnp.NewStruct(s, capnp.ObjectSize{DataSize: 0, PointerCount: 1})
+	return IHave(st), err
+}
+
+func NewRootIHave(s *capnp.Segment) (IHave, error) {
+	st, err := capnp.NewRootStruct(s, capnp.ObjectSize{DataSize: 0, PointerCount: 1})
+	return IHave(st), err
+}
+
+func ReadRootIHave(msg *capnp.Message) (IHave, error) {
+	root, err := msg.Root()
+	return IHave(root.Struct()), err
+}
+
+func (s IHave) String() string {
+	str, _ := text.Marshal(0xc88a6b346673aaac, capnp.Struct(s))
+	return str
+}
+
+func (s IHave) EncodeAsPtr(seg *capnp.Segment) capnp.Ptr {
+	return capnp.Struct(s).EncodeAsPtr(seg)
+}
+
+func (IHave) DecodeFromPtr(p capnp.Ptr) IHave {
+	return IHave(capnp.Struct{}.DecodeFromPtr(p))
+}
+
+func (s IHave) ToPtr() capnp.Ptr {
+	return capnp.Struct(s).ToPtr()
+}
+func (s IHave) IsValid() bool {
+	return capnp.Struct(s).IsValid()
+}
+
+func (s IHave) Message() *capnp.Message {
+	return capnp.Struct(s).Message()
+}
+
+func (s IHave) Segment() *capnp.Segment {
+	return capnp.Struct(s).Segment()
+}
+func (s IHave) MessageIds() (capnp.UInt16List, error) {
+	p, err := capnp.Struct(s).Ptr(0)
+	return capnp.UInt16List(p.List()), err
+}
+
+func (s IHave) HasMessageIds() bool {
+	return capnp.Struct(s).HasPtr(0)
+}
+
+func (s IHave) SetMessageIds(v capnp.UInt16List) error {
+	return capnp.Struct(s).SetPtr(0, v.ToPtr())
+}
+
+// NewMessageIds sets the messageIds field to a newly
+// allocated capnp.UInt16List, preferring placement in s's segment.
+func (s IHave) NewMessageIds(n int32) (capnp.UInt16List, error) {
+	l, err := capnp.NewUInt16List(capnp.Struct(s).Segment(), n)
+	if err != nil {
+		return capnp.UInt16List{}, err
+	}
+	err = capnp.Struct(s).SetPtr(0, l.ToPtr())
+	return l, err
+}
+
+// IHave_List is a list of IHave.
+type IHave_List = capnp.StructList[IHave]
+
+// NewIHave creates a new list of IHave.
+func NewIHave_List(s *capnp.Segment, sz int32) (IHave_List, error) {
+	l, err := capnp.NewCompositeList(s, capnp.ObjectSize{DataSize: 0, PointerCount: 1}, sz)
+	return capnp.StructList[IHave](l), err
+}
+
+// IHave_Future is a wrapper for a IHave promised by a client call.
+type IHave_Future struct{ *capnp.Future }
+
+func (f IHave_Future) Struct() (IHave, error) {
+	p, err := f.Future.Ptr()
+	return IHave(p.Struct()), err
+}
@@ -0,0 +1,97 @@
+// Code generated by capnpc-go. DO NOT EDIT.
+
+package types
+
+import (
+	capnp "capnproto.org/go/capnp/v3"
+	text "capnproto.org/go/capnp/v3/encoding/text"
+)
+
+// Request for announced messages on the horizontalApi.
+type IWant capnp.Struct
+
+// IWant_TypeID is the unique identifier for the type IWant.
+const IWant_TypeID = 0xad7b890b2851c5b8
+
+func NewIWant(s *capnp.Segment) (IWant, error) {
+	st, err := capnp.NewStruct(s, capnp.ObjectSize{DataSize: 0, PointerCount: 1})
+	return IWant(st), err
+}
+
+func NewRootIWant(s *capnp.Segment) (IWant, error) {
+	st, err := capnp.NewRootStruct(s, capnp.ObjectSize{DataSize: 0, PointerCount: 1})
+	return IWant(st), err
+}
+
+func ReadRootIWant(msg *capnp.Message) (IWant, error) {
+	root, err := msg.Root()
+	return IWant(root.Struct()), err
+}
+
+func (s IWant) String() string {
+	str, _ := text.Marshal(0xad7b890b2851c5b8, capnp.Struct(s))
+	return str
+}
+
+func (s IWant) EncodeAsPtr(seg *capnp.Segment) capnp.Ptr {
+	return capnp.Struct(s).EncodeAsPtr(seg)
+}
+
+func (IWant) DecodeFromPtr(p capnp.Ptr) IWant {
+	return IWant(capnp.Struct{}.DecodeFromPtr(p))
+}
+
+func (s IWant) ToPtr() capnp.Ptr {
+	return capnp.Struct(s).ToPtr()
+}
+func (s IWant) IsValid() bool {
+	return capnp.Struct(s).IsValid()
+}
+
+func (s IWant) Message() *capnp.Message {
+	return capnp.Struct(s).Message()
+}
+
+func (s IWant) Segment() *capnp.Segment {
+	return capnp.Struct(s).Segment()
+}
+func (s IWant) MessageIds() (capnp.UInt16List, error) {
+	p, err := capnp.Struct(s).Ptr(0)
+	return capnp.UInt16List(p.List()), err
+}
+
+func (s IWant) HasMessageIds() bool {
+	return capnp.Struct(s).HasPtr(0)
+}
+
+func (s IWant) SetMessageIds(v capnp.UInt16List) error {
+	return capnp.Struct(s).SetPtr(0, v.ToPtr())
+}
+
+// NewMessageIds sets the messageIds field to a newly
+// allocated capnp.UInt16List, preferring placement in s's segment.
+func (s IWant) NewMessageIds(n int32) (capnp.UInt16List, error) {
+	l, err := capnp.NewUInt16List(capnp.Struct(s).Segment(), n)
+	if err != nil {
+		return capnp.UInt16List{}, err
+	}
+	err = capnp.Struct(s).SetPtr(0, l.ToPtr())
+	return l, err
+}
+
+// IWant_List is a list of IWant.
+type IWant_List = capnp.StructList[IWant]
+
+// NewIWant creates a new list of IWant.
+func NewIWant_List(s *capnp.Segment, sz int32) (IWant_List, error) {
+	l, err := capnp.NewCompositeList(s, capnp.ObjectSize{DataSize: 0, PointerCount: 1}, sz)
+	return capnp.StructList[IWant](l), err
+}
+
+// IWant_Future is a wrapper for a IWant promised by a client call.
+type IWant_Future struct{ *capnp.Future }
+
+func (f IWant_Future) Struct() (IWant, error) {
+	p, err := f.Future.Ptr()
+	return IWant(p.Struct()), err
+}
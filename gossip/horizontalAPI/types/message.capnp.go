@@ -22,10 +22,16 @@ const (
 	Message_body_Which_powChall  Message_body_Which = 4
 	Message_body_Which_powPoW    Message_body_Which = 5
 	Message_body_Which_powReq    Message_body_Which = 6
+	Message_body_Which_iHave     Message_body_Which = 7
+	Message_body_Which_graft     Message_body_Which = 8
+	Message_body_Which_prune     Message_body_Which = 9
+	Message_body_Which_subscribe Message_body_Which = 10
+	Message_body_Which_iWant     Message_body_Which = 11
+	Message_body_Which_extension Message_body_Which = 12
 )
 
 func (w Message_body_Which) String() string {
-	const s = "pushconnChallconnPoWconnReqpowChallpowPoWpowReq"
+	const s = "pushconnChallconnPoWconnReqpowChallpowPoWpowReqiHavegraftprunesubscribeiWantextension"
 	switch w {
 	case Message_body_Which_push:
 		return s[0:4]
@@ -41,6 +47,18 @@ func (w Message_body_Which) String() string {
 		return s[35:41]
 	case Message_body_Which_powReq:
 		return s[41:47]
+	case Message_body_Which_iHave:
+		return s[47:52]
+	case Message_body_Which_graft:
+		return s[52:57]
+	case Message_body_Which_prune:
+		return s[57:62]
+	case Message_body_Which_subscribe:
+		return s[62:71]
+	case Message_body_Which_iWant:
+		return s[71:76]
+	case Message_body_Which_extension:
+		return s[76:85]
 
 	}
 	return "Message_body_Which(" + strconv.FormatUint(uint64(w), 10) + ")"
@@ -331,6 +349,198 @@ func (s Message_body) NewPowReq() (PowReq, error) {
 	return ss, err
 }
 
+func (s Message_body) IHave() (IHave, error) {
+	if capnp.Struct(s).Uint16(0) != 7 {
+		panic("Which() != iHave")
+	}
+	p, err := capnp.Struct(s).Ptr(0)
+	return IHave(p.Struct()), err
+}
+
+func (s Message_body) HasIHave() bool {
+	if capnp.Struct(s).Uint16(0) != 7 {
+		return false
+	}
+	return capnp.Struct(s).HasPtr(0)
+}
+
+func (s Message_body) SetIHave(v IHave) error {
+	capnp.Struct(s).SetUint16(0, 7)
+	return capnp.Struct(s).SetPtr(0, capnp.Struct(v).ToPtr())
+}
+
+// NewIHave sets the iHave field to a newly
+// allocated IHave struct, preferring placement in s's segment.
+func (s Message_body) NewIHave() (IHave, error) {
+	capnp.Struct(s).SetUint16(0, 7)
+	ss, err := NewIHave(capnp.Struct(s).Segment())
+	if err != nil {
+		return IHave{}, err
+	}
+	err = capnp.Struct(s).SetPtr(0, capnp.Struct(ss).ToPtr())
+	return ss, err
+}
+
+func (s Message_body) Graft() (Graft, error) {
+	if capnp.Struct(s).Uint16(0) != 8 {
+		panic("Which() != graft")
+	}
+	p, err := capnp.Struct(s).Ptr(0)
+	return Graft(p.Struct()), err
+}
+
+func (s Message_body) HasGraft() bool {
+	if capnp.Struct(s).Uint16(0) != 8 {
+		return false
+	}
+	return capnp.Struct(s).HasPtr(0)
+}
+
+func (s Message_body) SetGraft(v Graft) error {
+	capnp.Struct(s).SetUint16(0, 8)
+	return capnp.Struct(s).SetPtr(0, capnp.Struct(v).ToPtr())
+}
+
+// NewGraft sets the graft field to a newly
+// allocated Graft struct, preferring placement in s's segment.
+func (s Message_body) NewGraft() (Graft, error) {
+	capnp.Struct(s).SetUint16(0, 8)
+	ss, err := NewGraft(capnp.Struct(s).Segment())
+	if err != nil {
+		return Graft{}, err
+	}
+	err = capnp.Struct(s).SetPtr(0, capnp.Struct(ss).ToPtr())
+	return ss, err
+}
+
+func (s Message_body) Prune() (Prune, error) {
+	if capnp.Struct(s).Uint16(0) != 9 {
+		panic("Which() != prune")
+	}
+	p, err := capnp.Struct(s).Ptr(0)
+	return Prune(p.Struct()), err
+}
+
+func (s Message_body) HasPrune() bool {
+	if capnp.Struct(s).Uint16(0) != 9 {
+		return false
+	}
+	return capnp.Struct(s).HasPtr(0)
+}
+
+func (s Message_body) SetPrune(v Prune) error {
+	capnp.Struct(s).SetUint16(0, 9)
+	return capnp.Struct(s).SetPtr(0, capnp.Struct(v).ToPtr())
+}
+
+// NewPrune sets the prune field to a newly
+// allocated Prune struct, preferring placement in s's segment.
+func (s Message_body) NewPrune() (Prune, error) {
+	capnp.Struct(s).SetUint16(0, 9)
+	ss, err := NewPrune(capnp.Struct(s).Segment())
+	if err != nil {
+		return Prune{}, err
+	}
+	err = capnp.Struct(s).SetPtr(0, capnp.Struct(ss).ToPtr())
+	return ss, err
+}
+
+func (s Message_body) Subscribe() (Subscribe, error) {
+	if capnp.Struct(s).Uint16(0) != 10 {
+		panic("Which() != subscribe")
+	}
+	p, err := capnp.Struct(s).Ptr(0)
+	return Subscribe(p.Struct()), err
+}
+
+func (s Message_body) HasSubscribe() bool {
+	if capnp.Struct(s).Uint16(0) != 10 {
+		return false
+	}
+	return capnp.Struct(s).HasPtr(0)
+}
+
+func (s Message_body) SetSubscribe(v Subscribe) error {
+	capnp.Struct(s).SetUint16(0, 10)
+	return capnp.Struct(s).SetPtr(0, capnp.Struct(v).ToPtr())
+}
+
+// NewSubscribe sets the subscribe field to a newly
+// allocated Subscribe struct, preferring placement in s's segment.
+func (s Message_body) NewSubscribe() (Subscribe, error) {
+	capnp.Struct(s).SetUint16(0, 10)
+	ss, err := NewSubscribe(capnp.Struct(s).Segment())
+	if err != nil {
+		return Subscribe{}, err
+	}
+	err = capnp.Struct(s).SetPtr(0, capnp.Struct(ss).ToPtr())
+	return ss, err
+}
+
+func (s Message_body) IWant() (IWant, error) {
+	if capnp.Struct(s).Uint16(0) != 11 {
+		panic("Which() != iWant")
+	}
+	p, err := capnp.Struct(s).Ptr(0)
+	return IWant(p.Struct()), err
+}
+
+func (s Message_body) HasIWant() bool {
+	if capnp.Struct(s).Uint16(0) != 11 {
+		return false
+	}
+	return capnp.Struct(s).HasPtr(0)
+}
+
+func (s Message_body) SetIWant(v IWant) error {
+	capnp.Struct(s).SetUint16(0, 11)
+	return capnp.Struct(s).SetPtr(0, capnp.Struct(v).ToPtr())
+}
+
+// NewIWant sets the iWant field to a newly
+// allocated IWant struct, preferring placement in s's segment.
+func (s Message_body) NewIWant() (IWant, error) {
+	capnp.Struct(s).SetUint16(0, 11)
+	ss, err := NewIWant(capnp.Struct(s).Segment())
+	if err != nil {
+		return IWant{}, err
+	}
+	err = capnp.Struct(s).SetPtr(0, capnp.Struct(ss).ToPtr())
+	return ss, err
+}
+
+func (s Message_body) Extension() (Extension, error) {
+	if capnp.Struct(s).Uint16(0) != 12 {
+		panic("Which() != extension")
+	}
+	p, err := capnp.Struct(s).Ptr(0)
+	return Extension(p.Struct()), err
+}
+
+func (s Message_body) HasExtension() bool {
+	if capnp.Struct(s).Uint16(0) != 12 {
+		return false
+	}
+	return capnp.Struct(s).HasPtr(0)
+}
+
+func (s Message_body) SetExtension(v Extension) error {
+	capnp.Struct(s).SetUint16(0, 12)
+	return capnp.Struct(s).SetPtr(0, capnp.Struct(v).ToPtr())
+}
+
+// NewExtension sets the extension field to a newly
+// allocated Extension struct, preferring placement in s's segment.
+func (s Message_body) NewExtension() (Extension, error) {
+	capnp.Struct(s).SetUint16(0, 12)
+	ss, err := NewExtension(capnp.Struct(s).Segment())
+	if err != nil {
+		return Extension{}, err
+	}
+	err = capnp.Struct(s).SetPtr(0, capnp.Struct(ss).ToPtr())
+	return ss, err
+}
+
 // Message_List is a list of Message.
 type Message_List = capnp.StructList[Message]
 
@@ -377,117 +587,215 @@ func (p Message_body_Future) PowPoW() PowPoW_Future {
 func (p Message_body_Future) PowReq() PowReq_Future {
 	return PowReq_Future{Future: p.Future.Field(0, nil)}
 }
-
-const schema_d06424cd5634d6a3 = "x\xda\xe4Wo\x88\x1cg\x19\x7f\x9ewfw\xb2\xeb" +
-	"-\x97\xf1\xdd\x83\xb4\x06\xe6m\xac\xd0\xc6&&^\x10" +
-	"s\x04\xae\xb9\x13\xd4\xca\xc1\xcem\xeaF\xe5z\xce\xed" +
-	"\xbe\xb7387\xef\xdc\xccl\xd6-\x96\x80\x18\x11\xc1" +
-	"?T\xce\xa8%\xf4\x9f\x15\xa3V<k\xb0U\x14\x9a" +
-	"*hC!V\x0d\xb4r\x90\x14\x82\xad\xf6\xac\xf6K" +
-	"\xb9X\xfb\xca\xcc\xce\xce]\xd3\xcd^\xceo\xd2\x8f;" +
-	"\xcf\xfb>\xbf\xe7\xfd=\xcf\xf3{\x9e\xdd\xf7\xa8r\xbb" +
-	"\xba\xbf4\xae\x011\x8f\xe4\xf2\xf2\xb3\xe4\xdc\x9e\xdf\xbd" +
-	"\xf2\xd5\x87A\x7f\x17\xca\xedwO\xddW\xfe\xd2\xb7\xef" +
-	"\x87\x1cj\x00\xa3'\x94\xddH\x97\x14\x8d.)\x06\xfd" +
-	"\xb32\x0e(\x8f\x9f\xfe\xcb\xf9\x1bN\x1c}\x04\xcc2" +
-	"\xa2|\xf8\xc2\x81\x8f?ss\xe3|\xf7<=\xac\xbe" +
-	"@\xa7T\x8dN\xa9\x06=\xa5\xc6\xc7\xf5\xa5\xcb\xfa\x89" +
-	"\xd5\xaf\xfc\x14\xf4\x1bQ\xbe\x10>p\xaa\xb4t\xead" +
-	"\xea\xfdA\xf5F\xa4\xcb\xaaF\x97U\x83\xfe+9\xfe" +
-	"\xe4\xc9\xaf\xdf\xf3\xf7mw<\x06\xe6\x08\xa2,\xaf\xbc" +
-	"\xf4\xc6\x93\xdf\xbb|o\xea}&\xf77\xea\xe44\xea" +
-	"\xe4\x0c\xfa`\xae\x0d(G\x7f\xf2\xeb\xf0\xc5s\xef9" +
-	"\x0b\xe6N\xdc\x10\xda\x9d\xa8%\x00\x0b\xf9\"\x02\x8e\xb6" +
-	"\xf2_C@\xf9\xea\xc9\x0f\xb4N\xfb\x9fx\x0a\xf4\x1d" +
-	"(\xcf\x9f{\xed\x87O\xbd\xfb\x07\x8f\x83\x1a{~\xa4" +
-	"p\x85.\x174\xba\\0\x80\xc8?\x96\x7f\xde:\xf4" +
-	"\xe3o\xfe\xa6\xfb\xc4\x97?\xf6\xc4\xec\xda\xb3\xaf\\H" +
-	"\x83\xf8C\xe1y\xbaR\xd0\xe8J\xc1\xa0#\xc58\x88" +
-	"\x83O\\*\x1e}\xef\xaeg\xc0\xd4\x11\xe5/w\xc9" +
-	"\x7f\xdcy\xd3\xd1\x17\xd3\xe3\x97\x8aO\xd3\xd5\xa2FW" +
-	"\x8b\x06\xbd\xe9\x1d\x7f\x05\x94\xee\xc8k\xf7\x1c\xf8\x02\xbf" +
-	"\x0c\xfa\x0d(\x17?\xfc\xd8\xe3\x17\xef\xba\xef\xa1$\x8c" +
-	"\xd1\xdf\x0f\x15\x91>7\xa4\xd1\xe7\x86\x0c\xf8\xa2\x8c:" +
-	">\x0f\xdfW\x17\x8a\xe7\xcd\xd6m\xcbu\xb9\xd7\xe4{" +
-	"\xeb\x96\xef\xf9c\x93\xc2\xf3&\x87\xe3\x8f\x15DSE" +
-	"\"\xef\xfa\xc6\xfd\xe6\xaf.|\xf9\xb7`\xaa\x04\x0f\xef" +
-	"C\x1c\x02\xd8\x8fcDN\xf3\xd0\x17^\x83im'" +
-	"\xb2\x99\xc5<\xdef\x99;6/\x02\x16\xd9\x9c9\x9e" +
-	"\x139\x96\xcb*\xa2\xc6\x84\x97|\xb2E\xe0\xdc-\xbc" +
-	"h\xdcr\x0f\xfb\xce^\x00SUT\x00\x15\x01\xf4\xd2" +
-	"\x98^2\xcc#\x0a\x9a\x9f&8^\x17\xe23\x0e\xef" +
-	"\x13\xc6\x07\xd30\xbeC$\xf7\xeaA\xc7\x8fx\xa1\xc1" +
-	"\x1aVd\xb1V\xc8\x1b\xcc\x8a\x12\xa4\xa0\x1b\"\x0fX" +
-	"$\xd81\xcbu\x1aV\xc4\x13KE\xd4nc\x96\x1b" +
-	"\x0a\x16\xf2\xe0\x18\x0f\x99\x15\xf6\x82W\x9a\x1c\x00K@" +
-	"\xb0\x04\x98\x92\xb5\xc0I\x18Z\x19KS<\x0c5\xab" +
-	"\xc9\xfbst{\x1a\xdc\x84\"\xa7\xb9\x1f\xf0\x90{C" +
-	"Q\xc8,\xd6\xe4\x1e\x0f\x9c:[\xe0\x89\xb3\xab\xf9\xe8" +
-	"\xd2\xc1\xa6\xba\xd6\x18\x98Y^\x83\xc5|F6sB" +
-	"\xd6\x10\x1egs\x1d\x96\x04\x11\x88H\xb0P,p[" +
-	"\xb4\xf7B\x8fD\xdcP\xbczi7\x90\xe19\xd1\xe8" +
-	"\xa4\xaf\xf0\x85\xd2~K\xc6+\xa2=i[\xcaf\x09" +
-	"\x9fXO\xf8\xb6\x81\x09\xf7y\xe0\x88\x86S\xef\x97\xf1" +
-	"^\xc2\xff\x0f2^\x17\xc4\xf3f}\xd1^o\x8ca" +
-	"\xaf\"j\xfdY\xba%\x8d\xee\x17(\xab\xdck\xc4\x80" +
-	"\xb9\x84\x80-\xb5\xc1\xb6\x8c\x94[\xdf\xaf\xdf\x9a\x91\x82" +
-	"X\xc6\xf8\xe3\xcc\x98>c\x98\xdfR\xd0\xfc.A\xc3" +
-	"\x13^\xbd\x1fQ;\x93Pt|^&'X\xdb&" +
-	"N\xddf\xa1p\xe3G'L(\xa2\x06\x80\x05 X" +
-	"\x80\x01\x94\xdf\x96>\xeai\xcc(\xcfo\x95r\xc0\xb7" +
-	"t\x92ru'\xc5\xbf\xe2*\x050w*\xea\x90\x94" +
-	"\x09\x05gv\xebg\x0c\xf3\xa2\x82\xe6\xcb\x04K\xf8\x86" +
-	"\xec\x92\xf0\xd2\xb4\xbejToA\x05\xab\x07\x90`\x89" +
-	"\xfcG\x96\x91\x00\xd0\xfd8A\xf7\xa3Q\xb5cS\x14" +
-	"\x9b\x94\xd7e\x19\x15\x00\xba\x88\x13t\x11\x8d\xea\xf7c" +
-	"\xd3\xcfb\x93\xfaoYF\x15\x80.\xe3\x1d\xf4\x0c\x1a" +
-	"\xd5\x7f\xc6\xa6\xd7cS\xee\x8a,c\x0e\x80\xae\xe1\x18" +
-	"]C\xa3\xba\x8f(X=D\x08\x96\xf2k\xb2\x8cy" +
-	"\x00z\x90\x8c\xd1\x83\xc4\xa8\xfa\xb1\xe9s\x84\xe0\xb0\xdf" +
-	"\x0a\xed\x01\xc9\xb8\"{=O\x9cX\x09>Ui\x85" +
-	"\xf6T\xd8\x9ca\x0b\xe3]\x03\x00n_\x97|@\xdc" +
-	"\x0e(\xeb\xb1\x1a\xdb\x96\x0b\xe8\x0e\xc8\xcf\xb3\x98y\xcf" +
-	"w\xbdO\xa6\xf7\xdc\x99Lk\x92\x8c9^tU5" +
-	"b-\x01\xce\x86u\x17\xf8x\x0c\\\x11\xb5\x01\x95~" +
-	"\xb6/hE\xd46\x85\xacA\x02\x99\x8d\xe4\x0d\x90\xd3" +
-	"|q\xcb\x90\xd3|\xf1z!\xb3\x11\x99\xd2\xeb'\xd2" +
-	"\xe7\xba\x89 m\x81\xdeJz\xef\x1a\xb8\x1b\x140\xa5" +
-	"7\xdbV\xba\xc0\xe3\xbeho\x95\xddJres\xc4" +
-	"\xf4\xa9\xd9\xae\xb1\x8e\xb8Ur+\xc9\x95\xebF\xcc\xd6" +
-	"\xa0\x94\xdc\xde\xc8!\xed\xd9\x80/\xb6x\x18\xa5-?" +
-	"\xdeu<x\xdc\xbc3\x1e7\xc9-G\xf3\x9a\xcc\xfa" +
-	"_\x06\x0d\xee\x05\xd8\x18\xc6\xba\x98\xc7d*\x9bi\xf9" +
-	"\xd9u-\xcf\xbfI\xcb\xafo\xc2\xbd\x1d\xc4\xdco\x91" +
-	"\xd0\xeeq\xdaU4\x80\xfe\xac\xde\x9c\xe2\x7f\x1e\xb3\xa5" +
-	"(\x97,E\xb1r\x0e\xde\x88b6\xb7glZ\xbb" +
-	"t\xcb0\x1fP\xd0\xfc\x11A\xbdG\xe7\xe9O\xea\x8f" +
-	"\x1a\xe6\x9f\x144/\x12\xd4\x09If\x82\xbe2\xad_" +
-	"2\xaaC\xb1\xb6\xef@\x82\xa8t\xe7\xc1\x08N\xd0\x11" +
-	"4\xaa\x87b\xc3G\x90\xa0\x16E\xee\x80b\xdcMd" +
-	"\xe4,\xf0=\x91\xd8\xa3\xb9\xce1>\xc6l\xd1f\x0b" +
-	"\x96\xd7a\xf3\xad \xb2y\xc0l\xe1\x87,\xb4E\xcb" +
-	"M\x0a&{\xd0\x1cg~ |\xab9lE\xbc\x01" +
-	"\x80y \x98\x07\x94M\x11\x86\x8e\x7f\xa4\x03\x8a\xdf/" +
-	"];\xd2\xc4?\x940\xcd\xc4<\xc3\xa4\xf4\xac\x8e\xab" +
-	"\x09+v\xa4\x01A\x0d\xb2\xae\xfd(\xe0\x87\x06\x14\xd0" +
-	"\xab\xd2ip/r\xe6\x1dR\xb7\"Gx\xb1\xcbn" +
-	"\xa0Fo\xfe\xa4\x1e\x8f\xc7 \xc2j\x0c\x88\xea^i" +
-	"\x05sN\x14X\x01vX\xf786\xfa\xacP\xf1?" +
-	"\x8c77\x7f\xaa\xd8\xd7\xaa\x92\x1e\xe3\xc5\xcd\xdb\x7f\xc0" +
-	"F\x95\x96\x0d\xfe7\x00\x00\xff\xff\xf7\xafq\x98"
+func (p Message_body_Future) IHave() IHave_Future {
+	return IHave_Future{Future: p.Future.Field(0, nil)}
+}
+func (p Message_body_Future) Graft() Graft_Future {
+	return Graft_Future{Future: p.Future.Field(0, nil)}
+}
+func (p Message_body_Future) Prune() Prune_Future {
+	return Prune_Future{Future: p.Future.Field(0, nil)}
+}
+func (p Message_body_Future) Subscribe() Subscribe_Future {
+	return Subscribe_Future{Future: p.Future.Field(0, nil)}
+}
+func (p Message_body_Future) IWant() IWant_Future {
+	return IWant_Future{Future: p.Future.Field(0, nil)}
+}
+func (p Message_body_Future) Extension() Extension_Future {
+	return Extension_Future{Future: p.Future.Field(0, nil)}
+}
+
+const schema_d06424cd5634d6a3 = "x\xda\xe4Xml\x1c\xc5\xf9\x9fg\xf6\xce{vr" +
+	"8\x9b9G\x09\x7f\xd0N\xf2\xa7j\x92B\x0aIT" +
+	"\x15+\xadIR\x0a\xa1D\xbd\xf5\x91:$\x98t}" +
+	";\xf6m}7s\xd9\xdd\xb3\xb1)\xa5\xad@\xa2H" +
+	"F@I\xc3KS @\xa5\xa4\xe1E\xcd\x07\x12*" +
+	"Za\x1a\x95\x80\"\x85\x14\"\xa0 0\"*\x14Z" +
+	"\x0a-\xa5\xa4\xc0V\xb3\xbb\xb7wq\xce\xe7\x18\xf5C" +
+	"\xa5~\xbb\xdbgf~\xcf\xfc\xe6y?\xf7{-\x17" +
+	"$\xceKo\x99\x85\xb0q]\xb2\xc5\xaf\xbc\xb7\xfd\xed" +
+	"\xcd{\xc5\xcf\x906\x17\xfc\x15\x0f\x1fL-\xda9\xf7" +
+	"7(\x09*Bd[r\x9c\xecH\xaadGR'" +
+	"\xc7\x92]\x08\xfc\xab\xf03\xe7<\xf5\xeeM\xf7!\xed" +
+	"\xff\xc0\x9f3\xba\xfe\xae\xcc\x0dw\xdc\x8d\x92XEh" +
+	"\xc5\x86\x96\xa5@X\x8bJX\x8bN\x1eh\x19F\xe0" +
+	"\x1f\xfa\xba\xba~\xefc\xef\xdc\x17\x1c\xbek\xb4}\xbf" +
+	"\xb9\xf6\x83\xa7PB\x9e}\xbd:N\xc6T\x95\x8c\xa9" +
+	":\xc2\xfe\xb5\xbb\xfepx\xc1\xf5\x1b\x1f@F\x06\xc0" +
+	"\xbf\xef\xe8\xcao\x1d:\xcb:\x1c\xa9\xb1_}\x9d\x1c" +
+	"PUr@\xd5IGJ\xaa\xb1\xef\x80\xb1x\xd6\x8f" +
+	"\xae~08\x17\x0ek\x9f\xbf\xfa\xad\xf3\x9f\x8fV/" +
+	"H\x8d\x93\x85)\x95,L\xe9\xe4\xf2`\xf5\xfb+&" +
+	"\x1e}\xe1\x03\xed!d\xcc\x03\xf0\x97\xef\xb8\xf4\xde\xa3" +
+	"?\xf8ft\xf8\x8a\xc7S\x18\xc8\xc1\x94J\x0e\xa6t" +
+	"\x92n\x95Zk\xdb\x8ei\xd7\xffy\xec\x97H;\x1d" +
+	"\xfc\xd7\xdd{v\xa4\xb7\xed\xd8\x1e-\x7f\xb6\xf5t " +
+	"\x13\xad*\x99h\xd5\xc9\xc26y\xfc\x13\xdbo\xbe\xe6" +
+	"\xed\xd4%{\x91\xd1\x01\xe0g^y\xeb\xd3'~~" +
+	"\xec\xd6H\x9b\xb1\xb6?\x91;\xdaTrG\x9bN\x9e" +
+	"m\x93\xa7\xafx\xe4\xd7\xee\x9b\xcf|n\x1c\x19g\x00" +
+	"\xd4.\xbe\x01TH#\xb4b\xc7\xac6@\xb0\xe2\x81" +
+	"Y\xafb\xa9\xfa\xf6/Uv\x95/\x7f\x12i\xf3\xc1" +
+	"?\xfc\xcc\x87\xbfx\xf2\xffw\xef\x0b\x09<\xa6\x1d'" +
+	"\xefi*yO\x93\x04\xfe>\xf3he\xd5C?\xf9" +
+	"mH\xe0;\xdf\xd8\xbf\xe5\xa3#\xef\x1e\x8d\x94\xd0\xe6" +
+	"\xbeD\xce\x9c\xab\x923\xe7\xead\xc3\\\xa9\xc4\x9e\xdd" +
+	"n\xff\xca\xc1\x1b\x9f\x0a\x08|\xfa\xa5Mc\x8f\xdc\xf4" +
+	"\xea\xcb\xd1\xea\x85d\x9c,!*YBt\xb2\x95\xc8" +
+	"\x1b\x9e\xbf\x7f\xa2m\xe3\x17\x16\x1dB\x86\x06\xe0\xffj" +
+	"\x91\xff\x97\x0d\x0b7\xbe\x19-\x7f\x9e<M&\x88J" +
+	"&\x88N:2\x7fD\xe0\x7f\xe7+\xcf\xbd\xf3\xfd\xce" +
+	"\xc7\x9eE\xda<\xf0\xb7\xbfu\xdaW\x8f\xac\x1a}\xb8" +
+	"Jw\x87\xa4\xbbC%\x07;t\x92\x9e'O/v" +
+	"|x\xcd\xca\xeb\xd81\xa4-\x00\x7f\xebE{\xf7\xbd" +
+	"v\xe5];\xa3\xe5\xd7\xcck\x0326O%c\xf3" +
+	"trh^\x17\xfa\x9d\xef\x8d\x94\x99\xfb\xc5\x01\x07\x9b" +
+	"\xfd\xde\xb2\xbcY\xe6\xe5\xce\x8b\x1c\xb3\xdfCY\x00#" +
+	"\x01\xd8\xbf\xf2\xc7w\x1b\x8f\x1f\xbd\xf1\x002\x12\x18V" +
+	"\x7f\x19`6B\xe7\xc1n\xecw\xb3\xad\x15\xe6z\xb4" +
+	"\xd5\x13\xd4\xb4,\xea\x15\x18\xcd\x0b\xceY\xde\xb3\x05\xa7" +
+	"\x9e\x08\xbe\xf49\xc2\xb4\xf2\xa6\xebQ\xcfa\x8c.\xce" +
+	"\x16+%\xf9k\x09\x95k\x0a\x8c\x16\x84c\x8f\x0a\xee" +
+	"\x99\xc5\xf6\xd5e{\x19BFBI \x94\x00\x84\xb4" +
+	"\xf4&M\xd3\x8d\x8b\x150,\x0c~\x89\xb9\xae9\xc0" +
+	"\xd6!\xc5r\x1b\xa8\xb68R\xedF\xf0m\xcb\xa5\xa2" +
+	"\x9fz\xc9\x02\xa3%\xdbum>@\xa3\xdd.\x1d." +
+	"\xd8\xf9\x02u\x0b\xa2R\xb4h\x1f\xa3.\xe3\x1e\x028" +
+	"\x0dAV\x01P\x11\x96?#^\xf2B\xe1|K\xbe" +
+	"`\x16\x8b\x8c\x0f\xb0\x88\xa0\xb5\x82\xf3\xb5\xed\xf2cc" +
+	"\x92\xce\x8d4\xe9\x94$\xb9e\xc1-\xaa\x0e\xdb^\x81" +
+	"\x9a\x94\xb3a\x1a\x1fG\xfb\x85\x13p`s\xdb\xb3\xcd" +
+	"\"\xcd\x8a\x9e\xc9\xb4t\x99\xc5\x88\x96TL\xcb\x92N" +
+	"m\x89n\x94\x150\xbe\x8bA\x03\xc8\x80\xfc:\xd2\xa9" +
+	"\x8d\xe8\xc6\x1e\x05\x8c}\x18\xba\xf2B\x0c\xda\xac\xc9\x0b" +
+	"\xde\x89}\xc6\xf3\xceH\xd9c\xad\x16\xb5L\xcf\xa4\x15" +
+	"\x97Y\xd4\xf4\x02|'T\x9c9\xf2!\x87\xcc\xa2m" +
+	"\x99\x1e\x0b$Y\xd1s65\x8b\xae\xa0.s\x86\x98" +
+	"KM\xb7z%e\x80!\x04i\x84!\x8d\xa0\x8b\x0b" +
+	"\x8b\xad\xb3\x1a\xa8pV\xa4\xc2%\xe0;&\xb7D\x89" +
+	"\xda\x09\x8bq\xcf\xee\xb7\x99\x13<\\M\x01\xf9t\xf2" +
+	" \x04\xd5s\xa3\xa7);\xb8\xc2\xab/\x92u*\x9c" +
+	"Ma\xb2\xab\"\xb4#5\x93m\xf3\x04uXI\x0c" +
+	"\xb1\xc9V\xdb\xef\x88\xd2L\xedvu\xd9\x86e\x08E" +
+	"z\x95\x18\x0e\x0c-\xd2l=s]\xd5\x1c`\x8du" +
+	"\xbb \xd2m\x8d\xe2w\xb3\xb2\xc3\\\xc6g{.5" +
+	"\xe9\x00\xe3\xcc\xb1\xf3U\xabm\x08\xba\x8c\xae\x0f\xa5\x12" +
+	"\x98\x9a\xdc\xa2\xd2\xaa\xbc\x02\xb5]j\x09\xceh\xdf\x08" +
+	"\x0d\x94p\x84'\xa8+J\xac \x86\x97\xa1\xaa\x87A" +
+	"]\xf0\xd4\xd2K\x11n\xef\x13\xd6Ht\x0b{\x18\x9b" +
+	"\xbc\x1a\x10\xd6\xf5\x98|\xaa\x80P\xf5\xba\xdd\x10\xb3\x9b" +
+	"\x94fmr.*<\xcf\xac\x9a\xe3\x9d\xb2\xc3\xafR" +
+	"\xc0\xd88\xbd\xc3\x9f\x11@k\xf0~\xec\xef8\xb0\x9b" +
+	"@\x8b\x10X\x0f\x90\x11j\xec\xdd\xec*\xc5c\xdc\xb5" +
+	"\x05\x8f.z\xe1U\xd1\xff).\xbb2\xba\xec&\xec" +
+	"_\xc8\x87XQ\x94Y*p\xe2\xda\x1d\xfb\xe9\xa0\xcd" +
+	"-\x97:l\xc0v=\xe6\x84\x1e\xe5T\xb8g\x97\x1a" +
+	">\xa32\xd9\xb9\x97J\xe7\xbeL\x01\xe3\xdb\x18\xaa\xbe" +
+	"\xdd\xbbT\xeb\xd5\x8d\x9b\x150~\x8a\xa1]\"4\xe1" +
+	"\xe3\xb8_\x05\xc7\xcc\x0a\xd4\xa9\xfaT\xa9+\xd4\x13\xa1" +
+	"\x80\x09\x15A\xbbt\xfc&.\xba)~\x05\x9a`<" +
+	"/,fI\xb3\x92\x87\x85\x7f\x03\x7f\xb5=\x97\x0e\xda" +
+	"\x0a\xb7j\xde_\xf5R\xa1\x0c\x9f\x14?\xb3bxm" +
+	"\xc1T\xa6\x0b\x9fkj\xe13\xd54|\x96\x99c\x0b" +
+	"\xcb\xce7\x8a\x9f\xd5\xf0y\x82\x95uj\xe9\x98\xe2\xff" +
+	"\x82H\x19'\x1b\xcc\xf9\x96\xb2\x18\xae\xa5\x99v\x9e\x15" +
+	"=\xcd\x1d\xef1\xf0s\x8c\x07\xe97\x19\x10\xf0Y\x93" +
+	"\xca\xf2Fv\xd7)\xed\xeev\x05\x8c\xfb1\xe8\\\xf0" +
+	"<kbx/\xf9\xc1\x0a:\\\xc0A\x8a\x15Ey" +
+	"\xe9\x80\x09E\xf4 \x04\xad\x08C+jB\xf9\xd9\xd1" +
+	"\xa5\x9e\x86\x98\xf2\x96\x99R~R\xa6(1erD" +
+	"\x96\xffd\xb4C\xc8X\xa9$f\xfb\xbe\xa4\x80\xf4\xc2" +
+	"R\xd2\x0bz\xee\x06P w\x1b`H\xc3\xa7~@" +
+	"\x04\xb9\x05\xba\xc96\xd0s\x87\xa5\xe8e)\xc2\x9f\xf8" +
+	"\x19\xc0\x08\x91\x17a\x0dy\x11\xf4\xdc\x1c\xac@\xee\x0c" +
+	"\x8c!\xad|\xecg@\x91\xc53^C\x16`=\x97" +
+	"\x95\xa2+\xa4(\xf1/?\x03\x09\x84\xc8\xe5\xf8\x12\xd2" +
+	"\x8b\xf5\xdc\xedRt\xbf\x14%\x8f\xfb\x19H\"D\xee" +
+	"\xc5\x9d\xe4^\xac\xe7^\x90\xa27\xa4\xa8\xe5#?\x03" +
+	"-\x08\x91\x09\xdcI&\xb0\x9e\x9b\xaf(\x90;K\xc1" +
+	"\x90V\xff\xe9g\xc2:SYN\x16*z\xee\x0a)" +
+	"*HQ\xeaC?\x03)\x84\x08S\x96\x13\xa6\xe8\xb9" +
+	"{\xa4h\x8f\x14\xb5\xfe\xc3\xcf@+Bd\x97\xb2\x9c" +
+	"\xecR\xf4\xdc_\xa5\xe8c)j\xfb\xc0\xcf@\x1bB" +
+	"\xe4#\xa5\x9b|\xa2\xe8\xb9\x0b\x12\x0a\xe4.M`H" +
+	"\xcf\xfa\xbb\x9f\x81Y\x08\x91u\x89\xe5d]B\xcf\xdd" +
+	" E\xb7I\xd1\xec\xbf\xf9\x19\xf9t\xe4\x96D7\xd9" +
+	"\x96\xd0s/H\xd1\x1b\x09\x0c\xed\xe5\x8a[h\x1a\xad" +
+	"\xaa\x01\x06\xdb2\xf3m\xceV\xdc\xc2zw\xa0\xb7>" +
+	"Z\xcd\xa9\x15\xcd\x08`\x0e\x02_\xe6\xec\xb5\x05\xb3\x88" +
+	"\xa0\xd8\xc4\x8e\x8e\xd4\xc2WKx\xfa\xdah_\xb17" +
+	"\xce\xad\x81e\xd9\xdc\x9b\xe45\xd0\x13\x00\xc7=Z\x08" +
+	"|\xad\x04\xce\x8a\x9e&\x1e9\xde\x104+z\xa6\x85" +
+	"\xecA\x01d\xdc\x02\xd5Av\xb3\xad3\x86\xecf[" +
+	"O\x152\xee\x1a\"z\xcbA\x88.\x16\x83\xc09\x03" +
+	"z\xb3\xd1\xbe)p\xeb\"uDo\xdc\x1d\x86\xc0]" +
+	"e1<Sv\xb3\xc1\x96\xe9\x11\xa3\xab\xc6\xbd]\x0d" +
+	"q\xa6\xe4f\x83-\xa7\x8c\x18\xb7\x9d!\xa2n_l" +
+	"\x0e5\x8b~\xc7'\x01r\xbay\x9d\xdc2\x09\xd0\x13" +
+	"q\x95U+@\xba\x8a\xe6\xa8]\x1c\x09p\xe3\xb64" +
+	"\xc2\x1d\x90\x8d\xdd\x0cp\xe9\xe6\xa0\x15<\x19\xd6ae" +
+	"\xd3vN\xa8\x93\xbb\xc2B9\xc0\x8d\x87 \x11nY" +
+	"V\xe7M\x12\xecN\x1c\xe3\xb6F\x04\xcb\x1d\x8dp\x83" +
+	"\x9a\xddaV\x85[&\xf7h\xd1\xe6\x83n\xc3\xa2]" +
+	"\xad*\x13\x0fM\"\xcbv+}n\xde\xb1\xfb\x10\xb0" +
+	"&\x15\xdehM\xa5T\xa8R.\xda\xd7\xe8\x15\xac!" +
+	"\xe6x\xb6\x1b\xa6\xa0*\x00\xb3\xe8\x80p]\xbb\xdcE" +
+	"\x83<\x14h\x137\xf3US\x90\xa5u\x93\x1a\xa8\xad" +
+	"\xa6\x87Z5\x05\xb9\xa5\x117a\xf5}r\xe1-\xa1" +
+	"j3\x9e\x88\x06\x16\x95\xba\x08x\x13\x1a\xfa&\xd1\xc0" +
+	"\xe9\xe6\xb8F\xae\xa9\x90\x97u\x96\xcde\xabf\xc6\x1f" +
+	"E?5\xeb\xab\xe0A\xd5\x0e\xea\xc39\xb5\x01R\xa4" +
+	"J\xb5R\xc4\xc3[\xa2KD\x99\xba+\xf4\xb3\xe6U" +
+	"\xe2\xdc\xb8\xad\xb3\xd5@\x81\xcfP\x1f\xd6\xb5o\x81\x1a" +
+	"\xb5\x1aL\xc6\x16e\xba\x12l\xbcV\x82\xb5\x9cP\x82" +
+	"\x9dZa\xfa\xbfP\x83\xd9\x05l\x0eU+\xb0 \x98" +
+	"M3`\xba\x13\xfb\xab#Kn-1\xeeI{\x8a" +
+	"\x83\\\xe0f,\x00/\x98\xb2\xd7\xca3{\x88Yt" +
+	"q\xd1\x1c\x1d\xa1\xb2\xe0X2u\xab\xf5\x1f\xed6O" +
+	"\xf0\xb6\xe6\xddf\xb9\x82\xddB\xd5\xac\xc2\x1a\x07MA" +
+	"B\xb5\xfb\xfa!\xc4c\x81d0\x16\x90Wk>\x13" +
+	"\x90\x065'\xbe\xa1\xb9H3u\xe3\x1e\x05\x8c=u" +
+	"\x93\xa2]\x9b\xb4\x07u\xe39\x05\x8c\xd70h\x18\x07" +
+	"u\xac\xf6J\xb76\xa1\xe7f\xcb\x02w>`\x00%" +
+	"\xaca;`\x0d\xe9\x00=\xb7J\x0a.\x06\x0c\xaa\xe7" +
+	"\x15\x9b\xf8\xe3R\xec\xcbV\xf7\x1cO\x9c\xa3\x16\xed!" +
+	"\xd6I\x0bb\x98\x96L>B\xfb+\x8eW\x90o&" +
+	"\xcanu\x00\x17\xb4\xa5\xd1\x85\xfa\x18-;\xa2l\x0e" +
+	"\xb4\x9b\x1e\x93\xb1\xa2\x05ahA\xe0\x87q\xf4\xb2\x11" +
+	"\xa4\x94\x1bY\xec\xfc\xe8iv\x06L\xcb\xb7\x81\xc0\xfb" +
+	"\xcc\x91\xa2*L\xab\xd6\xed\xd6\xde\x18\xbe6\xcd\x13\x87" +
+	"\xe3(\x9c7\x83\xb1P\xb5\x7ff\xfa\xa4\xfe\xf9Z\x09" +
+	"\"L\xab\x89V\xb7\xfa\xa6\xd3g{\x8e\xe9\xc0\x08\x0d" +
+	"\x97\xc3\xc9\x8d\xb2[Q\xa2\xb4\x11\x99H\x9co\xa6\x9f" +
+	"E\\j\xbb\x81\x7f\xa4\xa4\x8e!Wa\xca\xa9w\x14" +
+	"\xdb\xad\xcfL\x9e8%\x07\xe9\xab\x9f\xbf\xc6\xaf\xa0\x96" +
+	"Y\xb3\x01\xec\x9d\xd1\x9d\xa8WH2Z\x12V\xa5\x18" +
+	"NF\xea\xb41\x1dV\x9f\x1c\xfa\x15\xe1 \xd4l\x06" +
+	"{bf\x88\xaa\xdb\xa9\xfc\xa7.{N\x97\x1b\x9at" +
+	"\xc9\x91C\xc1I\x93\x83\xac\x02\xc6\x15\xb8\xc9\x80\xb3\xfa" +
+	"6\xdd8\x1ep\xa6\x1a\x0c8C\xcd\xa2\x01\xe7\xd9\xb4" +
+	"OT\xb8U\x9d\x9bK}\x86l3\x9aO\x8aA\x1b" +
+	"\xea\x06\x06\xff\x1e\x00\xea3\x0b\x06"
 
 func RegisterSchema(reg *schemas.Registry) {
 	reg.Register(&schemas.Schema{
 		String: schema_d06424cd5634d6a3,
 		Nodes: []uint64{
+			0x9e6fb35beb97f175,
 			0xa38eefc82dcb0278,
+			0xa3ecbab34d0746cd,
 			0xa5588519d0dba97f,
+			0xad7b890b2851c5b8,
+			0xae11f5d8b7e133f2,
 			0xb28ded8511e59511,
 			0xb34a08eb7d9097c1,
 			0xc225cbe873beb033,
 			0xc35970a9753697f2,
 			0xc496ae3c75b714d3,
+			0xc88a6b346673aaac,
 			0xcd222b580ae1b939,
+			0xd1ba3a80ecd43d6a,
 			0xe56584347df7156c,
 		},
 		Compressed: true,
@@ -0,0 +1,74 @@
+// Code generated by capnpc-go. DO NOT EDIT.
+
+package types
+
+import (
+	capnp "capnproto.org/go/capnp/v3"
+	text "capnproto.org/go/capnp/v3/encoding/text"
+)
+
+// Request to remove the connection from the broadcast tree (Plumtree) on the horizontalApi.
+type Prune capnp.Struct
+
+// Prune_TypeID is the unique identifier for the type Prune.
+const Prune_TypeID = 0xa3ecbab34d0746cd
+
+func NewPrune(s *capnp.Segment) (Prune, error) {
+	st, err := capnp.NewStruct(s, capnp.ObjectSize{DataSize: 0, PointerCount: 0})
+	return Prune(st), err
+}
+
+func NewRootPrune(s *capnp.Segment) (Prune, error) {
+	st, err := capnp.NewRootStruct(s, capnp.ObjectSize{DataSize: 0, PointerCount: 0})
+	return Prune(st), err
+}
+
+func ReadRootPrune(msg *capnp.Message) (Prune, error) {
+	root, err := msg.Root()
+	return Prune(root.Struct()), err
+}
+
+func (s Prune) String() string {
+	str, _ := text.Marshal(0xa3ecbab34d0746cd, capnp.Struct(s))
+	return str
+}
+
+func (s Prune) EncodeAsPtr(seg *capnp.Segment) capnp.Ptr {
+	return capnp.Struct(s).EncodeAsPtr(seg)
+}
+
+func (Prune) DecodeFromPtr(p capnp.Ptr) Prune {
+	return Prune(capnp.Struct{}.DecodeFromPtr(p))
+}
+
+func (s Prune) ToPtr() capnp.Ptr {
+	return capnp.Struct(s).ToPtr()
+}
+func (s Prune) IsValid() bool {
+	return capnp.Struct(s).IsValid()
+}
+
+func (s Prune) Message() *capnp.Message {
+	return capnp.Struct(s).Message()
+}
+
+func (s Prune) Segment() *capnp.Segment {
+	return capnp.Struct(s).Segment()
+}
+
+// Prune_List is a list of Prune.
+type Prune_List = capnp.StructList[Prune]
+
+// NewPrune creates a new list of Prune.
+func NewPrune_List(s *capnp.Segment, sz int32) (Prune_List, error) {
+	l, err := capnp.NewCompositeList(s, capnp.ObjectSize{DataSize: 0, PointerCount: 0}, sz)
+	return capnp.StructList[Prune](l), err
+}
+
+// Prune_Future is a wrapper for a Prune promised by a client call.
+type Prune_Future struct{ *capnp.Future }
+
+func (f Prune_Future) Struct() (Prune, error) {
+	p, err := f.Future.Ptr()
+	return Prune(p.Struct()), err
+}
@@ -0,0 +1,97 @@
+// Code generated by capnpc-go. DO NOT EDIT.
+
+package types
+
+import (
+	capnp "capnproto.org/go/capnp/v3"
+	text "capnproto.org/go/capnp/v3/encoding/text"
+)
+
+// List of the gossip types the sender is subscribed to on the horizontalApi.
+type Subscribe capnp.Struct
+
+// Subscribe_TypeID is the unique identifier for the type Subscribe.
+const Subscribe_TypeID = 0xd1ba3a80ecd43d6a
+
+func NewSubscribe(s *capnp.Segment) (Subscribe, error) {
+	st, err := capnp.NewStruct(s, capnp.ObjectSize{DataSize: 0, PointerCount: 1})
+	return Subscribe(st), err
+}
+
+func NewRootSubscribe(s *capnp.Segment) (Subscribe, error) {
+	st, err := capnp.NewRootStruct(s, capnp.ObjectSize{DataSize: 0, PointerCount: 1})
+	return Subscribe(st), err
+}
+
+func ReadRootSubscribe(msg *capnp.Message) (Subscribe, error) {
+	root, err := msg.Root()
+	return Subscribe(root.Struct()), err
+}
+
+func (s Subscribe) String() string {
+	str, _ := text.Marshal(0xd1ba3a80ecd43d6a, capnp.Struct(s))
+	return str
+}
+
+func (s Subscribe) EncodeAsPtr(seg *capnp.Segment) capnp.Ptr {
+	return capnp.Struct(s).EncodeAsPtr(seg)
+}
+
+func (Subscribe) DecodeFromPtr(p capnp.Ptr) Subscribe {
+	return Subscribe(capnp.Struct{}.DecodeFromPtr(p))
+}
+
+func (s Subscribe) ToPtr() capnp.Ptr {
+	return capnp.Struct(s).ToPtr()
+}
+func (s Subscribe) IsValid() bool {
+	return capnp.Struct(s).IsValid()
+}
+
+func (s Subscribe) Message() *capnp.Message {
+	return capnp.Struct(s).Message()
+}
+
+func (s Subscribe) Segment() *capnp.Segment {
+	return capnp.Struct(s).Segment()
+}
+func (s Subscribe) GossipTypes() (capnp.UInt16List, error) {
+	p, err := capnp.Struct(s).Ptr(0)
+	return capnp.UInt16List(p.List()), err
+}
+
+func (s Subscribe) HasGossipTypes() bool {
+	return capnp.Struct(s).HasPtr(0)
+}
+
+func (s Subscribe) SetGossipTypes(v capnp.UInt16List) error {
+	return capnp.Struct(s).SetPtr(0, v.ToPtr())
+}
+
+// NewGossipTypes sets the gossipTypes field to a newly
+// allocated capnp.UInt16List, preferring placement in s's segment.
+func (s Subscribe) NewGossipTypes(n int32) (capnp.UInt16List, error) {
+	l, err := capnp.NewUInt16List(capnp.Struct(s).Segment(), n)
+	if err != nil {
+		return capnp.UInt16List{}, err
+	}
+	err = capnp.Struct(s).SetPtr(0, l.ToPtr())
+	return l, err
+}
+
+// Subscribe_List is a list of Subscribe.
+type Subscribe_List = capnp.StructList[Subscribe]
+
+// NewSubscribe creates a new list of Subscribe.
+func NewSubscribe_List(s *capnp.Segment, sz int32) (Subscribe_List, error) {
+	l, err := capnp.NewCompositeList(s, capnp.ObjectSize{DataSize: 0, PointerCount: 1}, sz)
+	return capnp.StructList[Subscribe](l), err
+}
+
+// Subscribe_Future is a wrapper for a Subscribe promised by a client call.
+type Subscribe_Future struct{ *capnp.Future }
+
+func (f Subscribe_Future) Struct() (Subscribe, error) {
+	p, err := f.Future.Ptr()
+	return Subscribe(p.Struct()), err
+}
@@ -19,6 +19,15 @@
 // Package args is used for specifying the arguments (internally) used by the application.
 package args
 
+import (
+	"fmt"
+	"gossip/common"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
 // Represents the arguments used actually/internally by the application
 
 // You can use [NewFromDefaults] to obtain this struct with default values set
@@ -30,26 +39,267 @@ type Args struct {
 	// part of the peer’s knowledge base. Older items will be removed to ensure
 	// space for newer items if the peer’s knowledge base exceeds this limit
 	Cache_size uint
-	// How often the gossip strategy should perform a strategy cycle, if
-	// applicable
-	GossipTimer uint
-	// Address to listen for incoming peer connections, ip:port
+	// How often the gossip strategy should perform a strategy cycle (e.g.
+	// forward the valid messages), if applicable
+	GossipTimer time.Duration
+	// Forward validated messages without waiting for the next strategy cycle
+	// (after Forward_window)
+	Eager_forward bool
+	// How long validated messages are collected before they are forwarded in
+	// one batch if Eager_forward is set. 0 forwards each message immediately
+	Forward_window time.Duration
+	// Minimum amount of connections to peers. Below it, the node connects to
+	// known peers on its own until Target_peers is reached. 0 disables looking
+	// for new peers
+	Min_peers uint
+	// Amount of connections to peers the node tries to reach when it looks for
+	// new peers
+	Target_peers uint
+	// Maximum amount of connections to peers. Further incoming connections are
+	// refused or replace other incoming ones
+	Max_peers uint
+	// Maximum amount of addresses of (not configured) peers which are
+	// remembered to replace failed connections (passive view)
+	Passive_view_size uint
+	// How often known peers are exchanged with a random peer (in seconds).
+	// 0 disables the exchange
+	Shuffle_interval uint
+	// Amount of addresses sent in such an exchange
+	Shuffle_length uint
+	// Addresses to listen for incoming peer connections, ip:port separated
+	// by commas or spaces (see [Args.HzAddrs])
 	Hz_addr string
-	// Address to listen for incoming peer connections, ip:port
+	// Address under which peers can reach this node, ip:port (e.g. if the
+	// node is behind a NAT). Empty means the first address of Hz_addr
+	Hz_advertise string
+	// Addresses to listen for incoming module connections, ip:port separated
+	// by commas or spaces (see [Args.VertAddrs])
 	Vert_addr string
 	// List of horizontal peers to connect to, [ip]:port
 	Peer_addrs []string
-	// Strategy string
+	// Peer which is used to join the network, ip:port. It is connected to in
+	// addition to Peer_addrs. Empty disables the bootstrapper
+	Bootstrapper string
+	// Name of the gossip strategy to use (see [Strategies])
+	Strategy string
+	// Gossip types whose messages are announced (IHave) instead of pushed,
+	// separated by commas or spaces (see [Args.LazyPushTypes])
+	Lazy_push_types string
+	// Messages whose payload has at least this size (in bytes) are announced
+	// instead of pushed. 0 disables the threshold
+	Lazy_push_size uint
+	// The rumor strategy stops spreading a message with probability
+	// 1/Rumor_k each time a peer already knew it
+	Rumor_k uint
+	// Policy choosing the peers messages are forwarded to (see
+	// [SelectionPolicies]). One policy applies to the whole node, whichever
+	// Strategy is used, except for plumtree which pushes to the links of its
+	// broadcast tree
+	Peer_selection string
+	// Policies for single gossip types which differ from Peer_selection,
+	// type=policy separated by commas or spaces (see
+	// [Args.PeerSelectionTypes])
+	Peer_selection_types string
+	// How long a peer has time to provide a PoW (in seconds). Connections
+	// whose last PoW is older than this are closed
+	Pow_timeout uint
+	// How often peers are asked to renew their PoW (in seconds)
+	Pow_request_time uint
+	// How often a Ping is sent to each peer to check that the connection is
+	// alive and to measure the round trip time. 0 disables the pings
+	Ping_interval time.Duration
+	// Connections on which nothing was received for this long are closed.
+	// 0 disables the timeout
+	Idle_timeout time.Duration
+	// Amount of messages from the horizontal api which are buffered until the
+	// strategy processes them
+	Hz_queue_size uint
+	// Amount of messages between main and the strategy which are buffered
+	// until they are processed
+	Strat_queue_size uint
+	// Rate limit (push messages per second) for incoming push messages per
+	// peer connection. 0 disables the limit
+	In_peer_rate float64
+	// Amount of incoming push messages per peer connection which may arrive at
+	// once. 0 means the same as the rate
+	In_peer_burst uint
+	// Rate limit (push messages per second) for incoming push messages per
+	// gossip type. 0 disables the limit
+	In_type_rate float64
+	// Amount of incoming push messages per gossip type which may arrive at
+	// once. 0 means the same as the rate
+	In_type_burst uint
+	// Rate limit (push messages per second) for forwarded push messages per
+	// peer connection. 0 disables the limit
+	Out_peer_rate float64
+	// Amount of forwarded push messages per peer connection which may be sent
+	// at once. 0 means the same as the rate
+	Out_peer_burst uint
+	// Rate limit (push messages per second) for forwarded messages per gossip
+	// type. 0 disables the limit
+	Out_type_rate float64
+	// Amount of forwarded messages per gossip type which may be sent at once.
+	// 0 means the same as the rate
+	Out_type_burst uint
+	// Maximum amount of bytes per second sent to all peers together. 0
+	// disables the limit
+	Egress_rate uint
+	// Maximum amount of bytes per second sent to a single peer. 0 disables
+	// the limit
+	Egress_peer_rate uint
+	// Peers whose reputation score drops to (or below) this value are
+	// banned. Scores range from -100 to 100, new peers start at 0
+	Ban_score int
+	// How long a ban lasts (in seconds)
+	Ban_time uint
+	// File in which the ban list is persisted across restarts. Empty
+	// disables persisting the ban list
+	Ban_file string
+	// File in which the seen and valid messages are stored so they are still
+	// known after a restart. Empty disables the message store
+	Store_file string
+	// How long messages are kept in the message store (in seconds)
+	Store_retention uint
+	// Address to serve the metrics on (http://<addr>/metrics), ip:port.
+	// Empty disables the metrics
+	Metrics_addr string
+	// Address to serve the admin interface on, ip:port. Empty disables the
+	// admin interface
+	Admin_addr string
+	// Token which has to be sent (Authorization: Bearer <token>) for
+	// changing requests to the admin interface. Without a token the admin
+	// interface can only be served on a loopback address
+	Admin_token string
+	// Level of the log messages (DEBUG, INFO, WARN or ERROR)
+	Log_level string
+	// Format of the log messages (text or json)
+	Log_format string
+	// File the log is written to. Empty writes the log to stdout
+	Log_file string
+	// Size (in MB) at which the log file is rotated. 0 disables rotating
+	Log_max_size uint
+	// Amount of rotated log files which are kept
+	Log_max_files uint
+	// Log levels of single modules, e.g. "strategy=INFO,horzAPI=WARN". The
+	// other modules use Log_level
+	Log_module_levels string
+	// Whether the events for testing/benchmarking ([common.LevelTest]) are
+	// logged
+	Log_test_events bool
+	// File the trace of the message propagation is written to. Empty
+	// disables tracing
+	Trace_file string
+	// How long the node may take on shutdown to forward pending messages and
+	// wait for outstanding validations (in seconds). 0 disables draining
+	Drain_timeout uint
 }
 
 // Returns a new [Args] struct with sane default values
 func NewFromDefaults() Args {
 	return Args{
-		Degree:      30,
-		Cache_size:  50,
-		GossipTimer: 1,
-		Hz_addr:     "127.0.0.1:6001",
-		Vert_addr:   "127.0.0.1:7001",
-		Peer_addrs:  nil,
+		Degree:            30,
+		Cache_size:        50,
+		GossipTimer:       time.Second,
+		Eager_forward:     false,
+		Forward_window:    0,
+		Min_peers:         0,
+		Target_peers:      0,
+		Max_peers:         50,
+		Passive_view_size: 30,
+		Shuffle_interval:  0,
+		Shuffle_length:    8,
+		Hz_addr:           "127.0.0.1:6001",
+		Hz_advertise:      "",
+		Vert_addr:         "127.0.0.1:7001",
+		Peer_addrs:        nil,
+		Bootstrapper:      "",
+		Strategy:          "dummy",
+		Lazy_push_types:   "",
+		Lazy_push_size:    0,
+		Rumor_k:           3,
+		Peer_selection:    "random",
+		Pow_timeout:       7,
+		Pow_request_time:  2,
+		Ping_interval:     0,
+		Idle_timeout:      0,
+		Hz_queue_size:     1,
+		Strat_queue_size:  0,
+		Ban_score:         -50,
+		Ban_time:          3600,
+		Ban_file:          "",
+		Store_file:        "",
+		Store_retention:   600,
+		Metrics_addr:      "",
+		Admin_addr:        "",
+		Admin_token:       "",
+		Log_level:         "DEBUG",
+		Log_format:        "text",
+		Log_file:          "",
+		Log_max_size:      100,
+		Log_max_files:     5,
+		Log_module_levels: "",
+		Log_test_events:   false,
+		Trace_file:        "",
+		Drain_timeout:     5,
+	}
+}
+
+// Split a list of addresses separated by commas or spaces
+func SplitAddrs(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' })
+}
+
+// Addresses to listen for incoming peer connections
+func (a Args) HzAddrs() []string {
+	return SplitAddrs(a.Hz_addr)
+}
+
+// Addresses to listen for incoming module connections
+func (a Args) VertAddrs() []string {
+	return SplitAddrs(a.Vert_addr)
+}
+
+// Gossip types whose messages are announced instead of pushed
+func (a Args) LazyPushTypes() ([]common.GossipType, error) {
+	var ret []common.GossipType
+	for _, s := range strings.FieldsFunc(a.Lazy_push_types, func(r rune) bool { return r == ',' || r == ' ' }) {
+		t, err := strconv.ParseUint(s, 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("invalid gossip type %q", s)
+		}
+		ret = append(ret, common.GossipType(t))
+	}
+	return ret, nil
+}
+
+// Policies for single gossip types which differ from Peer_selection
+func (a Args) PeerSelectionTypes() (map[common.GossipType]string, error) {
+	ret := make(map[common.GossipType]string)
+	for _, s := range strings.FieldsFunc(a.Peer_selection_types, func(r rune) bool { return r == ',' || r == ' ' }) {
+		typ, policy, ok := strings.Cut(s, "=")
+		if !ok {
+			return nil, fmt.Errorf("%q is not of the form type=policy", s)
+		}
+		t, err := strconv.ParseUint(typ, 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("invalid gossip type %q", typ)
+		}
+		if !slices.Contains(SelectionPolicies, policy) {
+			return nil, fmt.Errorf("unknown policy %q (available: %v)", policy, SelectionPolicies)
+		}
+		ret[common.GossipType(t)] = policy
+	}
+	return ret, nil
+}
+
+// Address under which peers can reach this node. It also serves as
+// identifier of the node (e.g. in the logs).
+func (a Args) AdvertisedAddr() string {
+	if a.Hz_advertise != "" {
+		return a.Hz_advertise
+	}
+	if addrs := a.HzAddrs(); len(addrs) > 0 {
+		return addrs[0]
 	}
+	return ""
 }
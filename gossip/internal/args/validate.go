@@ -0,0 +1,208 @@
+/*
+* gossip
+* Copyright (C) 2024 Fabio Gaiba and Lukas Heindl
+*
+* This program is free software: you can redistribute it and/or modify
+* it under the terms of the GNU General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* This program is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+* GNU General Public License for more details.
+*
+* You should have received a copy of the GNU General Public License
+* along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package args
+
+import (
+	"errors"
+	"fmt"
+	"gossip/internal/logging"
+	"log/slog"
+	"net"
+	"slices"
+	"strconv"
+)
+
+// Names of the available gossip strategies
+var Strategies = []string{"dummy", "plumtree", "gossipsub", "rumor"}
+
+// Names of the available policies to select the peers messages are forwarded
+// to:
+//   - random: uniformly random peers
+//   - reputation: random peers, weighted by their reputation
+//   - rtt: random peers, weighted by the inverse of their round trip time
+//   - lru: the peers which were selected least recently
+//   - bandwidth: random peers, weighted by how much of their outgoing rate
+//     limit is left
+var SelectionPolicies = []string{"random", "reputation", "rtt", "lru", "bandwidth"}
+
+// Names of the available log formats
+var LogFormats = []string{"text", "json"}
+
+// Check that the arguments are usable. All problems found are reported in the
+// returned error (nil if there are none).
+//
+// The options are referred to by their name in the config file.
+func (a Args) Validate() error {
+	var errs []error
+	fail := func(option string, format string, v ...any) {
+		errs = append(errs, fmt.Errorf("%s: %s", option, fmt.Sprintf(format, v...)))
+	}
+	checkAddr := func(option string, addr string, optional bool) {
+		if addr == "" && optional {
+			return
+		}
+		if err := validateAddr(addr); err != nil {
+			fail(option, "invalid address %q: %v", addr, err)
+		}
+	}
+
+	if a.Degree == 0 {
+		fail("degree", "must be greater than 0")
+	}
+	if a.Cache_size == 0 {
+		fail("cache_size", "must be greater than 0")
+	}
+	if a.GossipTimer <= 0 {
+		fail("gtimer", "must be greater than 0")
+	}
+	if a.Forward_window < 0 {
+		fail("forward_window", "must not be negative")
+	}
+	if a.Max_peers == 0 {
+		fail("max_peers", "must be greater than 0")
+	}
+	if a.Min_peers > a.Target_peers {
+		fail("min_peers", "must not be greater than target_peers (%d)", a.Target_peers)
+	}
+	if a.Target_peers > a.Max_peers {
+		fail("target_peers", "must not be greater than max_peers (%d)", a.Max_peers)
+	}
+	if a.Shuffle_interval > 0 && a.Shuffle_length == 0 {
+		fail("shuffle_length", "must be greater than 0 if shuffle_interval is set")
+	}
+
+	checkAddrs := func(option string, addrs []string) {
+		if len(addrs) == 0 {
+			fail(option, "at least one address is needed")
+		}
+		for _, addr := range addrs {
+			checkAddr(option, addr, false)
+		}
+	}
+	checkAddrs("p2p_address", a.HzAddrs())
+	checkAddrs("api_address", a.VertAddrs())
+	checkAddr("p2p_advertise_address", a.Hz_advertise, true)
+	for _, addr := range a.Peer_addrs {
+		checkAddr("hconns", addr, false)
+	}
+	checkAddr("bootstrapper", a.Bootstrapper, true)
+	checkAddr("metrics_address", a.Metrics_addr, true)
+	checkAddr("admin_address", a.Admin_addr, true)
+
+	// the listeners cannot share an address
+	type listener struct{ option, addr string }
+	var ls []listener
+	for _, addr := range a.HzAddrs() {
+		ls = append(ls, listener{"p2p_address", addr})
+	}
+	for _, addr := range a.VertAddrs() {
+		ls = append(ls, listener{"api_address", addr})
+	}
+	ls = append(ls, listener{"metrics_address", a.Metrics_addr}, listener{"admin_address", a.Admin_addr})
+	listeners := map[string]string{}
+	for _, l := range ls {
+		if l.addr == "" {
+			continue
+		}
+		if other, ok := listeners[l.addr]; ok {
+			fail(l.option, "same address as %s (%s)", other, l.addr)
+		}
+		listeners[l.addr] = l.option
+	}
+
+	if !slices.Contains(Strategies, a.Strategy) {
+		fail("strategy", "unknown strategy %q (available: %v)", a.Strategy, Strategies)
+	}
+	if _, err := a.LazyPushTypes(); err != nil {
+		fail("lazy_push_types", "%v", err)
+	}
+	if a.Rumor_k == 0 {
+		fail("rumor_k", "must be greater than 0")
+	}
+	if !slices.Contains(SelectionPolicies, a.Peer_selection) {
+		fail("peer_selection", "unknown policy %q (available: %v)", a.Peer_selection, SelectionPolicies)
+	}
+	if _, err := a.PeerSelectionTypes(); err != nil {
+		fail("peer_selection_types", "%v", err)
+	}
+
+	if a.Pow_timeout == 0 {
+		fail("pow_timeout", "must be greater than 0")
+	}
+	if a.Pow_request_time == 0 {
+		fail("pow_request_time", "must be greater than 0")
+	} else if a.Pow_request_time >= a.Pow_timeout {
+		fail("pow_request_time", "must be smaller than pow_timeout (%d), otherwise valid connections time out", a.Pow_timeout)
+	}
+	if a.Ping_interval < 0 {
+		fail("ping_interval", "must not be negative")
+	}
+	if a.Idle_timeout < 0 {
+		fail("idle_timeout", "must not be negative")
+	} else if a.Idle_timeout > 0 && (a.Ping_interval == 0 || a.Idle_timeout <= a.Ping_interval) {
+		fail("idle_timeout", "must be greater than ping_interval (%v), otherwise quiet connections time out", a.Ping_interval)
+	}
+
+	for _, r := range []struct {
+		option string
+		rate   float64
+	}{
+		{"in_peer_rate", a.In_peer_rate},
+		{"in_type_rate", a.In_type_rate},
+		{"out_peer_rate", a.Out_peer_rate},
+		{"out_type_rate", a.Out_type_rate},
+	} {
+		if r.rate < 0 {
+			fail(r.option, "must not be negative")
+		}
+	}
+
+	if a.Ban_score < -100 || a.Ban_score >= 0 {
+		fail("ban_score", "must be between -100 and -1, otherwise new peers are banned right away")
+	}
+	if a.Store_file != "" && a.Store_retention == 0 {
+		fail("store_retention", "must be greater than 0 if store_file is set")
+	}
+
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(a.Log_level)); err != nil {
+		fail("log_level", "unknown log level %q (available: DEBUG, INFO, WARN, ERROR)", a.Log_level)
+	}
+	if !slices.Contains(LogFormats, a.Log_format) {
+		fail("log_format", "unknown log format %q (available: %v)", a.Log_format, LogFormats)
+	}
+
+	if _, err := logging.ParseModuleLevels(a.Log_module_levels); err != nil {
+		fail("log_module_levels", "%v", err)
+	}
+
+	return errors.Join(errs...)
+}
+
+// Check that addr is of the form host:port
+func validateAddr(addr string) error {
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+		return errors.New("port must be a number between 0 and 65535")
+	}
+	return nil
+}
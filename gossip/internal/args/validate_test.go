@@ -0,0 +1,88 @@
+/*
+* gossip
+* Copyright (C) 2024 Fabio Gaiba and Lukas Heindl
+*
+* This program is free software: you can redistribute it and/or modify
+* it under the terms of the GNU General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* This program is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+* GNU General Public License for more details.
+*
+* You should have received a copy of the GNU General Public License
+* along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package args_test
+
+import (
+	"gossip/internal/args"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidate(t *testing.T) {
+	if err := args.NewFromDefaults().Validate(); err != nil {
+		t.Fatalf("default arguments should be valid: %v", err)
+	}
+
+	a := args.NewFromDefaults()
+	a.Hz_addr = "127.0.0.1:6001, [::1]:6001"
+	a.Vert_addr = "[::]:7001"
+	a.Hz_advertise = "[2001:db8::1]:6001"
+	if err := a.Validate(); err != nil {
+		t.Fatalf("multiple and IPv6 addresses should be valid: %v", err)
+	}
+	if addrs := a.HzAddrs(); len(addrs) != 2 || addrs[1] != "[::1]:6001" {
+		t.Fatalf("addresses were not split correctly: %v", addrs)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(a *args.Args)
+		option string
+	}{
+		{"degree 0", func(a *args.Args) { a.Degree = 0 }, "degree"},
+		{"cache 0", func(a *args.Args) { a.Cache_size = 0 }, "cache_size"},
+		{"negative forward window", func(a *args.Args) { a.Forward_window = -time.Millisecond }, "forward_window"},
+		{"peer limits", func(a *args.Args) { a.Target_peers = a.Max_peers + 1 }, "target_peers"},
+		{"min peers", func(a *args.Args) { a.Min_peers = a.Target_peers + 1 }, "min_peers"},
+		{"shuffle length", func(a *args.Args) { a.Shuffle_interval = 10; a.Shuffle_length = 0 }, "shuffle_length"},
+		{"bad address", func(a *args.Args) { a.Hz_addr = "127.0.0.1" }, "p2p_address"},
+		{"bad port", func(a *args.Args) { a.Vert_addr = "127.0.0.1:70000" }, "api_address"},
+		{"no address", func(a *args.Args) { a.Hz_addr = " , " }, "p2p_address"},
+		{"bad advertised address", func(a *args.Args) { a.Hz_advertise = "nat" }, "p2p_advertise_address"},
+		{"bad peer", func(a *args.Args) { a.Peer_addrs = []string{"localhost"} }, "hconns"},
+		{"same address", func(a *args.Args) { a.Admin_addr = a.Hz_addr }, "admin_address"},
+		{"unknown strategy", func(a *args.Args) { a.Strategy = "foo" }, "strategy"},
+		{"lazy push types", func(a *args.Args) { a.Lazy_push_types = "1,70000" }, "lazy_push_types"},
+		{"rumor k 0", func(a *args.Args) { a.Rumor_k = 0 }, "rumor_k"},
+		{"unknown policy", func(a *args.Args) { a.Peer_selection = "fastest" }, "peer_selection"},
+		{"policy per type", func(a *args.Args) { a.Peer_selection_types = "1=rtt,2" }, "peer_selection_types"},
+		{"pow timeouts", func(a *args.Args) { a.Pow_request_time = a.Pow_timeout }, "pow_request_time"},
+		{"idle timeout", func(a *args.Args) { a.Ping_interval = 10 * time.Second; a.Idle_timeout = a.Ping_interval }, "idle_timeout"},
+		{"idle timeout without pings", func(a *args.Args) { a.Idle_timeout = 30 * time.Second }, "idle_timeout"},
+		{"negative rate", func(a *args.Args) { a.In_peer_rate = -1 }, "in_peer_rate"},
+		{"ban score", func(a *args.Args) { a.Ban_score = 0 }, "ban_score"},
+		{"log level", func(a *args.Args) { a.Log_level = "LOUD" }, "log_level"},
+		{"log format", func(a *args.Args) { a.Log_format = "xml" }, "log_format"},
+		{"module levels", func(a *args.Args) { a.Log_module_levels = "foo=INFO" }, "log_module_levels"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := args.NewFromDefaults()
+			tt.modify(&a)
+			err := a.Validate()
+			if err == nil {
+				t.Fatalf("arguments should be invalid")
+			}
+			if !strings.HasPrefix(err.Error(), tt.option+":") {
+				t.Fatalf("error should refer to %s: %v", tt.option, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,162 @@
+/*
+* gossip
+* Copyright (C) 2024 Fabio Gaiba and Lukas Heindl
+*
+* This program is free software: you can redistribute it and/or modify
+* it under the terms of the GNU General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* This program is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+* GNU General Public License for more details.
+*
+* You should have received a copy of the GNU General Public License
+* along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+// Package logging implements the configurable log output: log levels per
+// module and a log file which is rotated once it grows too large.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"gossip/common"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Names of the modules the loggers are tagged with (attribute "module")
+var Modules = []string{"main", "strategy", "horzAPI", "vertAPI"}
+
+// Levels holds the log level of each module. Modules without a level of
+// their own use the base level.
+//
+// Levels is safe for concurrent use, so the levels can be changed at runtime.
+type Levels struct {
+	base slog.Leveler
+
+	mutex   sync.RWMutex
+	modules map[string]slog.Level
+
+	// whether the events logged with [common.LevelTest] are written
+	testEvents atomic.Bool
+}
+
+// Use this function to instantiate [Levels] with the given base level
+func NewLevels(base slog.Leveler) *Levels {
+	return &Levels{
+		base:    base,
+		modules: make(map[string]slog.Level),
+	}
+}
+
+// Returns the level of module (the base level if no level was set for it)
+func (l *Levels) Level(module string) slog.Level {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+	if level, ok := l.modules[module]; ok {
+		return level
+	}
+	return l.base.Level()
+}
+
+// Replace the levels of the modules
+func (l *Levels) SetModules(modules map[string]slog.Level) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.modules = modules
+}
+
+// Enable or disable writing the events logged with [common.LevelTest]
+func (l *Levels) SetTestEvents(enabled bool) {
+	l.testEvents.Store(enabled)
+}
+
+// Parse the levels of the modules in the form "module=LEVEL" separated by
+// commas or spaces (e.g. "strategy=INFO,horzAPI=WARN").
+func ParseModuleLevels(s string) (map[string]slog.Level, error) {
+	ret := make(map[string]slog.Level)
+	for _, entry := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' }) {
+		module, levelStr, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("expected module=LEVEL, got %q", entry)
+		}
+		if !slices.Contains(Modules, module) {
+			return nil, fmt.Errorf("unknown module %q (available: %v)", module, Modules)
+		}
+		var level slog.Level
+		if err := level.UnmarshalText([]byte(levelStr)); err != nil {
+			return nil, fmt.Errorf("module %s: %w", module, err)
+		}
+		ret[module] = level
+	}
+	return ret, nil
+}
+
+// ModuleHandler filters the logs by the level of the module they stem from.
+//
+// The module is taken from the "module" attribute added via
+// [slog.Logger.With]. The wrapped handler should accept all levels, the
+// filtering is done by this handler.
+type ModuleHandler struct {
+	handler slog.Handler
+	levels  *Levels
+	module  string
+}
+
+// NewModuleHandler creates a new ModuleHandler.
+func NewModuleHandler(handler slog.Handler, levels *Levels) *ModuleHandler {
+	return &ModuleHandler{
+		handler: handler,
+		levels:  levels,
+	}
+}
+
+// Enabled checks if the level is enabled for the module of this handler.
+// Events logged with [common.LevelTest] are only enabled if they were turned on
+// explicitly.
+func (h *ModuleHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	if level == common.LevelTest {
+		return h.levels.testEvents.Load()
+	}
+	return level >= h.levels.Level(h.module)
+}
+
+// Handle processes the log entry if its level is enabled.
+func (h *ModuleHandler) Handle(ctx context.Context, r slog.Record) error {
+	if h.Enabled(ctx, r.Level) {
+		return h.handler.Handle(ctx, r)
+	}
+	return nil
+}
+
+// WithAttrs returns a new handler with additional attributes. If one of them
+// is the module, the new handler uses the level of that module.
+func (h *ModuleHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	module := h.module
+	for _, a := range attrs {
+		if a.Key == "module" {
+			module = a.Value.String()
+		}
+	}
+	return &ModuleHandler{
+		handler: h.handler.WithAttrs(attrs),
+		levels:  h.levels,
+		module:  module,
+	}
+}
+
+// WithGroup returns a new handler with an additional group.
+func (h *ModuleHandler) WithGroup(name string) slog.Handler {
+	return &ModuleHandler{
+		handler: h.handler.WithGroup(name),
+		levels:  h.levels,
+		module:  h.module,
+	}
+}
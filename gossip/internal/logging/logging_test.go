@@ -0,0 +1,104 @@
+/*
+* gossip
+* Copyright (C) 2024 Fabio Gaiba and Lukas Heindl
+*
+* This program is free software: you can redistribute it and/or modify
+* it under the terms of the GNU General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* This program is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+* GNU General Public License for more details.
+*
+* You should have received a copy of the GNU General Public License
+* along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package logging_test
+
+import (
+	"bytes"
+	"context"
+	"gossip/common"
+	"gossip/internal/logging"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestModuleHandler(t *testing.T) {
+	var buf bytes.Buffer
+	base := new(slog.LevelVar)
+	base.Set(slog.LevelInfo)
+	levels := logging.NewLevels(base)
+	log := slog.New(logging.NewModuleHandler(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: common.LevelTest}), levels))
+
+	modules, err := logging.ParseModuleLevels("strategy=DEBUG, horzAPI=ERROR")
+	if err != nil {
+		t.Fatalf("parsing the module levels failed: %v", err)
+	}
+	levels.SetModules(modules)
+
+	log.With("module", "main").Debug("main debug")
+	log.With("module", "main").Info("main info")
+	log.With("module", "strategy").Debug("strategy debug")
+	log.With("module", "horzAPI").Warn("horzAPI warn")
+	log.With("module", "strategy").Log(context.Background(), common.LevelTest, "test event hidden")
+	levels.SetTestEvents(true)
+	log.With("module", "strategy").Log(context.Background(), common.LevelTest, "test event shown")
+
+	out := buf.String()
+	for _, s := range []string{"main info", "strategy debug", "test event shown"} {
+		if !strings.Contains(out, s) {
+			t.Errorf("%q should have been logged", s)
+		}
+	}
+	for _, s := range []string{"main debug", "horzAPI warn", "test event hidden"} {
+		if strings.Contains(out, s) {
+			t.Errorf("%q should not have been logged", s)
+		}
+	}
+
+	for _, s := range []string{"strategy", "foo=INFO", "strategy=LOUD"} {
+		if _, err := logging.ParseModuleLevels(s); err == nil {
+			t.Errorf("parsing %q should fail", s)
+		}
+	}
+}
+
+func TestRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gossip.log")
+	f, err := logging.Open(path, 10, 2)
+	if err != nil {
+		t.Fatalf("opening the log file failed: %v", err)
+	}
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		if _, err := f.Write([]byte(line)); err != nil {
+			t.Fatalf("writing failed: %v", err)
+		}
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("closing failed: %v", err)
+	}
+
+	for name, expected := range map[string]string{
+		path:        "fourth\n",
+		path + ".1": "third\n",
+		path + ".2": "second\n",
+	} {
+		content, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatalf("reading %s failed: %v", name, err)
+		}
+		if string(content) != expected {
+			t.Errorf("%s should contain %q but contains %q", name, expected, content)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("only 2 rotated files should be kept")
+	}
+}
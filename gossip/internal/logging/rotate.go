@@ -0,0 +1,129 @@
+/*
+* gossip
+* Copyright (C) 2024 Fabio Gaiba and Lukas Heindl
+*
+* This program is free software: you can redistribute it and/or modify
+* it under the terms of the GNU General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* This program is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+* GNU General Public License for more details.
+*
+* You should have received a copy of the GNU General Public License
+* along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package logging
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// File is a log file which is rotated once it exceeds a maximum size.
+//
+// On rotation the file is renamed to <path>.1 (the previous <path>.1 to
+// <path>.2 and so on), only the newest maxFiles rotated files are kept.
+//
+// File is safe for concurrent use.
+type File struct {
+	path     string
+	maxSize  int64
+	maxFiles uint
+
+	mutex sync.Mutex
+	file  *os.File
+	size  int64
+}
+
+// Open (or create) the log file at path and append to it. A maxSize of 0
+// disables the rotation.
+func Open(path string, maxSize int64, maxFiles uint) (*File, error) {
+	f := &File{path: path, maxSize: maxSize, maxFiles: maxFiles}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// open the file at f.path for appending
+func (f *File) open() error {
+	file, err := os.OpenFile(f.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return fmt.Errorf("open log file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("stat log file: %w", err)
+	}
+	f.file = file
+	f.size = info.Size()
+	return nil
+}
+
+// Write p to the log file, rotate the file before if p does not fit anymore.
+func (f *File) Write(p []byte) (int, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+	if f.maxSize > 0 && f.size > 0 && f.size+int64(len(p)) > f.maxSize {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// rename the current file and start with an empty one
+func (f *File) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	f.file = nil
+
+	name := func(i uint) string {
+		return fmt.Sprintf("%s.%d", f.path, i)
+	}
+	if f.maxFiles == 0 {
+		if err := os.Remove(f.path); err != nil {
+			return err
+		}
+	} else {
+		// drop the oldest file and shift the others
+		if err := os.Remove(name(f.maxFiles)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		for i := f.maxFiles - 1; i > 0; i-- {
+			if err := os.Rename(name(i), name(i+1)); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+		}
+		if err := os.Rename(f.path, name(1)); err != nil {
+			return err
+		}
+	}
+	return f.open()
+}
+
+// Close the log file
+func (f *File) Close() error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
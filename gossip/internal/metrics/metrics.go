@@ -0,0 +1,376 @@
+/*
+* gossip
+* Copyright (C) 2024 Fabio Gaiba and Lukas Heindl
+*
+* This program is free software: you can redistribute it and/or modify
+* it under the terms of the GNU General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* This program is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+* GNU General Public License for more details.
+*
+* You should have received a copy of the GNU General Public License
+* along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+// Package metrics implements a small metrics registry which can be exported
+// in the prometheus text exposition format.
+//
+// All methods are nil-safe: a nil *Registry hands out nil metrics and
+// updating a nil metric is a no-op. This way metrics can be disabled by
+// simply not creating a registry.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Sample of a metric with a specific set of label values
+type Sample struct {
+	LabelValues []string
+	Value       float64
+}
+
+// everything which can be written to the exposition
+type collector interface {
+	write(w *bufio.Writer)
+}
+
+// This struct holds all metrics and renders them.
+//
+// Use [NewRegistry] to instantiate it.
+type Registry struct {
+	collectors []collector
+	mutex      sync.Mutex
+}
+
+// Use this function to instantiate a registry
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// add a collector to the registry
+func (r *Registry) register(c collector) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.collectors = append(r.collectors, c)
+}
+
+// Write all metrics in the text exposition format to w
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	if r == nil {
+		return 0, nil
+	}
+	r.mutex.Lock()
+	collectors := slices.Clone(r.collectors)
+	r.mutex.Unlock()
+
+	cw := &countingWriter{w: w}
+	bw := bufio.NewWriter(cw)
+	for _, c := range collectors {
+		c.write(bw)
+	}
+	err := bw.Flush()
+	return cw.n, err
+}
+
+// Returns a handler which serves the metrics (e.g. on /metrics)
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		r.WriteTo(w)
+	})
+}
+
+// common fields of all metrics
+type desc struct {
+	name       string
+	help       string
+	labelNames []string
+}
+
+// write the HELP and TYPE lines
+func (d *desc) writeHeader(w *bufio.Writer, typ string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", d.name, strings.ReplaceAll(d.help, "\n", " "))
+	fmt.Fprintf(w, "# TYPE %s %s\n", d.name, typ)
+}
+
+// write a single sample line, extra is an additional label (e.g. le of a
+// histogram bucket)
+func (d *desc) writeSample(w *bufio.Writer, suffix string, labelValues []string, extra [2]string, v float64) {
+	w.WriteString(d.name)
+	w.WriteString(suffix)
+	n := 0
+	label := func(k, v string) {
+		if n == 0 {
+			w.WriteByte('{')
+		} else {
+			w.WriteByte(',')
+		}
+		n++
+		w.WriteString(k)
+		w.WriteString(`="`)
+		w.WriteString(escapeLabel(v))
+		w.WriteByte('"')
+	}
+	for i, name := range d.labelNames {
+		if i < len(labelValues) {
+			label(name, labelValues[i])
+		}
+	}
+	if extra[0] != "" {
+		label(extra[0], extra[1])
+	}
+	if n > 0 {
+		w.WriteByte('}')
+	}
+	w.WriteByte(' ')
+	w.WriteString(formatValue(v))
+	w.WriteByte('\n')
+}
+
+// A value which is identified by the values of its labels
+type series struct {
+	labelValues []string
+	value       float64
+}
+
+// collection of series, sorted by their label values when written
+type seriesMap struct {
+	data  map[string]*series
+	mutex sync.Mutex
+}
+
+// obtain the series for the label values (created on demand), the mutex must
+// be held
+func (s *seriesMap) unsafeGet(labelValues []string) *series {
+	if s.data == nil {
+		s.data = make(map[string]*series)
+	}
+	key := strings.Join(labelValues, "\xff")
+	x, ok := s.data[key]
+	if !ok {
+		x = &series{labelValues: slices.Clone(labelValues)}
+		s.data[key] = x
+	}
+	return x
+}
+
+// copy all series sorted by their label values
+func (s *seriesMap) snapshot() []series {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	ret := make([]series, 0, len(s.data))
+	for _, x := range s.data {
+		ret = append(ret, *x)
+	}
+	slices.SortFunc(ret, func(a, b series) int {
+		return slices.Compare(a.labelValues, b.labelValues)
+	})
+	return ret
+}
+
+// A counter which can only increase
+type Counter struct {
+	desc
+	values seriesMap
+}
+
+// Create a new counter and register it. The amount of labelValues passed on
+// updates must match the amount of labelNames.
+func (r *Registry) NewCounter(name, help string, labelNames ...string) *Counter {
+	if r == nil {
+		return nil
+	}
+	c := &Counter{desc: desc{name: name, help: help, labelNames: labelNames}}
+	r.register(c)
+	return c
+}
+
+// Increment the counter by one
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Increment the counter by v (must be >= 0)
+func (c *Counter) Add(v float64, labelValues ...string) {
+	if c == nil {
+		return
+	}
+	c.values.mutex.Lock()
+	defer c.values.mutex.Unlock()
+	c.values.unsafeGet(labelValues).value += v
+}
+
+func (c *Counter) write(w *bufio.Writer) {
+	c.writeHeader(w, "counter")
+	for _, s := range c.values.snapshot() {
+		c.writeSample(w, "", s.labelValues, [2]string{}, s.value)
+	}
+}
+
+// A gauge which can be set to arbitrary values
+type Gauge struct {
+	desc
+	values seriesMap
+}
+
+// Create a new gauge and register it. The amount of labelValues passed on
+// updates must match the amount of labelNames.
+func (r *Registry) NewGauge(name, help string, labelNames ...string) *Gauge {
+	if r == nil {
+		return nil
+	}
+	g := &Gauge{desc: desc{name: name, help: help, labelNames: labelNames}}
+	r.register(g)
+	return g
+}
+
+// Set the gauge to v
+func (g *Gauge) Set(v float64, labelValues ...string) {
+	if g == nil {
+		return
+	}
+	g.values.mutex.Lock()
+	defer g.values.mutex.Unlock()
+	g.values.unsafeGet(labelValues).value = v
+}
+
+// Add v (might be negative) to the gauge
+func (g *Gauge) Add(v float64, labelValues ...string) {
+	if g == nil {
+		return
+	}
+	g.values.mutex.Lock()
+	defer g.values.mutex.Unlock()
+	g.values.unsafeGet(labelValues).value += v
+}
+
+func (g *Gauge) write(w *bufio.Writer) {
+	g.writeHeader(w, "gauge")
+	for _, s := range g.values.snapshot() {
+		g.writeSample(w, "", s.labelValues, [2]string{}, s.value)
+	}
+}
+
+// A gauge whose samples are obtained by calling a function each time the
+// metrics are written
+type gaugeFunc struct {
+	desc
+	f func() []Sample
+}
+
+// Create a gauge whose samples are obtained by calling f on every scrape and
+// register it. f is called from a different goroutine, so it must be safe
+// for concurrent use.
+func (r *Registry) NewGaugeFunc(name, help string, labelNames []string, f func() []Sample) {
+	if r == nil {
+		return
+	}
+	r.register(&gaugeFunc{desc: desc{name: name, help: help, labelNames: labelNames}, f: f})
+}
+
+func (g *gaugeFunc) write(w *bufio.Writer) {
+	g.writeHeader(w, "gauge")
+	for _, s := range g.f() {
+		g.writeSample(w, "", s.LabelValues, [2]string{}, s.Value)
+	}
+}
+
+// A histogram counting observations in buckets
+type Histogram struct {
+	desc
+	// upper bounds of the buckets (ascending)
+	buckets []float64
+	counts  []uint64
+	sum     float64
+	count   uint64
+	mutex   sync.Mutex
+}
+
+// Create a histogram with the given (ascending) upper bounds of the buckets
+// and register it. A +Inf bucket is always added.
+func (r *Registry) NewHistogram(name, help string, buckets []float64) *Histogram {
+	if r == nil {
+		return nil
+	}
+	h := &Histogram{
+		desc:    desc{name: name, help: help},
+		buckets: slices.Clone(buckets),
+		counts:  make([]uint64, len(buckets)),
+	}
+	r.register(h)
+	return h
+}
+
+// Add an observation to the histogram
+func (h *Histogram) Observe(v float64) {
+	if h == nil {
+		return
+	}
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	for i, b := range h.buckets {
+		if v <= b {
+			h.counts[i]++
+		}
+	}
+	h.sum += v
+	h.count++
+}
+
+func (h *Histogram) write(w *bufio.Writer) {
+	h.mutex.Lock()
+	counts := slices.Clone(h.counts)
+	sum, count := h.sum, h.count
+	h.mutex.Unlock()
+
+	h.writeHeader(w, "histogram")
+	for i, b := range h.buckets {
+		h.writeSample(w, "_bucket", nil, [2]string{"le", formatValue(b)}, float64(counts[i]))
+	}
+	h.writeSample(w, "_bucket", nil, [2]string{"le", "+Inf"}, float64(count))
+	h.writeSample(w, "_sum", nil, [2]string{}, sum)
+	h.writeSample(w, "_count", nil, [2]string{}, float64(count))
+}
+
+// format a value as required by the exposition format
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// escape a label value as required by the exposition format
+func escapeLabel(v string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v)
+}
+
+// writer which counts the bytes written
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
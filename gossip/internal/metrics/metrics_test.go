@@ -0,0 +1,86 @@
+/*
+* gossip
+* Copyright (C) 2024 Fabio Gaiba and Lukas Heindl
+*
+* This program is free software: you can redistribute it and/or modify
+* it under the terms of the GNU General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* This program is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+* GNU General Public License for more details.
+*
+* You should have received a copy of the GNU General Public License
+* along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package metrics_test
+
+import (
+	"gossip/internal/metrics"
+	"strings"
+	"testing"
+)
+
+func TestExposition(t *testing.T) {
+	r := metrics.NewRegistry()
+
+	c := r.NewCounter("msgs_total", "Messages received", "type")
+	c.Inc("42")
+	c.Add(2, "42")
+	c.Inc("7")
+
+	g := r.NewGauge("queue_depth", "Depth of the queue")
+	g.Set(5)
+	g.Add(-2)
+
+	r.NewGaugeFunc("conns", "Connections", []string{"state"}, func() []metrics.Sample {
+		return []metrics.Sample{{LabelValues: []string{"valid"}, Value: 3}}
+	})
+
+	h := r.NewHistogram("solve_seconds", "Solve time", []float64{0.1, 1})
+	h.Observe(0.05)
+	h.Observe(0.5)
+	h.Observe(5)
+
+	var b strings.Builder
+	if _, err := r.WriteTo(&b); err != nil {
+		t.Fatalf("writing the metrics failed: %v", err)
+	}
+
+	expected := `# HELP msgs_total Messages received
+# TYPE msgs_total counter
+msgs_total{type="42"} 3
+msgs_total{type="7"} 1
+# HELP queue_depth Depth of the queue
+# TYPE queue_depth gauge
+queue_depth 3
+# HELP conns Connections
+# TYPE conns gauge
+conns{state="valid"} 3
+# HELP solve_seconds Solve time
+# TYPE solve_seconds histogram
+solve_seconds_bucket{le="0.1"} 1
+solve_seconds_bucket{le="1"} 2
+solve_seconds_bucket{le="+Inf"} 3
+solve_seconds_sum 5.55
+solve_seconds_count 3
+`
+	if b.String() != expected {
+		t.Fatalf("unexpected exposition:\n%s", b.String())
+	}
+}
+
+func TestNilRegistry(t *testing.T) {
+	var r *metrics.Registry
+	// none of these may panic
+	r.NewCounter("a", "a").Inc()
+	r.NewGauge("b", "b").Set(1)
+	r.NewHistogram("c", "c", nil).Observe(1)
+	r.NewGaugeFunc("d", "d", nil, nil)
+	if n, err := r.WriteTo(&strings.Builder{}); n != 0 || err != nil {
+		t.Fatalf("nil registry should not write anything")
+	}
+}
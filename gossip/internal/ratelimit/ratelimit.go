@@ -0,0 +1,214 @@
+/*
+* gossip
+* Copyright (C) 2024 Fabio Gaiba and Lukas Heindl
+*
+* This program is free software: you can redistribute it and/or modify
+* it under the terms of the GNU General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* This program is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+* GNU General Public License for more details.
+*
+* You should have received a copy of the GNU General Public License
+* along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+// Package ratelimit implements token buckets which can be used to limit the
+// rate at which events (e.g. packets) are accepted.
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// This struct represents a single token bucket.
+//
+// The bucket is refilled with rate tokens per second and holds at most burst
+// tokens. A rate <= 0 disables the limiting (every event is allowed).
+//
+// The struct contains various internal fields, thus it should only be created
+// by using the [NewBucket] function!
+type Bucket struct {
+	// tokens added per second
+	rate float64
+	// maximum amount of tokens the bucket can hold
+	burst float64
+	// tokens currently available
+	tokens float64
+	// last time the amount of tokens was updated
+	last  time.Time
+	mutex sync.Mutex
+}
+
+// Use this function to instantiate a token bucket.
+//
+// If burst is 0, the burst is set to the rate (but at least 1) so that one
+// second worth of events can pass at once. The bucket starts full.
+func NewBucket(rate float64, burst uint) *Bucket {
+	b := float64(burst)
+	if b == 0 {
+		b = max(1, math.Ceil(rate))
+	}
+	return &Bucket{
+		rate:   rate,
+		burst:  b,
+		tokens: b,
+	}
+}
+
+// Returns weather the bucket limits anything at all
+func (b *Bucket) Unlimited() bool {
+	return b.rate <= 0
+}
+
+// Take one token from the bucket (at time.Now()).
+//
+// Returns false if no token was available, the event should be dropped then.
+func (b *Bucket) Allow() bool {
+	return b.AllowN(time.Now(), 1)
+}
+
+// Take n tokens from the bucket at the time now.
+//
+// Either all n tokens are taken or none (in which case false is returned).
+func (b *Bucket) AllowN(now time.Time, n float64) bool {
+	if b.Unlimited() {
+		return true
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.unsafeRefill(now)
+	if b.tokens < n {
+		return false
+	}
+	b.tokens -= n
+	return true
+}
+
+// Take n tokens from the bucket at the time now, even if less tokens are
+// available. The bucket then goes into debt, which needs to be paid off by
+// refilling before further tokens are available.
+//
+// Returns how long it takes until the debt is paid off (0 if there is none),
+// so the caller can wait this long before the event to stay within the rate.
+func (b *Bucket) TakeN(now time.Time, n float64) time.Duration {
+	if b.Unlimited() {
+		return 0
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.unsafeRefill(now)
+	b.tokens -= n
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// add the tokens refilled until now (without locking the bucket)
+func (b *Bucket) unsafeRefill(now time.Time) {
+	if !b.last.IsZero() && now.After(b.last) {
+		b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	}
+	if b.last.IsZero() || now.After(b.last) {
+		b.last = now
+	}
+}
+
+// Returns the fraction (between 0 and 1) of the burst which is available at
+// the time now, without taking any tokens. Unlimited buckets are always fully
+// available.
+func (b *Bucket) Available(now time.Time) float64 {
+	if b.Unlimited() {
+		return 1
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	tokens := b.tokens
+	if !b.last.IsZero() && now.After(b.last) {
+		tokens = min(b.burst, tokens+now.Sub(b.last).Seconds()*b.rate)
+	}
+	return max(0, tokens) / b.burst
+}
+
+// This struct manages one token bucket per key (e.g. per connection or per
+// gossip type). All buckets share the same rate and burst.
+//
+// The struct contains various internal fields, thus it should only be created
+// by using the [NewLimiter] function!
+type Limiter[K comparable] struct {
+	rate    float64
+	burst   uint
+	buckets map[K]*Bucket
+	mutex   sync.Mutex
+}
+
+// Use this function to instantiate a keyed limiter.
+//
+// See [NewBucket] for the meaning of rate and burst.
+func NewLimiter[K comparable](rate float64, burst uint) *Limiter[K] {
+	return &Limiter[K]{
+		rate:    rate,
+		burst:   burst,
+		buckets: make(map[K]*Bucket),
+	}
+}
+
+// Take one token from the bucket associated with key. Buckets are created on
+// demand.
+//
+// Returns false if no token was available, the event should be dropped then.
+func (l *Limiter[K]) Allow(key K) bool {
+	if l.rate <= 0 {
+		return true
+	}
+	return l.bucket(key).Allow()
+}
+
+// Returns the fraction of the burst of the bucket associated with key which is
+// available now (see [Bucket.Available]). Keys without a bucket are fully
+// available, since their bucket would start full.
+func (l *Limiter[K]) Available(key K) float64 {
+	if l.rate <= 0 {
+		return 1
+	}
+	l.mutex.Lock()
+	b, ok := l.buckets[key]
+	l.mutex.Unlock()
+	if !ok {
+		return 1
+	}
+	return b.Available(time.Now())
+}
+
+// obtain the bucket of the key, create it if it doesn't exist yet
+func (l *Limiter[K]) bucket(key K) *Bucket {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = NewBucket(l.rate, l.burst)
+		l.buckets[key] = b
+	}
+	return b
+}
+
+// Remove the bucket associated with key (e.g. when a connection is closed)
+func (l *Limiter[K]) Forget(key K) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	delete(l.buckets, key)
+}
@@ -0,0 +1,130 @@
+/*
+* gossip
+* Copyright (C) 2024 Fabio Gaiba and Lukas Heindl
+*
+* This program is free software: you can redistribute it and/or modify
+* it under the terms of the GNU General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* This program is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+* GNU General Public License for more details.
+*
+* You should have received a copy of the GNU General Public License
+* along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package ratelimit_test
+
+import (
+	"gossip/internal/ratelimit"
+	"testing"
+	"time"
+)
+
+func TestBucket(t *testing.T) {
+	b := ratelimit.NewBucket(2, 3)
+	now := time.Now()
+
+	// bucket starts full
+	for i := 0; i < 3; i++ {
+		if !b.AllowN(now, 1) {
+			t.Fatalf("token %d should be available in a full bucket", i)
+		}
+	}
+	if b.AllowN(now, 1) {
+		t.Fatalf("bucket should be empty after taking burst tokens")
+	}
+
+	// half a second refills one token
+	now = now.Add(500 * time.Millisecond)
+	if !b.AllowN(now, 1) {
+		t.Fatalf("bucket should have been refilled with one token")
+	}
+	if b.AllowN(now, 1) {
+		t.Fatalf("bucket should be empty again")
+	}
+
+	// refill is capped by the burst
+	now = now.Add(time.Minute)
+	if !b.AllowN(now, 3) {
+		t.Fatalf("bucket should be completely refilled")
+	}
+	if b.AllowN(now, 1) {
+		t.Fatalf("bucket should not hold more than burst tokens")
+	}
+}
+
+func TestBucketAvailable(t *testing.T) {
+	b := ratelimit.NewBucket(2, 4)
+	now := time.Now()
+
+	if a := b.Available(now); a != 1 {
+		t.Fatalf("full bucket should be completely available, was %v", a)
+	}
+	b.AllowN(now, 3)
+	if a := b.Available(now); a != 0.25 {
+		t.Fatalf("a quarter of the bucket should be available, was %v", a)
+	}
+	// refilling is taken into account without taking tokens
+	if a := b.Available(now.Add(500 * time.Millisecond)); a != 0.5 {
+		t.Fatalf("half of the bucket should be available, was %v", a)
+	}
+	if a := ratelimit.NewBucket(0, 0).Available(now); a != 1 {
+		t.Fatalf("unlimited bucket should be completely available, was %v", a)
+	}
+}
+
+func TestBucketTake(t *testing.T) {
+	b := ratelimit.NewBucket(100, 0)
+	now := time.Now()
+
+	if wait := b.TakeN(now, 50); wait != 0 {
+		t.Fatalf("available tokens should not require waiting, wait was %v", wait)
+	}
+	// taking more than available results in a debt
+	if wait := b.TakeN(now, 100); wait != 500*time.Millisecond {
+		t.Fatalf("debt of 50 tokens should take 500ms to pay off, wait was %v", wait)
+	}
+	if b.AllowN(now.Add(400*time.Millisecond), 1) {
+		t.Fatalf("no tokens should be available while the debt is paid off")
+	}
+	if !b.AllowN(now.Add(time.Second), 1) {
+		t.Fatalf("tokens should be available after the debt was paid off")
+	}
+	if wait := ratelimit.NewBucket(0, 0).TakeN(now, 1e9); wait != 0 {
+		t.Fatalf("unlimited bucket should not require waiting, wait was %v", wait)
+	}
+}
+
+func TestBucketUnlimited(t *testing.T) {
+	b := ratelimit.NewBucket(0, 0)
+	for i := 0; i < 1000; i++ {
+		if !b.Allow() {
+			t.Fatalf("bucket with rate 0 should never limit")
+		}
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	l := ratelimit.NewLimiter[string](1, 1)
+
+	if !l.Allow("a") {
+		t.Fatalf("first event of key a should be allowed")
+	}
+	if l.Allow("a") {
+		t.Fatalf("second event of key a should be limited")
+	}
+	// keys are independent
+	if !l.Allow("b") {
+		t.Fatalf("first event of key b should be allowed")
+	}
+
+	// forgetting a key resets the bucket
+	l.Forget("a")
+	if !l.Allow("a") {
+		t.Fatalf("event of key a should be allowed after forgetting the key")
+	}
+}
@@ -155,6 +155,30 @@ func (r *Ringbuffer[T]) FindFirst(f func(T) bool) (T, error) {
 	return ret, ErrNotPresent
 }
 
+// Change the capacity of the ringbuffer.
+//
+// If the new capacity is smaller than the amount of stored values, the oldest
+// values are removed.
+func (r *Ringbuffer[T]) Resize(capacity uint) {
+	r.cap = capacity
+	if r.len <= capacity {
+		return
+	}
+	if capacity == 0 {
+		r.data = nil
+		r.len = 0
+		return
+	}
+	// the element after data is the oldest one
+	r.data.Unlink(int(r.len - capacity))
+	r.len = capacity
+}
+
+// Returns the amount of values currently stored in the ringbuffer
+func (r *Ringbuffer[T]) Len() uint {
+	return r.len
+}
+
 func (r *Ringbuffer[T]) ExtractToSlice() []T {
 	ret := make([]T, 0)
 	r.Do(func(x T) {
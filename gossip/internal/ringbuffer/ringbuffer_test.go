@@ -174,3 +174,32 @@ func TestFindFirst(t *testing.T) {
 		t.Fatalf("Found first should have found nothing")
 	}
 }
+
+func TestResize(t *testing.T) {
+	rb := ringbuffer.NewRingbuffer[int](5)
+
+	for i := 1; i < 6; i++ {
+		rb.Insert(i)
+	}
+
+	rb.Resize(3)
+	res := rb.Filter(func(a int) bool { return true })
+	should := []int{5, 3, 4}
+	if !reflect.DeepEqual(res, should) {
+		t.Fatalf("After shrinking ringbuffer should be %v but is %v", should, res)
+	}
+
+	rb.Resize(4)
+	rb.Insert(6)
+	rb.Insert(7)
+	res = rb.Filter(func(a int) bool { return true })
+	should = []int{7, 4, 5, 6}
+	if !reflect.DeepEqual(res, should) {
+		t.Fatalf("After growing ringbuffer should be %v but is %v", should, res)
+	}
+
+	rb.Resize(0)
+	if rb.Len() != 0 {
+		t.Fatalf("Ringbuffer with capacity 0 should be empty but contains %v values", rb.Len())
+	}
+}
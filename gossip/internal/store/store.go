@@ -0,0 +1,253 @@
+/*
+* gossip
+* Copyright (C) 2024 Fabio Gaiba and Lukas Heindl
+*
+* This program is free software: you can redistribute it and/or modify
+* it under the terms of the GNU General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* This program is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+* GNU General Public License for more details.
+*
+* You should have received a copy of the GNU General Public License
+* along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+// Package store implements a simple append-only log on disk. It can be used to
+// persist state across restarts of the application.
+package store
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// define errors
+var (
+	ErrClosed error = errors.New("store already closed")
+)
+
+// amount of appends after which the log is compacted at the latest
+const COMPACT_MIN = 1024
+
+// A single entry of the log
+type entry[T any] struct {
+	Time  time.Time `json:"time"`
+	Value T         `json:"value"`
+}
+
+// This struct represents an append-only log of values of type T which is
+// stored as json lines in a file.
+//
+// Entries older than the retention are dropped when the log is compacted.
+// Compaction rewrites the file and happens when opening the log and
+// regularly while appending.
+//
+// A nil *Log is valid and behaves like a log which never stores anything,
+// this way the store can simply be disabled.
+//
+// The struct contains various internal fields, thus it should only be created
+// by using the [Open] function!
+type Log[T any] struct {
+	path      string
+	retention time.Duration
+	file      *os.File
+	writer    *bufio.Writer
+	// all entries currently contained in the file
+	entries []entry[T]
+	// amount of appends since the last compaction
+	appended int
+	mutex    sync.Mutex
+}
+
+// Open the log stored at path (the file is created if it doesn't exist yet).
+//
+// The existing entries are loaded, entries older than retention are dropped.
+// A partially written last line (e.g. after a crash) is ignored.
+func Open[T any](path string, retention time.Duration) (*Log[T], error) {
+	l := &Log[T]{
+		path:      path,
+		retention: retention,
+	}
+
+	f, err := os.Open(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("opening the store failed: %w", err)
+	} else if err == nil {
+		d := json.NewDecoder(bufio.NewReader(f))
+		for {
+			var e entry[T]
+			if err := d.Decode(&e); err != nil {
+				// either EOF or a broken entry at the end
+				break
+			}
+			l.entries = append(l.entries, e)
+		}
+		f.Close()
+	}
+
+	if err := l.unsafeCompact(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Returns the values of all entries which are not expired (in the order they
+// were appended)
+func (l *Log[T]) Values() []T {
+	if l == nil {
+		return nil
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	deadline := time.Now().Add(-l.retention)
+	ret := make([]T, 0, len(l.entries))
+	for _, e := range l.entries {
+		if e.Time.After(deadline) {
+			ret = append(ret, e.Value)
+		}
+	}
+	return ret
+}
+
+// Append a value to the log.
+//
+// The value is written to the buffer of the file, [Log.Sync] or [Log.Close]
+// make sure it is actually written to the file. An error of the regular
+// compaction is returned as well, the value is appended nonetheless.
+func (l *Log[T]) Append(v T) error {
+	if l == nil {
+		return nil
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.file == nil {
+		return ErrClosed
+	}
+
+	e := entry[T]{Time: time.Now(), Value: v}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if _, err := l.writer.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	l.entries = append(l.entries, e)
+
+	l.appended++
+	if l.appended >= max(COMPACT_MIN, len(l.entries)/2) {
+		return l.unsafeCompact()
+	}
+	return nil
+}
+
+// Flush the buffered entries to the file
+func (l *Log[T]) Sync() error {
+	if l == nil {
+		return nil
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.file == nil {
+		return ErrClosed
+	}
+	if err := l.writer.Flush(); err != nil {
+		return err
+	}
+	return l.file.Sync()
+}
+
+// Flush all buffered entries and close the file. The log cannot be used
+// afterwards.
+func (l *Log[T]) Close() error {
+	if l == nil {
+		return nil
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.file == nil {
+		return ErrClosed
+	}
+	err := l.writer.Flush()
+	if e := l.file.Close(); e != nil {
+		err = e
+	}
+	l.file = nil
+	return err
+}
+
+// Drop the expired entries and rewrite the file with the remaining ones
+// (without locking the mutex).
+//
+// The entries are written to a temporary file first which then replaces the
+// log, so the log is never only partially written. If this fails the log keeps
+// appending to the old file and the compaction is retried after the next
+// COMPACT_MIN appends.
+func (l *Log[T]) unsafeCompact() error {
+	deadline := time.Now().Add(-l.retention)
+	kept := l.entries[:0]
+	for _, e := range l.entries {
+		if e.Time.After(deadline) {
+			kept = append(kept, e)
+		}
+	}
+	l.entries = kept
+	l.appended = 0
+
+	if l.file != nil {
+		if err := l.writer.Flush(); err != nil {
+			return err
+		}
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(l.path), ".store-*")
+	if err != nil {
+		return fmt.Errorf("compacting the store failed: %w", err)
+	}
+	w := bufio.NewWriter(tmp)
+	enc := json.NewEncoder(w)
+	for _, e := range l.entries {
+		if err := enc.Encode(e); err != nil {
+			return discardTemp(tmp, err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return discardTemp(tmp, err)
+	}
+	if err := tmp.Sync(); err != nil {
+		return discardTemp(tmp, err)
+	}
+	if err := os.Rename(tmp.Name(), l.path); err != nil {
+		return discardTemp(tmp, err)
+	}
+
+	// the temporary file is the log now, new entries are appended to it
+	if l.file != nil {
+		l.file.Close()
+	}
+	l.file = tmp
+	l.writer = w
+	return nil
+}
+
+// Close and remove the temporary file of a failed compaction
+func discardTemp(tmp *os.File, err error) error {
+	tmp.Close()
+	os.Remove(tmp.Name())
+	return fmt.Errorf("compacting the store failed: %w", err)
+}
@@ -0,0 +1,136 @@
+/*
+* gossip
+* Copyright (C) 2024 Fabio Gaiba and Lukas Heindl
+*
+* This program is free software: you can redistribute it and/or modify
+* it under the terms of the GNU General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* This program is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+* GNU General Public License for more details.
+*
+* You should have received a copy of the GNU General Public License
+* along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package store_test
+
+import (
+	"gossip/internal/store"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store")
+
+	l, err := store.Open[int](path, time.Hour)
+	if err != nil {
+		t.Fatalf("opening a new store failed: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := l.Append(i); err != nil {
+			t.Fatalf("append failed: %v", err)
+		}
+	}
+	if err := l.Close(); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+	if err := l.Append(3); err != store.ErrClosed {
+		t.Fatalf("append to a closed store should fail with ErrClosed, got %v", err)
+	}
+
+	// simulate a crash in the middle of writing an entry
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	f.WriteString(`{"time":"2024-`)
+	f.Close()
+
+	l, err = store.Open[int](path, time.Hour)
+	if err != nil {
+		t.Fatalf("reopening the store failed: %v", err)
+	}
+	if v := l.Values(); !slices.Equal(v, []int{0, 1, 2}) {
+		t.Fatalf("reloaded values are wrong: %v", v)
+	}
+	l.Close()
+
+	// everything is expired with a zero retention
+	l, err = store.Open[int](path, 0)
+	if err != nil {
+		t.Fatalf("reopening the store failed: %v", err)
+	}
+	if v := l.Values(); len(v) != 0 {
+		t.Fatalf("expired values should be dropped: %v", v)
+	}
+	l.Close()
+}
+
+func TestFailedCompaction(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "data")
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatalf("%v", err)
+	}
+	l, err := store.Open[int](filepath.Join(dir, "store"), time.Hour)
+	if err != nil {
+		t.Fatalf("opening a new store failed: %v", err)
+	}
+
+	// the compaction fails since the directory vanished, the store stays open
+	moved := dir + ".moved"
+	if err := os.Rename(dir, moved); err != nil {
+		t.Fatalf("%v", err)
+	}
+	var compactErr error
+	for i := 0; i < store.COMPACT_MIN; i++ {
+		if err := l.Append(i); err != nil {
+			compactErr = err
+		}
+	}
+	if compactErr == nil {
+		t.Fatalf("compaction should have failed")
+	}
+	if err := l.Append(store.COMPACT_MIN); err != nil {
+		t.Fatalf("append after a failed compaction failed: %v", err)
+	}
+
+	// the next compaction succeeds again
+	if err := os.Rename(moved, dir); err != nil {
+		t.Fatalf("%v", err)
+	}
+	for i := store.COMPACT_MIN + 1; i < 2*store.COMPACT_MIN; i++ {
+		if err := l.Append(i); err != nil {
+			t.Fatalf("append failed: %v", err)
+		}
+	}
+	if err := l.Close(); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+
+	l, err = store.Open[int](filepath.Join(dir, "store"), time.Hour)
+	if err != nil {
+		t.Fatalf("reopening the store failed: %v", err)
+	}
+	defer l.Close()
+	if v := l.Values(); len(v) != 2*store.COMPACT_MIN || v[0] != 0 || v[len(v)-1] != 2*store.COMPACT_MIN-1 {
+		t.Fatalf("reloaded %d values, want %d", len(v), 2*store.COMPACT_MIN)
+	}
+}
+
+func TestNilLog(t *testing.T) {
+	var l *store.Log[int]
+	if err := l.Append(1); err != nil {
+		t.Fatalf("append on a nil store should be a no-op")
+	}
+	if v := l.Values(); len(v) != 0 {
+		t.Fatalf("nil store should not contain values")
+	}
+}
@@ -28,7 +28,7 @@ type node struct {
 	// use pointers for a quick and dirty optional
 	Degree      *uint
 	Cache_size  *uint
-	GossipTimer *uint
+	GossipTimer *uint // in seconds
 }
 
 // do custom unmarshalling to allow node to also be a simple integer (use
@@ -65,6 +65,11 @@ func FilterLog(ctx context.Context, c chan<- Event, r io.Reader) {
 		var e Event
 		err := d.Decode(&e)
 		if err != nil {
+			select {
+			case <-ctx.Done():
+				return
+			default:
+			}
 			panic(err)
 		}
 		if e.Level != int(common.LevelTest) {
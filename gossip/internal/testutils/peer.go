@@ -95,7 +95,8 @@ func (p *peer) markAllValid() {
 		buf = buf[0:msgHdr.CalcSize()]
 		// read the message header
 		nRead, err := io.ReadFull(p.conn, buf)
-		if errors.Is(err, io.EOF) {
+		// the connection is closed by the peer (EOF) or on teardown
+		if errors.Is(err, io.EOF) || errors.Is(err, net.ErrClosed) {
 			return
 		}
 
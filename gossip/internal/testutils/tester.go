@@ -59,6 +59,12 @@ func (t testState) String() string {
 type Tester struct {
 	// the graph on which the test is executed
 	G Graph
+	// the gossip strategy all peers use (default strategy if empty), has to
+	// be set before [Startup]
+	Strategy string
+	// called with the arguments of each peer before it is started (e.g. to
+	// set further options), may be nil. Has to be set before [Startup]
+	Configure func(a *args.Args)
 	// mapping from nodeIdx to peer
 	Peers map[uint]*peer
 	// mapping from connectionId (ip address) to nodeIdx
@@ -175,11 +181,10 @@ func (t *Tester) Startup(startIp string) error {
 	for nodeIdx, node := range t.G.Nodes {
 		nodeIdx := uint(nodeIdx)
 
-		args := args.Args{
-			Hz_addr:    ip.String() + ":7001",
-			Vert_addr:  ip.String() + ":6001",
-			Peer_addrs: []string{},
-		}
+		args := args.NewFromDefaults()
+		args.Hz_addr = ip.String() + ":7001"
+		args.Vert_addr = ip.String() + ":6001"
+		args.Peer_addrs = []string{}
 
 		// read config, use config from json. If unset, use default values
 		if node.Degree != nil {
@@ -193,9 +198,16 @@ func (t *Tester) Startup(startIp string) error {
 			args.Cache_size = 50
 		}
 		if node.GossipTimer != nil {
-			args.GossipTimer = *node.GossipTimer
+			args.GossipTimer = time.Duration(*node.GossipTimer) * time.Second
 		} else {
-			args.GossipTimer = 1
+			args.GossipTimer = time.Second
+		}
+
+		if t.Strategy != "" {
+			args.Strategy = t.Strategy
+		}
+		if t.Configure != nil {
+			t.Configure(&args)
 		}
 
 		// add the neighbors
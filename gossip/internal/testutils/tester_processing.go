@@ -141,6 +141,31 @@ func (t *Tester) ProcessGraphDistCnt(startNode uint) (data.CntDistancesAll, erro
 
 // get timeseries with amount of packets sent over time
 func (t *Tester) ProcessSentPackets(gtype common.GossipType, all bool) (data.SentPacketsCntAll, error) {
+	return t.processSentPackets("hz packet sent", gtype, all)
+}
+
+// get timeseries with amount of packets sent over time, excluding the packets
+// of the periodic PoW and keepalive (which only depend on the duration of the
+// test)
+func (t *Tester) ProcessSentNonPowPackets(gtype common.GossipType, all bool) (data.SentPacketsCntAll, error) {
+	return t.processSentPackets("hz non-pow packet sent", gtype, all)
+}
+
+// get timeseries with amount of bytes sent over time (Cnt is the amount of
+// bytes)
+func (t *Tester) ProcessSentBytes(gtype common.GossipType, all bool) (data.SentPacketsCntAll, error) {
+	return t.processSentPackets("hz bytes sent", gtype, all)
+}
+
+// get timeseries with amount of bytes sent over time, excluding the bytes of
+// the periodic PoW and keepalive messages
+func (t *Tester) ProcessSentNonPowBytes(gtype common.GossipType, all bool) (data.SentPacketsCntAll, error) {
+	return t.processSentPackets("hz non-pow bytes sent", gtype, all)
+}
+
+// get timeseries with amount of packets sent over time, counting the events
+// with message msg
+func (t *Tester) processSentPackets(msg string, gtype common.GossipType, all bool) (data.SentPacketsCntAll, error) {
 	ret := make(data.SentPacketsCntAll, 0)
 	if t.state != TestStateProcessing {
 		return ret, errors.New("cannot do processing if tester is not in processing state")
@@ -148,7 +173,7 @@ func (t *Tester) ProcessSentPackets(gtype common.GossipType, all bool) (data.Sen
 
 	sentEvents := make([]Event, 0, len(t.Events))
 	for _, e := range t.Events {
-		if !(e.Msg == "hz packet sent") {
+		if !(e.Msg == msg) {
 			continue
 		}
 		if !(all || gtype == e.MsgType) {
@@ -0,0 +1,160 @@
+/*
+* gossip
+* Copyright (C) 2024 Fabio Gaiba and Lukas Heindl
+*
+* This program is free software: you can redistribute it and/or modify
+* it under the terms of the GNU General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* This program is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+* GNU General Public License for more details.
+*
+* You should have received a copy of the GNU General Public License
+* along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+// Package trace records how messages propagate through the network.
+//
+// Each node writes the events concerning the messages it handles to a trace
+// file (one JSON object per line, see [Event]). The trace files of many nodes
+// can be merged into one propagation tree per message with [Build].
+package trace
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gossip/common"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Kind of a trace event
+type Kind string
+
+const (
+	// message was announced by a module of the node
+	Announced Kind = "announced"
+	// new message was received from a peer
+	Received Kind = "received"
+	// already known message was received from a peer
+	Duplicate Kind = "duplicate"
+	// message from a peer was dropped (e.g. due to the rate limits)
+	Dropped Kind = "dropped"
+	// message was marked valid by the modules
+	Validated Kind = "validated"
+	// message was marked invalid by the modules
+	Invalid Kind = "invalid"
+	// message was sent to a peer
+	Forwarded Kind = "forwarded"
+)
+
+// A single trace event. The json representation is the stable schema of the
+// trace files.
+type Event struct {
+	Time time.Time `json:"time"`
+	// node which recorded the event (its p2p address)
+	Node       string            `json:"node"`
+	Kind       Kind              `json:"event"`
+	MessageId  uint16            `json:"msgId"`
+	GossipType common.GossipType `json:"type"`
+	TTL        uint8             `json:"ttl"`
+	// address of the peer on the connection the message was received on
+	// or sent to (empty for events not related to a peer)
+	Peer string `json:"peer,omitempty"`
+	// own address on that connection
+	Local string `json:"local,omitempty"`
+}
+
+// Sink writes the trace events of a node to a file.
+//
+// All methods can be called on a nil Sink, in which case they do nothing. This
+// way tracing can be disabled by simply not opening a sink.
+type Sink struct {
+	node  string
+	mutex sync.Mutex
+	file  *os.File
+	w     *bufio.Writer
+	enc   *json.Encoder
+}
+
+// Open the trace file at path (new events are appended). node identifies the
+// node in the events.
+func Open(path string, node string) (*Sink, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open trace file: %w", err)
+	}
+	w := bufio.NewWriter(file)
+	return &Sink{node: node, file: file, w: w, enc: json.NewEncoder(w)}, nil
+}
+
+// Record ev. The node is set by the sink, the time is set to now if it is
+// unset.
+func (s *Sink) Record(ev Event) {
+	if s == nil {
+		return
+	}
+	if ev.Time.IsZero() {
+		ev.Time = time.Now()
+	}
+	ev.Node = s.node
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.file == nil {
+		return
+	}
+	// errors show up on Flush as well
+	_ = s.enc.Encode(ev)
+}
+
+// Write the buffered events to the file
+func (s *Sink) Flush() error {
+	if s == nil {
+		return nil
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.file == nil {
+		return os.ErrClosed
+	}
+	return s.w.Flush()
+}
+
+// Flush the buffered events and close the file
+func (s *Sink) Close() error {
+	if s == nil {
+		return nil
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	err := errors.Join(s.w.Flush(), s.file.Close())
+	s.file = nil
+	return err
+}
+
+// Read all events from a trace file
+func Read(r io.Reader) ([]Event, error) {
+	var ret []Event
+	dec := json.NewDecoder(r)
+	for {
+		var ev Event
+		err := dec.Decode(&ev)
+		if errors.Is(err, io.EOF) {
+			return ret, nil
+		} else if err != nil {
+			return ret, err
+		}
+		ret = append(ret, ev)
+	}
+}
@@ -0,0 +1,104 @@
+/*
+* gossip
+* Copyright (C) 2024 Fabio Gaiba and Lukas Heindl
+*
+* This program is free software: you can redistribute it and/or modify
+* it under the terms of the GNU General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* This program is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+* GNU General Public License for more details.
+*
+* You should have received a copy of the GNU General Public License
+* along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package trace_test
+
+import (
+	"gossip/internal/trace"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trace.jsonl")
+	s, err := trace.Open(path, "a:1")
+	if err != nil {
+		t.Fatalf("opening the sink failed: %v", err)
+	}
+	s.Record(trace.Event{Kind: trace.Announced, MessageId: 1, GossipType: 42, TTL: 3})
+	s.Record(trace.Event{Kind: trace.Forwarded, MessageId: 1, GossipType: 42, TTL: 3, Peer: "b:1", Local: "a:2"})
+	if err := s.Close(); err != nil {
+		t.Fatalf("closing the sink failed: %v", err)
+	}
+
+	// nil sinks are allowed
+	var nilSink *trace.Sink
+	nilSink.Record(trace.Event{})
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	evs, err := trace.Read(f)
+	if err != nil {
+		t.Fatalf("reading the trace failed: %v", err)
+	}
+	if len(evs) != 2 || evs[0].Node != "a:1" || evs[0].Time.IsZero() || evs[1].Peer != "b:1" || evs[1].Local != "a:2" {
+		t.Fatalf("unexpected events: %+v", evs)
+	}
+}
+
+func TestBuild(t *testing.T) {
+	start := time.Now()
+	at := func(ms int) time.Time {
+		return start.Add(time.Duration(ms) * time.Millisecond)
+	}
+	// a announces, forwards to b and c; b forwards to c (duplicate) and d
+	// (whose trace is missing the sender) while e drops it
+	events := []trace.Event{
+		{Time: at(0), Node: "a", Kind: trace.Announced, MessageId: 7},
+		{Time: at(1), Node: "a", Kind: trace.Forwarded, MessageId: 7, Local: "a:10", Peer: "b"},
+		{Time: at(1), Node: "a", Kind: trace.Forwarded, MessageId: 7, Local: "a:11", Peer: "c"},
+		{Time: at(2), Node: "b", Kind: trace.Received, MessageId: 7, Peer: "a:10", Local: "b"},
+		{Time: at(3), Node: "c", Kind: trace.Received, MessageId: 7, Peer: "a:11", Local: "c"},
+		{Time: at(4), Node: "b", Kind: trace.Validated, MessageId: 7},
+		{Time: at(5), Node: "b", Kind: trace.Forwarded, MessageId: 7, Local: "b:20", Peer: "c"},
+		{Time: at(6), Node: "c", Kind: trace.Duplicate, MessageId: 7, Peer: "b:20", Local: "c"},
+		{Time: at(7), Node: "d", Kind: trace.Received, MessageId: 7, Peer: "x:1", Local: "d"},
+		{Time: at(8), Node: "e", Kind: trace.Dropped, MessageId: 7, Peer: "b:21", Local: "e"},
+		{Time: at(0), Node: "b", Kind: trace.Announced, MessageId: 8},
+	}
+
+	ps := trace.Build(events)
+	if len(ps) != 2 || ps[0].MessageId != 7 || ps[1].MessageId != 8 {
+		t.Fatalf("expected the propagations of messages 7 and 8, got %+v", ps)
+	}
+	p := ps[0]
+	if len(p.Roots) != 1 || p.Roots[0].Node != "a" {
+		t.Fatalf("a should be the only root: %+v", p.Roots)
+	}
+	a := p.Roots[0]
+	if len(a.Children) != 2 || a.Children[0].Node != "b" || a.Children[1].Node != "c" || a.Forwarded != 2 {
+		t.Fatalf("b and c should have received the message from a: %+v", a.Children)
+	}
+	if b := a.Children[0]; b.Validation != trace.Validated || len(b.Children) != 0 {
+		t.Fatalf("b should be validated and have no children: %+v", b)
+	}
+	if c := a.Children[1]; c.Duplicates != 1 || c.Validation != "" {
+		t.Fatalf("c should have received a duplicate and not be validated: %+v", c)
+	}
+	if len(p.Orphans) != 1 || p.Orphans[0].Node != "d" {
+		t.Fatalf("d should be an orphan: %+v", p.Orphans)
+	}
+	if len(p.Dropped) != 1 || p.Dropped[0].Node != "e" {
+		t.Fatalf("e should have dropped the message: %+v", p.Dropped)
+	}
+}
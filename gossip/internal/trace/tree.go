@@ -0,0 +1,150 @@
+/*
+* gossip
+* Copyright (C) 2024 Fabio Gaiba and Lukas Heindl
+*
+* This program is free software: you can redistribute it and/or modify
+* it under the terms of the GNU General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* This program is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+* GNU General Public License for more details.
+*
+* You should have received a copy of the GNU General Public License
+* along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package trace
+
+import (
+	"cmp"
+	"gossip/common"
+	"slices"
+	"time"
+)
+
+// A node in the propagation tree of a message
+type Node struct {
+	Node string
+	// when the node got to know the message
+	Time time.Time
+	// Announced or Received
+	Kind Kind
+	// TTL of the message when it arrived
+	TTL uint8
+	// Validated, Invalid or empty if the validation is missing
+	Validation Kind
+	// how often the message was forwarded to peers
+	Forwarded int
+	// how often the message was received again
+	Duplicates int
+	// nodes which got to know the message from this node
+	Children []*Node
+}
+
+// Propagation of a single message
+type Propagation struct {
+	MessageId  uint16
+	GossipType common.GossipType
+	// nodes which announced the message
+	Roots []*Node
+	// nodes which received the message from a peer whose trace is missing
+	Orphans []*Node
+	// messages dropped by the receiving node
+	Dropped []Event
+	// all nodes the message reached
+	Nodes map[string]*Node
+}
+
+// Build the propagation tree of each message from the (merged) events of
+// multiple nodes. The result is sorted by the message id.
+//
+// A received event is linked to the forwarded event of the sender by the
+// addresses of the connection: the peer of the one is the local address of
+// the other.
+func Build(events []Event) []*Propagation {
+	events = slices.Clone(events)
+	slices.SortStableFunc(events, func(a, b Event) int {
+		return a.Time.Compare(b.Time)
+	})
+
+	byMsg := make(map[uint16][]Event)
+	for _, ev := range events {
+		byMsg[ev.MessageId] = append(byMsg[ev.MessageId], ev)
+	}
+
+	ret := make([]*Propagation, 0, len(byMsg))
+	for id, evs := range byMsg {
+		ret = append(ret, build(id, evs))
+	}
+	slices.SortFunc(ret, func(a, b *Propagation) int {
+		return cmp.Compare(a.MessageId, b.MessageId)
+	})
+	return ret
+}
+
+// build the propagation of a single message, events must be sorted by time
+func build(id uint16, events []Event) *Propagation {
+	p := &Propagation{MessageId: id, Nodes: make(map[string]*Node)}
+	// the node which sent on a connection, by (local, peer) of the sender
+	senders := make(map[[2]string]string)
+	// the connection each node received the message on, by node
+	receivedOn := make(map[string][2]string)
+
+	for _, ev := range events {
+		p.GossipType = ev.GossipType
+		n := p.Nodes[ev.Node]
+		switch ev.Kind {
+		case Announced, Received:
+			if n != nil {
+				continue
+			}
+			n = &Node{Node: ev.Node, Time: ev.Time, Kind: ev.Kind, TTL: ev.TTL}
+			p.Nodes[ev.Node] = n
+			if ev.Kind == Announced {
+				p.Roots = append(p.Roots, n)
+			} else {
+				receivedOn[ev.Node] = [2]string{ev.Peer, ev.Local}
+			}
+		case Duplicate:
+			if n != nil {
+				n.Duplicates++
+			}
+		case Dropped:
+			p.Dropped = append(p.Dropped, ev)
+		case Validated, Invalid:
+			if n != nil {
+				n.Validation = ev.Kind
+			}
+		case Forwarded:
+			senders[[2]string{ev.Local, ev.Peer}] = ev.Node
+			if n != nil {
+				n.Forwarded++
+			}
+		}
+	}
+
+	// link the nodes in the order they received the message
+	nodes := make([]*Node, 0, len(p.Nodes))
+	for _, n := range p.Nodes {
+		nodes = append(nodes, n)
+	}
+	slices.SortFunc(nodes, func(a, b *Node) int {
+		return cmp.Or(a.Time.Compare(b.Time), cmp.Compare(a.Node, b.Node))
+	})
+	for _, n := range nodes {
+		if n.Kind != Received {
+			continue
+		}
+		conn := receivedOn[n.Node]
+		parent, ok := p.Nodes[senders[conn]]
+		if !ok {
+			p.Orphans = append(p.Orphans, n)
+			continue
+		}
+		parent.Children = append(parent.Children, n)
+	}
+	return p
+}
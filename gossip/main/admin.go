@@ -0,0 +1,158 @@
+/*
+* gossip
+* Copyright (C) 2024 Fabio Gaiba and Lukas Heindl
+*
+* This program is free software: you can redistribute it and/or modify
+* it under the terms of the GNU General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* This program is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+* GNU General Public License for more details.
+*
+* You should have received a copy of the GNU General Public License
+* along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package gossip
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	gs "gossip/strats"
+	"log/slog"
+	"net"
+	"net/http"
+)
+
+// Body of the request to connect to a new peer
+type AddPeerRequest struct {
+	Addr string `json:"addr"`
+}
+
+// Body of the requests/responses regarding the log level
+type LogLevelMessage struct {
+	Level string `json:"level"`
+}
+
+// Serve the admin interface on addr. The peers and the cache are obtained
+// from strategy (which may be nil if the strategy doesn't support this).
+//
+// Changing requests need to carry token (Authorization: Bearer <token>). If
+// token is empty, the admin interface is only served on loopback addresses.
+//
+// Listening is done synchronously so errors are reported directly, serving is
+// done in a new goroutine. Close the returned server to stop serving.
+func (m *Main) serveAdmin(addr string, token string, strategy gs.StrategyAdmin) (*http.Server, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("listen to port for admin interface: %w", err)
+	}
+	if tcpAddr, ok := ln.Addr().(*net.TCPAddr); token == "" && (!ok || !tcpAddr.IP.IsLoopback()) {
+		ln.Close()
+		return nil, fmt.Errorf("admin interface on non-loopback address %s needs an admin_token", ln.Addr())
+	}
+
+	mux := http.NewServeMux()
+	authorized := func(h http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if token != "" && subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+token)) != 1 {
+				m.mlog.Warn("Admin: unauthorized request", "method", r.Method, "path", r.URL.Path, "remote", r.RemoteAddr)
+				http.Error(w, "missing or wrong admin token", http.StatusUnauthorized)
+				return
+			}
+			h(w, r)
+		}
+	}
+	withStrategy := func(h func(w http.ResponseWriter, r *http.Request)) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if strategy == nil {
+				http.Error(w, "strategy does not support this", http.StatusNotImplemented)
+				return
+			}
+			h(w, r)
+		}
+	}
+
+	mux.HandleFunc("GET /peers", withStrategy(func(w http.ResponseWriter, r *http.Request) {
+		peers, err := strategy.Peers()
+		writeJSON(w, peers, err)
+	}))
+	mux.HandleFunc("POST /peers", authorized(withStrategy(func(w http.ResponseWriter, r *http.Request) {
+		var req AddPeerRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Addr == "" {
+			http.Error(w, "expected {\"addr\": \"ip:port\"}", http.StatusBadRequest)
+			return
+		}
+		m.mlog.Info("Admin: adding peer", "addr", req.Addr)
+		if err := strategy.AddPeer(req.Addr); err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})))
+	mux.HandleFunc("DELETE /peers/{id}", authorized(withStrategy(func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+		m.mlog.Info("Admin: disconnecting peer", "id", id)
+		err := strategy.Disconnect(id)
+		if errors.Is(err, gs.ErrUnknownPeer) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})))
+	mux.HandleFunc("GET /cache", withStrategy(func(w http.ResponseWriter, r *http.Request) {
+		msgs, err := strategy.Cache()
+		writeJSON(w, msgs, err)
+	}))
+	mux.HandleFunc("GET /modules", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, m.typeStorage.Registrations(), nil)
+	})
+	mux.HandleFunc("GET /loglevel", func(w http.ResponseWriter, r *http.Request) {
+		if m.logLevel == nil {
+			http.Error(w, "log level cannot be adjusted", http.StatusNotImplemented)
+			return
+		}
+		writeJSON(w, LogLevelMessage{Level: m.logLevel.Level().String()}, nil)
+	})
+	mux.HandleFunc("PUT /loglevel", authorized(func(w http.ResponseWriter, r *http.Request) {
+		if m.logLevel == nil {
+			http.Error(w, "log level cannot be adjusted", http.StatusNotImplemented)
+			return
+		}
+		var req LogLevelMessage
+		var level slog.Level
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "expected {\"level\": \"<level>\"}", http.StatusBadRequest)
+			return
+		}
+		if err := level.UnmarshalText([]byte(req.Level)); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		m.mlog.Info("Admin: changing log level", "level", level)
+		m.logLevel.Set(level)
+		writeJSON(w, LogLevelMessage{Level: level.String()}, nil)
+	}))
+
+	srv := &http.Server{Handler: mux}
+	go srv.Serve(ln)
+	return srv, nil
+}
+
+// write v as json response (or an error if err != nil)
+func writeJSON(w http.ResponseWriter, v any, err error) {
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
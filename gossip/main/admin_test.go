@@ -0,0 +1,83 @@
+/*
+* gossip
+* Copyright (C) 2024 Fabio Gaiba and Lukas Heindl
+*
+* This program is free software: you can redistribute it and/or modify
+* it under the terms of the GNU General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* This program is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+* GNU General Public License for more details.
+*
+* You should have received a copy of the GNU General Public License
+* along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package gossip
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/neilotoole/slogt"
+)
+
+func TestAdminToken(test *testing.T) {
+	m := &Main{mlog: slogt.New(test)}
+
+	// without a token only loopback addresses are allowed
+	if srv, err := m.serveAdmin("0.0.0.0:0", "", nil); err == nil {
+		srv.Close()
+		test.Fatalf("admin interface was served on a non-loopback address without a token")
+	}
+
+	// pick a free port
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		test.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+	srv, err := m.serveAdmin(addr, "secret", nil)
+	if err != nil {
+		test.Fatalf("serving the admin interface failed: %v", err)
+	}
+	defer srv.Close()
+
+	request := func(method string, token string) int {
+		test.Helper()
+		req, err := http.NewRequest(method, "http://"+addr+"/loglevel", strings.NewReader(`{"level": "INFO"}`))
+		if err != nil {
+			test.Fatal(err)
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			test.Fatal(err)
+		}
+		res.Body.Close()
+		return res.StatusCode
+	}
+
+	// changing requests need the token, the log level cannot be adjusted in
+	// this test, so passing the check results in NotImplemented
+	if code := request(http.MethodPut, ""); code != http.StatusUnauthorized {
+		test.Fatalf("request without token got %d", code)
+	}
+	if code := request(http.MethodPut, "wrong"); code != http.StatusUnauthorized {
+		test.Fatalf("request with a wrong token got %d", code)
+	}
+	if code := request(http.MethodPut, "secret"); code != http.StatusNotImplemented {
+		test.Fatalf("request with the token got %d", code)
+	}
+	if code := request(http.MethodGet, ""); code != http.StatusNotImplemented {
+		test.Fatalf("reading request without token got %d", code)
+	}
+}
@@ -0,0 +1,72 @@
+/*
+* gossip
+* Copyright (C) 2024 Fabio Gaiba and Lukas Heindl
+*
+* This program is free software: you can redistribute it and/or modify
+* it under the terms of the GNU General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* This program is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+* GNU General Public License for more details.
+*
+* You should have received a copy of the GNU General Public License
+* along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package gossip
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alexflint/go-arg"
+	"github.com/neilotoole/slogt"
+	"gopkg.in/ini.v1"
+)
+
+func TestLoadDurations(test *testing.T) {
+	cfg := filepath.Join(test.TempDir(), "gossip.ini")
+	// plain numbers are seconds, like in older config files
+	ini := "[gossip]\ngtimer = 2\nforward_window = 20ms\nping_interval = 5\n"
+	if err := os.WriteFile(cfg, []byte(ini), 0o644); err != nil {
+		test.Fatalf("%v", err)
+	}
+
+	var cargs UserArgs
+	p, err := arg.NewParser(arg.Config{}, &cargs)
+	if err != nil {
+		test.Fatalf("creating the parser failed: %v", err)
+	}
+	if err := p.Parse([]string{"-c", cfg, "--idle_timeout", "60"}); err != nil {
+		test.Fatalf("parsing the cli arguments failed: %v", err)
+	}
+	a, err := loadArgs(cargs, slogt.New(test))
+	if err != nil {
+		test.Fatalf("loading the arguments failed: %v", err)
+	}
+	if a.GossipTimer != 2*time.Second || a.Forward_window != 20*time.Millisecond || a.Ping_interval != 5*time.Second || a.Idle_timeout != time.Minute {
+		test.Fatalf("durations were parsed wrongly: gtimer %v, forward_window %v, ping_interval %v, idle_timeout %v", a.GossipTimer, a.Forward_window, a.Ping_interval, a.Idle_timeout)
+	}
+
+	if err := os.WriteFile(cfg, []byte("[gossip]\ngtimer = soon\n"), 0o644); err != nil {
+		test.Fatalf("%v", err)
+	}
+	if _, err := loadArgs(cargs, slogt.New(test)); err == nil {
+		test.Fatalf("an invalid duration should be refused")
+	}
+}
+
+func TestUnknownKeys(test *testing.T) {
+	cfg, err := ini.Load([]byte("[gossip]\ndegree = 3\ngtimr = 1\n"))
+	if err != nil {
+		test.Fatalf("loading the config failed: %v", err)
+	}
+	if keys := unknownKeys(cfg.Section("gossip")); len(keys) != 1 || keys[0] != "gtimr" {
+		test.Fatalf("unknown keys are wrong: %v", keys)
+	}
+}
@@ -21,6 +21,7 @@ package gossip
 import (
 	"errors"
 	"gossip/common"
+	"slices"
 	"sync"
 )
 
@@ -73,6 +74,39 @@ func (nm *notifyMap) AddChannelToType(gossip_type common.GossipType, new_channel
 	return nil
 }
 
+// Returns the ids of the connections registered for each type
+func (nm *notifyMap) Registrations() map[common.GossipType][]common.ConnectionId {
+	nm.RLock()
+	defer nm.RUnlock()
+	ret := make(map[common.GossipType][]common.ConnectionId, len(nm.data))
+	for k, l := range nm.data {
+		if len(l) == 0 {
+			continue
+		}
+		ids := make([]common.ConnectionId, 0, len(l))
+		for _, c := range l {
+			ids = append(ids, c.Id)
+		}
+		ret[k] = ids
+	}
+	return ret
+}
+
+// Returns the types for which at least one connection is registered (sorted
+// ascending)
+func (nm *notifyMap) Types() []common.GossipType {
+	nm.RLock()
+	defer nm.RUnlock()
+	ret := make([]common.GossipType, 0, len(nm.data))
+	for k, l := range nm.data {
+		if len(l) > 0 {
+			ret = append(ret, k)
+		}
+	}
+	slices.Sort(ret)
+	return ret
+}
+
 // remove the connection with id == unreg from the notifyMap
 //
 // returns a pointer to the removed connection (or nil if no connection with id unreg was found)
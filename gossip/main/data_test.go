@@ -21,6 +21,7 @@ package gossip
 import (
 	"fmt"
 	"gossip/common"
+	"slices"
 	"sync"
 	"testing"
 )
@@ -131,3 +132,27 @@ func TestRemoveChannel(t *testing.T) {
 		}
 	}
 }
+
+func TestRegistrations(t *testing.T) {
+	store := NewNotifyMap()
+	mod := common.RegisteredModule{MainToVert: make(chan common.ToVert)}
+
+	store.AddChannelToType(42, &common.Conn[common.RegisteredModule]{Data: mod, Id: "a"})
+	store.AddChannelToType(42, &common.Conn[common.RegisteredModule]{Data: mod, Id: "b"})
+	store.AddChannelToType(7, &common.Conn[common.RegisteredModule]{Data: mod, Id: "a"})
+	if types := store.Types(); !slices.Equal(types, []common.GossipType{7, 42}) {
+		t.Fatalf("wrong types listed: %v", types)
+	}
+	store.RemoveChannel("a")
+	if types := store.Types(); !slices.Equal(types, []common.GossipType{42}) {
+		t.Fatalf("wrong types listed after removing a module: %v", types)
+	}
+
+	reg := store.Registrations()
+	if len(reg) != 1 {
+		t.Fatalf("types without modules should not be listed: %v", reg)
+	}
+	if len(reg[42]) != 1 || reg[42][0] != "b" {
+		t.Fatalf("wrong modules listed for type 42: %v", reg[42])
+	}
+}
@@ -21,10 +21,16 @@ package gossip
 import (
 	"context"
 	"errors"
+	"fmt"
 	"gossip/common"
 	"gossip/internal/args"
+	"gossip/internal/logging"
+	"gossip/internal/metrics"
 	gs "gossip/strats"
 	verticalapi "gossip/verticalAPI"
+	"io"
+	"reflect"
+	"strconv"
 	"sync"
 
 	"log/slog"
@@ -38,23 +44,85 @@ import (
 
 // Arguments read using go-arg https://github.com/alexflint/go-arg. The annotation instruct the library on
 // the type of comment and optionally the help message.
+//
+// Each option can also be set via the environment variable GOSSIP_<ini key>
+// (in upper case), see [loadArgs] for the precedence.
 type UserArgs struct {
-	Degree      *uint    `ini:"degree" arg:"-d,--degree" help:"Gossip parameter degree: Number of peers the current peer has to exchange information with"`
-	Cache_size  *uint    `ini:"cache_size" arg:"--cache" help:"Gossip parameter cache_size: Maximum number of data items to be held as part of the peer’s knowledge base. Older items will be removed to ensure space for newer items if the peer’s knowledge base exceeds this limit"`
-	GossipTimer *uint    `ini:"gtimer" arg:"-t,--gtimer" help:"How often the gossip strategy should perform a strategy cycle, if applicable"`
-	Hz_addr     *string  `ini:"p2p address" arg:"-H,--haddr" help:"Address to listen for incoming peer connections, ip:port"`
-	Vert_addr   *string  `ini:"api address" arg:"-V,--vaddr" help:"Address to listen for incoming peer connections, ip:port"`
-	Peer_addrs  []string `ini:"hconns" delim:" " arg:"positional" help:"List of horizontal peers to connect to, [ip]:port"`
-	ConfigFile  *string  `arg:"-c,--config_file" help:"Path to the configuration file (cli arguments always take predecence)"`
-	// Strategy string ``
+	Degree       *uint        `ini:"degree" arg:"-d,--degree,env:GOSSIP_DEGREE" help:"Gossip parameter degree: Number of peers the current peer has to exchange information with"`
+	Cache_size   *uint        `ini:"cache_size" arg:"--cache,env:GOSSIP_CACHE_SIZE" help:"Gossip parameter cache_size: Maximum number of data items to be held as part of the peer’s knowledge base. Older items will be removed to ensure space for newer items if the peer’s knowledge base exceeds this limit"`
+	GossipTimer  *durationArg `ini:"gtimer" arg:"-t,--gtimer,env:GOSSIP_GTIMER" help:"How often the gossip strategy should perform a strategy cycle, if applicable (e.g. 1s or 500ms, plain numbers are seconds)"`
+	Min_peers    *uint        `ini:"min_peers" arg:"--min_peers,env:GOSSIP_MIN_PEERS" help:"Minimum amount of peers, below the node connects to known peers on its own"`
+	Target_peers *uint        `ini:"target_peers" arg:"--target_peers,env:GOSSIP_TARGET_PEERS" help:"Amount of peers the node tries to reach when looking for new peers"`
+	Max_peers    *uint        `ini:"max_peers" arg:"--max_peers,env:GOSSIP_MAX_PEERS" help:"Maximum amount of peers, further incoming connections are refused or replace others"`
+	Hz_addr      *string      `ini:"p2p_address" arg:"-H,--haddr,env:GOSSIP_P2P_ADDRESS" help:"Addresses to listen for incoming peer connections, ip:port separated by commas"`
+	Hz_advertise *string      `ini:"p2p_advertise_address" arg:"--advertise,env:GOSSIP_P2P_ADVERTISE_ADDRESS" help:"Address under which peers can reach this node, ip:port (empty = first p2p address)"`
+	Vert_addr    *string      `ini:"api_address" arg:"-V,--vaddr,env:GOSSIP_API_ADDRESS" help:"Addresses to listen for incoming module connections, ip:port separated by commas"`
+	Peer_addrs   []string     `ini:"hconns" delim:" " arg:"positional,env:GOSSIP_HCONNS" help:"List of horizontal peers to connect to, [ip]:port"`
+	Bootstrapper *string      `ini:"bootstrapper" arg:"--bootstrapper,env:GOSSIP_BOOTSTRAPPER" help:"Peer used to join the network (connected to in addition to the peers), ip:port"`
+	Strategy     *string      `ini:"strategy" arg:"--strategy,env:GOSSIP_STRATEGY" help:"Name of the gossip strategy (dummy, plumtree, gossipsub, rumor)"`
+	ConfigFile   *string      `arg:"-c,--config_file,env:GOSSIP_CONFIG_FILE" help:"Path to the configuration file (cli arguments and environment variables always take predecence)"`
+	// forwarding
+	Eager_forward  *bool        `ini:"eager_forward" arg:"--eager_forward,env:GOSSIP_EAGER_FORWARD" help:"Forward validated messages without waiting for the next strategy cycle"`
+	Forward_window *durationArg `ini:"forward_window" arg:"--forward_window,env:GOSSIP_FORWARD_WINDOW" help:"How long validated messages are collected before they are forwarded in one batch with eager_forward (e.g. 20ms, 0 = immediately)"`
+	// lazy push
+	Lazy_push_types *string `ini:"lazy_push_types" arg:"--lazy_push_types,env:GOSSIP_LAZY_PUSH_TYPES" help:"Gossip types whose messages are announced instead of pushed, separated by commas"`
+	Lazy_push_size  *uint   `ini:"lazy_push_size" arg:"--lazy_push_size,env:GOSSIP_LAZY_PUSH_SIZE" help:"Payload size (in bytes) from which messages are announced instead of pushed (0 = disabled)"`
+	// rumor mongering
+	Rumor_k *uint `ini:"rumor_k" arg:"--rumor_k,env:GOSSIP_RUMOR_K" help:"The rumor strategy stops spreading a message with probability 1/rumor_k when a peer already knew it"`
+	// peer selection
+	Peer_selection       *string `ini:"peer_selection" arg:"--peer_selection,env:GOSSIP_PEER_SELECTION" help:"Policy choosing the peers messages are forwarded to, for all strategies but plumtree (random, reputation, rtt, lru, bandwidth)"`
+	Peer_selection_types *string `ini:"peer_selection_types" arg:"--peer_selection_types,env:GOSSIP_PEER_SELECTION_TYPES" help:"Policies for single gossip types, type=policy separated by commas (e.g. 1=rtt,2=lru)"`
+	// membership
+	Passive_view_size *uint `ini:"passive_view_size" arg:"--passive_view_size,env:GOSSIP_PASSIVE_VIEW_SIZE" help:"Maximum amount of remembered addresses of peers used to replace failed connections"`
+	Shuffle_interval  *uint `ini:"shuffle_interval" arg:"--shuffle_interval,env:GOSSIP_SHUFFLE_INTERVAL" help:"How often known peers are exchanged with a random peer (in seconds, 0 = never)"`
+	Shuffle_length    *uint `ini:"shuffle_length" arg:"--shuffle_length,env:GOSSIP_SHUFFLE_LENGTH" help:"Amount of addresses sent when exchanging known peers"`
+	// PoW
+	Pow_timeout      *uint `ini:"pow_timeout" arg:"--pow_timeout,env:GOSSIP_POW_TIMEOUT" help:"How long a peer has time to provide a PoW (in seconds)"`
+	Pow_request_time *uint `ini:"pow_request_time" arg:"--pow_request_time,env:GOSSIP_POW_REQUEST_TIME" help:"How often peers are asked to renew their PoW (in seconds)"`
+	// keepalive
+	Ping_interval *durationArg `ini:"ping_interval" arg:"--ping_interval,env:GOSSIP_PING_INTERVAL" help:"How often peers are pinged to check the connection and measure the round trip time (e.g. 10s, 0 = never)"`
+	Idle_timeout  *durationArg `ini:"idle_timeout" arg:"--idle_timeout,env:GOSSIP_IDLE_TIMEOUT" help:"Connections on which nothing was received for this long are closed (e.g. 30s, 0 = never)"`
+	// queues
+	Hz_queue_size    *uint `ini:"hz_queue_size" arg:"--hz_queue_size,env:GOSSIP_HZ_QUEUE_SIZE" help:"Amount of messages from peers which are buffered until the strategy processes them"`
+	Strat_queue_size *uint `ini:"strat_queue_size" arg:"--strat_queue_size,env:GOSSIP_STRAT_QUEUE_SIZE" help:"Amount of messages between the modules and the strategy which are buffered until they are processed"`
+	// rate limiting of push messages
+	In_peer_rate   *float64 `ini:"in_peer_rate" arg:"--in_peer_rate,env:GOSSIP_IN_PEER_RATE" help:"Maximum amount of incoming push messages per second per peer (0 = unlimited)"`
+	In_peer_burst  *uint    `ini:"in_peer_burst" arg:"--in_peer_burst,env:GOSSIP_IN_PEER_BURST" help:"Amount of incoming push messages per peer which may arrive at once (0 = same as rate)"`
+	In_type_rate   *float64 `ini:"in_type_rate" arg:"--in_type_rate,env:GOSSIP_IN_TYPE_RATE" help:"Maximum amount of incoming push messages per second per gossip type (0 = unlimited)"`
+	In_type_burst  *uint    `ini:"in_type_burst" arg:"--in_type_burst,env:GOSSIP_IN_TYPE_BURST" help:"Amount of incoming push messages per gossip type which may arrive at once (0 = same as rate)"`
+	Out_peer_rate  *float64 `ini:"out_peer_rate" arg:"--out_peer_rate,env:GOSSIP_OUT_PEER_RATE" help:"Maximum amount of forwarded push messages per second per peer (0 = unlimited)"`
+	Out_peer_burst *uint    `ini:"out_peer_burst" arg:"--out_peer_burst,env:GOSSIP_OUT_PEER_BURST" help:"Amount of forwarded push messages per peer which may be sent at once (0 = same as rate)"`
+	Out_type_rate  *float64 `ini:"out_type_rate" arg:"--out_type_rate,env:GOSSIP_OUT_TYPE_RATE" help:"Maximum amount of forwarded messages per second per gossip type (0 = unlimited)"`
+	Out_type_burst *uint    `ini:"out_type_burst" arg:"--out_type_burst,env:GOSSIP_OUT_TYPE_BURST" help:"Amount of forwarded messages per gossip type which may be sent at once (0 = same as rate)"`
+	// egress bandwidth
+	Egress_rate      *uint `ini:"egress_rate" arg:"--egress_rate,env:GOSSIP_EGRESS_RATE" help:"Maximum amount of bytes per second sent to all peers together (0 = unlimited)"`
+	Egress_peer_rate *uint `ini:"egress_peer_rate" arg:"--egress_peer_rate,env:GOSSIP_EGRESS_PEER_RATE" help:"Maximum amount of bytes per second sent to a single peer (0 = unlimited)"`
+	// reputation of peers
+	Ban_score *int    `ini:"ban_score" arg:"--ban_score,env:GOSSIP_BAN_SCORE" help:"Peers whose reputation score (-100 to 100) drops to this value are banned"`
+	Ban_time  *uint   `ini:"ban_time" arg:"--ban_time,env:GOSSIP_BAN_TIME" help:"How long a ban of a peer lasts (in seconds)"`
+	Ban_file  *string `ini:"ban_file" arg:"--ban_file,env:GOSSIP_BAN_FILE" help:"File in which the ban list is persisted across restarts (empty = not persisted)"`
+	// persisting messages
+	Store_file      *string `ini:"store_file" arg:"--store_file,env:GOSSIP_STORE_FILE" help:"File in which seen messages are stored to survive restarts (empty = not stored)"`
+	Store_retention *uint   `ini:"store_retention" arg:"--store_retention,env:GOSSIP_STORE_RETENTION" help:"How long messages are kept in the message store (in seconds)"`
+	// observability
+	Metrics_addr      *string `ini:"metrics_address" arg:"--metrics_addr,env:GOSSIP_METRICS_ADDRESS" help:"Address to serve the metrics on (http://<addr>/metrics), ip:port (empty = disabled)"`
+	Admin_addr        *string `ini:"admin_address" arg:"--admin_addr,env:GOSSIP_ADMIN_ADDRESS" help:"Address to serve the admin interface on (used by gossipctl), ip:port (empty = disabled)"`
+	Admin_token       *string `ini:"admin_token" arg:"--admin_token,env:GOSSIP_ADMIN_TOKEN" help:"Token required for changing requests to the admin interface (empty = only a loopback admin_address is allowed)"`
+	Log_level         *string `ini:"log_level" arg:"--log_level,env:GOSSIP_LOG_LEVEL" help:"Log level (DEBUG, INFO, WARN or ERROR)"`
+	Log_format        *string `ini:"log_format" arg:"--log_format,env:GOSSIP_LOG_FORMAT" help:"Format of the log messages (text or json)"`
+	Log_file          *string `ini:"log_file" arg:"--log_file,env:GOSSIP_LOG_FILE" help:"File the log is written to (empty = stdout)"`
+	Log_max_size      *uint   `ini:"log_max_size" arg:"--log_max_size,env:GOSSIP_LOG_MAX_SIZE" help:"Size (in MB) at which the log file is rotated (0 = never)"`
+	Log_max_files     *uint   `ini:"log_max_files" arg:"--log_max_files,env:GOSSIP_LOG_MAX_FILES" help:"Amount of rotated log files which are kept"`
+	Log_module_levels *string `ini:"log_module_levels" arg:"--log_module_levels,env:GOSSIP_LOG_MODULE_LEVELS" help:"Log levels of single modules, e.g. strategy=INFO,horzAPI=WARN (modules: main, strategy, horzAPI, vertAPI)"`
+	Log_test_events   *bool   `ini:"log_test_events" arg:"--log_test_events,env:GOSSIP_LOG_TEST_EVENTS" help:"Log the events used for testing/benchmarking"`
+	Trace_file        *string `ini:"trace_file" arg:"--trace_file,env:GOSSIP_TRACE_FILE" help:"File the trace of the message propagation is written to (empty = disabled), see gossiptrace"`
+	// shutdown
+	Drain_timeout *uint `ini:"drain_timeout" arg:"--drain_timeout,env:GOSSIP_DRAIN_TIMEOUT" help:"How long pending messages are forwarded and validations awaited on shutdown (in seconds, 0 = no draining)"`
 }
 
 // uses the values set in arg as defaults and overwrites the values which are
 // set (!= nil) in uarg
 func (uarg *UserArgs) Merge(arg args.Args) args.Args {
-	if uarg.Degree != nil {
-		arg.Degree = *uarg.Degree
-	}
 	if uarg.Degree != nil {
 		arg.Degree = *uarg.Degree
 	}
@@ -62,28 +130,185 @@ func (uarg *UserArgs) Merge(arg args.Args) args.Args {
 		arg.Cache_size = *uarg.Cache_size
 	}
 	if uarg.GossipTimer != nil {
-		arg.GossipTimer = *uarg.GossipTimer
+		arg.GossipTimer = time.Duration(*uarg.GossipTimer)
+	}
+	if uarg.Min_peers != nil {
+		arg.Min_peers = *uarg.Min_peers
+	}
+	if uarg.Target_peers != nil {
+		arg.Target_peers = *uarg.Target_peers
+	}
+	if uarg.Max_peers != nil {
+		arg.Max_peers = *uarg.Max_peers
+	}
+	if uarg.Passive_view_size != nil {
+		arg.Passive_view_size = *uarg.Passive_view_size
+	}
+	if uarg.Shuffle_interval != nil {
+		arg.Shuffle_interval = *uarg.Shuffle_interval
+	}
+	if uarg.Shuffle_length != nil {
+		arg.Shuffle_length = *uarg.Shuffle_length
 	}
 	if uarg.Hz_addr != nil {
 		arg.Hz_addr = *uarg.Hz_addr
 	}
+	if uarg.Hz_advertise != nil {
+		arg.Hz_advertise = *uarg.Hz_advertise
+	}
 	if uarg.Vert_addr != nil {
 		arg.Vert_addr = *uarg.Vert_addr
 	}
 	if uarg.Peer_addrs != nil {
 		arg.Peer_addrs = uarg.Peer_addrs
 	}
+	if uarg.Bootstrapper != nil {
+		arg.Bootstrapper = *uarg.Bootstrapper
+	}
+	if uarg.Strategy != nil {
+		arg.Strategy = *uarg.Strategy
+	}
+	if uarg.Eager_forward != nil {
+		arg.Eager_forward = *uarg.Eager_forward
+	}
+	if uarg.Forward_window != nil {
+		arg.Forward_window = time.Duration(*uarg.Forward_window)
+	}
+	if uarg.Lazy_push_types != nil {
+		arg.Lazy_push_types = *uarg.Lazy_push_types
+	}
+	if uarg.Lazy_push_size != nil {
+		arg.Lazy_push_size = *uarg.Lazy_push_size
+	}
+	if uarg.Rumor_k != nil {
+		arg.Rumor_k = *uarg.Rumor_k
+	}
+	if uarg.Peer_selection != nil {
+		arg.Peer_selection = *uarg.Peer_selection
+	}
+	if uarg.Peer_selection_types != nil {
+		arg.Peer_selection_types = *uarg.Peer_selection_types
+	}
+	if uarg.Pow_timeout != nil {
+		arg.Pow_timeout = *uarg.Pow_timeout
+	}
+	if uarg.Pow_request_time != nil {
+		arg.Pow_request_time = *uarg.Pow_request_time
+	}
+	if uarg.Ping_interval != nil {
+		arg.Ping_interval = time.Duration(*uarg.Ping_interval)
+	}
+	if uarg.Idle_timeout != nil {
+		arg.Idle_timeout = time.Duration(*uarg.Idle_timeout)
+	}
+	if uarg.Hz_queue_size != nil {
+		arg.Hz_queue_size = *uarg.Hz_queue_size
+	}
+	if uarg.Strat_queue_size != nil {
+		arg.Strat_queue_size = *uarg.Strat_queue_size
+	}
+	if uarg.In_peer_rate != nil {
+		arg.In_peer_rate = *uarg.In_peer_rate
+	}
+	if uarg.In_peer_burst != nil {
+		arg.In_peer_burst = *uarg.In_peer_burst
+	}
+	if uarg.In_type_rate != nil {
+		arg.In_type_rate = *uarg.In_type_rate
+	}
+	if uarg.In_type_burst != nil {
+		arg.In_type_burst = *uarg.In_type_burst
+	}
+	if uarg.Out_peer_rate != nil {
+		arg.Out_peer_rate = *uarg.Out_peer_rate
+	}
+	if uarg.Out_peer_burst != nil {
+		arg.Out_peer_burst = *uarg.Out_peer_burst
+	}
+	if uarg.Out_type_rate != nil {
+		arg.Out_type_rate = *uarg.Out_type_rate
+	}
+	if uarg.Out_type_burst != nil {
+		arg.Out_type_burst = *uarg.Out_type_burst
+	}
+	if uarg.Egress_rate != nil {
+		arg.Egress_rate = *uarg.Egress_rate
+	}
+	if uarg.Egress_peer_rate != nil {
+		arg.Egress_peer_rate = *uarg.Egress_peer_rate
+	}
+	if uarg.Ban_score != nil {
+		arg.Ban_score = *uarg.Ban_score
+	}
+	if uarg.Ban_time != nil {
+		arg.Ban_time = *uarg.Ban_time
+	}
+	if uarg.Ban_file != nil {
+		arg.Ban_file = *uarg.Ban_file
+	}
+	if uarg.Store_file != nil {
+		arg.Store_file = *uarg.Store_file
+	}
+	if uarg.Store_retention != nil {
+		arg.Store_retention = *uarg.Store_retention
+	}
+	if uarg.Metrics_addr != nil {
+		arg.Metrics_addr = *uarg.Metrics_addr
+	}
+	if uarg.Admin_addr != nil {
+		arg.Admin_addr = *uarg.Admin_addr
+	}
+	if uarg.Admin_token != nil {
+		arg.Admin_token = *uarg.Admin_token
+	}
+	if uarg.Log_level != nil {
+		arg.Log_level = *uarg.Log_level
+	}
+	if uarg.Log_format != nil {
+		arg.Log_format = *uarg.Log_format
+	}
+	if uarg.Log_file != nil {
+		arg.Log_file = *uarg.Log_file
+	}
+	if uarg.Log_max_size != nil {
+		arg.Log_max_size = *uarg.Log_max_size
+	}
+	if uarg.Log_max_files != nil {
+		arg.Log_max_files = *uarg.Log_max_files
+	}
+	if uarg.Log_module_levels != nil {
+		arg.Log_module_levels = *uarg.Log_module_levels
+	}
+	if uarg.Log_test_events != nil {
+		arg.Log_test_events = *uarg.Log_test_events
+	}
+	if uarg.Trace_file != nil {
+		arg.Trace_file = *uarg.Trace_file
+	}
+	if uarg.Drain_timeout != nil {
+		arg.Drain_timeout = *uarg.Drain_timeout
+	}
 
 	return arg
 }
 
-// initialize a [slog.Logger]
-func logInit(identifier any) *slog.Logger {
-	return slog.New(tint.NewHandler(os.Stdout, &tint.Options{
-		Level:      slog.LevelDebug,
-		TimeFormat: time.RFC3339,
-		NoColor:    false,
-	})).With("id", identifier)
+// initialize a [slog.Logger] writing to w in the given format (text or json).
+// The levels (of each module) can be adjusted at runtime via levels.
+func logInit(identifier any, format string, w io.Writer, levels *logging.Levels) *slog.Logger {
+	var handler slog.Handler
+	// filtering is done by the module handler
+	minLevel := common.LevelTest
+	if format == "json" {
+		handler = slog.NewJSONHandler(w, &slog.HandlerOptions{Level: minLevel})
+	} else {
+		handler = tint.NewHandler(w, &tint.Options{
+			Level:      minLevel,
+			TimeFormat: time.RFC3339,
+			// no escape sequences in files
+			NoColor: w != os.Stdout,
+		})
+	}
+	return slog.New(logging.NewModuleHandler(handler, levels)).With("id", identifier)
 }
 
 // Main is the struct which connects the verticalAPI to the actual gossip
@@ -91,14 +316,38 @@ func logInit(identifier any) *slog.Logger {
 //
 // Use either [NewMainWithArgs] or [NewMain] to instanciate
 type Main struct {
-	log              *slog.Logger
-	mlog             *slog.Logger
+	log  *slog.Logger
+	mlog *slog.Logger
+	// only changed on the goroutine of [Main.Run] with argsMutex locked for
+	// writing, other goroutines have to lock argsMutex for reading
 	args             args.Args
+	argsMutex        sync.RWMutex
 	typeStorage      notifyMap
 	vertToMain       chan common.FromVert
 	strategyChannels gs.StrategyChannels
+	ctx              context.Context
 	cancel           context.CancelFunc
 	wg               sync.WaitGroup
+	// metrics of all components (nil if disabled)
+	metrics *metrics.Registry
+	// level of the logger (nil if the logger was passed from outside)
+	logLevel *slog.LevelVar
+	// levels of the modules (nil if the logger was passed from outside)
+	logLevels *logging.Levels
+	// log file (nil if the log is written to stdout)
+	logFile io.Closer
+	// requests to drain the node before shutting down
+	drain chan drainRequest
+	// reads the arguments again (nil if the arguments were passed from outside)
+	loadArgs func() (args.Args, error)
+	// requests to apply reloaded arguments
+	reload chan reloadRequest
+}
+
+// Request to drain the node, the result is sent on done
+type drainRequest struct {
+	ctx  context.Context
+	done chan<- error
 }
 
 // Used to instanciate [Main] with a certain set of arguments (does not attempt
@@ -123,47 +372,173 @@ func NewMainWithArgs(args args.Args, log *slog.Logger) *Main {
 		"Peers addresses", m.args.Peer_addrs,
 	)
 
+	if m.args.Metrics_addr != "" {
+		m.metrics = metrics.NewRegistry()
+	}
+
+	m.ctx, m.cancel = context.WithCancel(context.Background())
 	m.vertToMain = make(chan common.FromVert)
+	m.drain = make(chan drainRequest)
+	m.reload = make(chan reloadRequest)
 
 	m.strategyChannels = gs.StrategyChannels{
-		FromStrat: make(chan common.FromStrat),
-		ToStrat:   make(chan common.ToStrat),
+		FromStrat: make(chan common.FromStrat, args.Strat_queue_size),
+		ToStrat:   make(chan common.ToStrat, args.Strat_queue_size),
 	}
 
 	return m
 }
 
 // Used to instanciate [Main] without special arguments. Will start parsing the
-// cli arguments and depending on the arguments continue with parsing arguments
-// from an ini file.
-func NewMain() *Main {
-	// obtain the arguments with the default values set
-	args := args.NewFromDefaults()
-
+// cli arguments (and environment variables) and depending on the arguments
+// continue with parsing arguments from an ini file.
+//
+// Returns an error if the config file cannot be read or the arguments are
+// invalid.
+func NewMain() (*Main, error) {
 	// read the cli arguments
 	var cargs UserArgs
 	arg.MustParse(&cargs)
 
+	// the logger is only set up once the arguments are known
+	log := slog.Default()
+	loadArgs := func() (args.Args, error) {
+		return loadArgs(cargs, log)
+	}
+	args, err := loadArgs()
+	if err != nil {
+		return nil, err
+	}
+
+	// already validated
+	var level slog.Level
+	_ = level.UnmarshalText([]byte(args.Log_level))
+	modules, _ := logging.ParseModuleLevels(args.Log_module_levels)
+
+	levelVar := new(slog.LevelVar)
+	levelVar.Set(level)
+	levels := logging.NewLevels(levelVar)
+	levels.SetModules(modules)
+	levels.SetTestEvents(args.Log_test_events)
+
+	var out io.Writer = os.Stdout
+	var logFile *logging.File
+	if args.Log_file != "" {
+		logFile, err = logging.Open(args.Log_file, int64(args.Log_max_size)<<20, args.Log_max_files)
+		if err != nil {
+			return nil, err
+		}
+		out = logFile
+	}
+
+	m := NewMainWithArgs(args, logInit(args.AdvertisedAddr(), args.Log_format, out, levels))
+	m.logLevel = levelVar
+	m.logLevels = levels
+	if logFile != nil {
+		m.logFile = logFile
+	}
+	m.loadArgs = loadArgs
+	log = m.mlog
+	return m, nil
+}
+
+// Obtain the arguments from the defaults, the ini file (if set in cargs) and
+// cargs (which contains the cli arguments and environment variables).
+//
+// The precedence is (the latter overwrites the former): defaults, ini file,
+// environment variables, cli arguments. The resulting arguments are validated.
+// Unknown keys in the ini file (e.g. typos) are logged to log.
+func loadArgs(cargs UserArgs, log *slog.Logger) (args.Args, error) {
+	// obtain the arguments with the default values set
+	a := args.NewFromDefaults()
+
 	// if set also read the ini arguments
 	if cargs.ConfigFile != nil {
 		cfg, err := ini.Load(*cargs.ConfigFile)
 		if err != nil {
-			panic(err)
+			return a, fmt.Errorf("reading config file: %w", err)
+		}
+		for _, key := range unknownKeys(cfg.Section("gossip")) {
+			log.Warn("Unknown option in config file", "file", *cargs.ConfigFile, "key", key)
 		}
 		var iargs UserArgs
 		if err = cfg.Section("gossip").MapTo(&iargs); err != nil {
-			panic(err)
+			return a, fmt.Errorf("parsing config file %s: %w", *cargs.ConfigFile, err)
+		}
+		if err = mapDurations(cfg.Section("gossip"), &iargs); err != nil {
+			return a, fmt.Errorf("parsing config file %s: %w", *cargs.ConfigFile, err)
 		}
 
 		// use args as defaults and overwrite those values which were set by
 		// the ini config file
-		args = iargs.Merge(args)
+		a = iargs.Merge(a)
 	}
 
 	// merge in the end as cli takes predecence
-	args = cargs.Merge(args)
+	a = cargs.Merge(a)
+
+	if err := a.Validate(); err != nil {
+		return a, fmt.Errorf("invalid configuration:\n%w", err)
+	}
+	return a, nil
+}
 
-	return NewMainWithArgs(args, logInit(args.Hz_addr))
+// Keys in section which don't belong to an option
+func unknownKeys(section *ini.Section) []string {
+	known := make(map[string]bool)
+	t := reflect.TypeOf(UserArgs{})
+	for i := 0; i < t.NumField(); i++ {
+		if key := t.Field(i).Tag.Get("ini"); key != "" {
+			known[key] = true
+		}
+	}
+	var unknown []string
+	for _, key := range section.KeyStrings() {
+		if !known[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	return unknown
+}
+
+// A duration option, either with unit (e.g. 500ms, 1s) or a plain number of
+// seconds (like in older config files)
+type durationArg time.Duration
+
+// Parse the duration (used by go-arg for cli arguments and environment
+// variables and by [mapDurations] for the ini file)
+func (d *durationArg) UnmarshalText(text []byte) error {
+	if secs, err := strconv.ParseInt(string(text), 10, 64); err == nil {
+		*d = durationArg(time.Duration(secs) * time.Second)
+		return nil
+	}
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = durationArg(v)
+	return nil
+}
+
+// Set the durations in uargs from section.
+//
+// ini does not know [durationArg] and maps it like a plain integer, so the
+// durations are parsed again after the mapping.
+func mapDurations(section *ini.Section, uargs *UserArgs) error {
+	v := reflect.ValueOf(uargs).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		key := field.Tag.Get("ini")
+		if field.Type != reflect.TypeOf((*durationArg)(nil)) || !section.HasKey(key) {
+			continue
+		}
+		d := new(durationArg)
+		if err := d.UnmarshalText([]byte(section.Key(key).String())); err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		v.Field(i).Set(reflect.ValueOf(d))
+	}
+	return nil
 }
 
 // Start this component.
@@ -173,23 +548,44 @@ func NewMain() *Main {
 // resulted in an error, it will send that error instead of `nil`.
 func (m *Main) Run(initFinished chan<- error) {
 	var err error
-	var ctx context.Context
-	ctx, m.cancel = context.WithCancel(context.Background())
+	if err = m.args.Validate(); err != nil {
+		m.mlog.Error("Invalid arguments", "err", err)
+		initFinished <- err
+		return
+	}
+	ctx := m.ctx
 	m.wg.Add(1)
 
-	vInitFin := make(chan struct{}, 1)
+	vertAddrs := m.args.VertAddrs()
+	vInitFin := make(chan struct{}, len(vertAddrs))
 	gsInitFin := make(chan struct{}, 1)
 
 	va := verticalapi.NewVerticalApi(m.log, m.vertToMain)
-	err = va.Listen(m.args.Vert_addr, vInitFin)
-	if err != nil {
-		m.mlog.Error("Error on listening on vertAPI", "err", err)
-		initFinished <- err
-		return
+	for _, addr := range vertAddrs {
+		err = va.Listen(addr, vInitFin)
+		if err != nil {
+			m.mlog.Error("Error on listening on vertAPI", "err", err)
+			va.Close()
+			initFinished <- err
+			return
+		}
 	}
 	defer va.Close()
 
-	strategy, err := gs.New(m.log, m.args, m.strategyChannels, gsInitFin)
+	if m.metrics != nil {
+		m.metrics.NewGaugeFunc("gossip_vertical_clients", "Modules connected via the vertical API", nil, func() []metrics.Sample {
+			return []metrics.Sample{{Value: float64(va.Connections())}}
+		})
+		srv, err := serveMetrics(m.args.Metrics_addr, m.metrics)
+		if err != nil {
+			m.mlog.Error("Error on serving the metrics", "err", err)
+			initFinished <- err
+			return
+		}
+		defer srv.Close()
+	}
+
+	strategy, err := gs.New(m.log, m.args, m.strategyChannels, m.metrics, gsInitFin)
 	if err != nil {
 		m.mlog.Error("Error on instantiating the strategy", "err", err)
 		initFinished <- err
@@ -199,13 +595,30 @@ func (m *Main) Run(initFinished chan<- error) {
 	strategy.Listen()
 	defer strategy.Close()
 
+	if m.args.Admin_addr != "" {
+		// not all strategies need to support being managed
+		admin, _ := strategy.(gs.StrategyAdmin)
+		srv, err := m.serveAdmin(m.args.Admin_addr, m.args.Admin_token, admin)
+		if err != nil {
+			m.mlog.Error("Error on serving the admin interface", "err", err)
+			initFinished <- err
+			return
+		}
+		defer srv.Close()
+	}
+
 	// wait asynchronously until strat and vertApi are initialized, to notify caller
 	go func(initFinished chan<- error, vInitFin <-chan struct{}, gsInitFin <-chan struct{}) {
-		<-vInitFin
+		for range vertAddrs {
+			<-vInitFin
+		}
 		<-gsInitFin
 		initFinished <- nil
 	}(initFinished, vInitFin, gsInitFin)
 
+	// set once the node is shutting down
+	draining := false
+
 loop:
 	for {
 		select {
@@ -214,17 +627,43 @@ loop:
 			case common.GossipValidation:
 				m.handleGossipValidation(x)
 			case common.GossipAnnounce:
+				if draining {
+					m.mlog.Warn("Rejected announce because the node is shutting down", "type", x.DataType)
+					break
+				}
 				_ = m.handleGossipAnnounce(x)
 			case common.GossipRegister:
 				m.handleTypeRegistration(x)
 			case common.GossipUnRegister:
 				m.handleModuleUnregister(x)
+			case common.GossipCatchupRequest:
+				m.handleCatchupRequest(x)
 			}
 		case x := <-m.strategyChannels.FromStrat:
 			switch x := x.(type) {
 			case common.GossipNotification:
 				m.handleNotification(x)
+			case common.GossipCatchupReply:
+				m.handleCatchupReply(x)
+			}
+		case req := <-m.reload:
+			m.applyArgs(req, strategy)
+		case req := <-m.drain:
+			draining = true
+			m.mlog.Info("Draining before shutdown")
+			if err := va.StopListening(); err != nil {
+				m.mlog.Warn("Stop listening on vertAPI failed", "err", err)
 			}
+			// not all strategies need to support draining
+			drainer, ok := strategy.(gs.StrategyDrainer)
+			if !ok {
+				req.done <- nil
+				break
+			}
+			// keep handling validations while the strategy drains
+			go func() {
+				req.done <- drainer.Drain(req.ctx)
+			}()
 		case <-ctx.Done():
 			break loop
 		}
@@ -233,10 +672,51 @@ loop:
 	m.wg.Done()
 }
 
+// Gracefully terminate the main component.
+//
+// New announces and vertical api connections are rejected while the strategy
+// forwards its pending messages and waits for the outstanding validations.
+// This takes at most Drain_timeout seconds (or until ctx is done), afterwards
+// everything is closed like with [Main.Close]. A Drain_timeout of 0 skips the
+// draining.
+func (m *Main) Shutdown(ctx context.Context) error {
+	m.argsMutex.RLock()
+	drainTimeout := time.Duration(m.args.Drain_timeout) * time.Second
+	m.argsMutex.RUnlock()
+	if drainTimeout == 0 {
+		return m.Close()
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, drainTimeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	var err error
+	select {
+	case m.drain <- drainRequest{ctx: ctx, done: done}:
+		select {
+		case err = <-done:
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	if err != nil {
+		m.mlog.Warn("Draining incomplete", "err", err)
+	} else {
+		m.mlog.Info("Draining finished")
+	}
+	return m.Close()
+}
+
 // terminate the main component
 func (m *Main) Close() error {
 	m.cancel()
 	m.wg.Wait()
+	if m.logFile != nil {
+		return m.logFile.Close()
+	}
 	return nil
 }
 
@@ -248,6 +728,7 @@ func (m *Main) handleModuleUnregister(msg common.GossipUnRegister) {
 		// close the writing end of the connection as well
 		// there is no second goroutine working on that datastructure so no races should occur
 		c.Cfunc()
+		m.updateSubscriptions()
 	}
 }
 
@@ -259,7 +740,40 @@ func (m *Main) handleTypeRegistration(msg common.GossipRegister) {
 		m.mlog.Warn("Skipped registration of module", "type", typeToRegister, "module", msg.Module.Id)
 	} else {
 		m.mlog.Info("Registered module", "type", typeToRegister, "module", msg.Module.Id)
+		m.updateSubscriptions()
+	}
+}
+
+// Tell the strategy for which types modules are registered, so that it can
+// advertise them to the peers
+func (m *Main) updateSubscriptions() {
+	m.strategyChannels.ToStrat <- common.GossipSubscriptions{DataTypes: m.typeStorage.Types()}
+}
+
+// Handle incoming Gossip Catchup messages. The module is registered for the
+// type (if not done already) and the strategy is asked for the messages it
+// already knows.
+func (m *Main) handleCatchupRequest(msg common.GossipCatchupRequest) {
+	typeToRegister := common.GossipType(msg.Data.DataType)
+	if err := m.typeStorage.AddChannelToType(typeToRegister, msg.Module); err == nil {
+		m.mlog.Info("Registered module", "type", typeToRegister, "module", msg.Module.Id)
+		m.updateSubscriptions()
+	}
+	m.mlog.Info("Catchup requested", "type", typeToRegister, "module", msg.Module.Id, "count", msg.Data.Count, "max age", msg.Data.MaxAge)
+	m.strategyChannels.ToStrat <- msg
+}
+
+// Replay the messages of a catchup reply to the module which asked for them
+func (m *Main) handleCatchupReply(msg common.GossipCatchupReply) {
+	for _, n := range msg.Notifications {
+		select {
+		case msg.Module.Data.MainToVert <- n:
+		case <-msg.Module.Ctx.Done():
+			// module is gone in the meantime
+			return
+		}
 	}
+	m.mlog.Info("Catchup replayed", "module", msg.Module.Id, "amount", len(msg.Notifications))
 }
 
 // Handle incoming Gossip Validation messages.
@@ -290,8 +804,10 @@ func (m *Main) handleNotification(msg common.GossipNotification) error {
 	res := m.typeStorage.Load(typeToCheck)
 	if len(res) == 0 {
 		// if no module is registered for this type, mark this message as non-valid (don't propagate it)
+		// but don't blame the peer for it
 		s := common.GossipValidation{
 			MessageId: msg.MessageId,
+			Unhandled: true,
 		}
 		s.SetValid(false)
 		m.strategyChannels.ToStrat <- s
@@ -21,6 +21,7 @@ package gossip_test
 import (
 	"context"
 	"gossip/common"
+	"gossip/internal/args"
 	"gossip/internal/testutils"
 	gossip "gossip/main"
 	vtypes "gossip/verticalAPI/types"
@@ -58,13 +59,16 @@ func NotTestMainHorizontal(test *testing.T) {
 
 	os.Args = []string{"placeholder", "-gossip", "1", "-cache", "2", "-peers", "127.0.1.1:6001,127.0.2.1:6001"}
 
-	m := gossip.NewMain()
+	m, err := gossip.NewMain()
+	if err != nil {
+		panic(err)
+	}
 
 	// run
 	initFin := make(chan error, 1)
 	go m.Run(initFin)
 	defer m.Close()
-	err := <-initFin
+	err = <-initFin
 	if err != nil {
 		panic(err)
 	}
@@ -125,13 +129,16 @@ func NotTestMain(test *testing.T) {
 		},
 	}
 
-	m := gossip.NewMain()
+	m, err := gossip.NewMain()
+	if err != nil {
+		panic(err)
+	}
 
 	// run
 	initFin := make(chan error, 1)
 	go m.Run(initFin)
 	defer m.Close()
-	err := <-initFin
+	err = <-initFin
 	if err != nil {
 		panic(err)
 	}
@@ -362,3 +369,122 @@ func TestMainEndToEndOneHopC(test *testing.T) {
 		test.Fatalf("message was received by %d nodes (should be %d nodes)", len(data), 20)
 	}
 }
+
+// run the erdos graph with strategy, announce msgs messages (one per gossip
+// round) and return how many non-PoW packets were sent and how often a
+// message was received. configure (may be nil) can set further options of
+// the nodes.
+func runStrategy(test *testing.T, strategy string, startIp string, msgs int, configure func(a *args.Args)) (uint, int) {
+	t, err := testutils.NewTesterFromJSON("../test_assets/erdos.json")
+	if err != nil {
+		test.Fatal(err)
+	}
+	t.Strategy = strategy
+	t.Configure = configure
+	if err = t.AddLogger(slogt.New(test)); err != nil {
+		test.Fatal(err)
+	}
+	if err = t.Startup(startIp); err != nil {
+		test.Fatal(err)
+	}
+	if err = t.RegisterAllPeersForType(1337); err != nil {
+		test.Fatal(err)
+	}
+	for i := 0; i < msgs; i++ {
+		msg := vtypes.GossipAnnounce{
+			Ga: common.GossipAnnounce{
+				TTL:      10,
+				Reserved: 0,
+				DataType: 1337,
+				Data:     []byte{byte(i)},
+			},
+			MessageHeader: vtypes.MessageHeader{
+				Type: vtypes.GossipAnnounceType,
+			},
+		}
+		msg.MessageHeader.RecalcSize(&msg)
+		if err = t.Peers[0].SendMsg(&msg); err != nil {
+			test.Fatal(err)
+		}
+		time.Sleep(time.Second)
+	}
+
+	ctx, cfunc := context.WithTimeout(context.Background(), time.Minute)
+	defer cfunc()
+	// interval is three gossip rounds long (announcements are only acted
+	// upon after MISSING_ROUNDS rounds)
+	if err = t.WaitUntilSilent(ctx, true, 0, 3*time.Second); err != nil {
+		test.Fatalf("wait for %s exited with %v", strategy, err)
+	}
+	time.Sleep(1 * time.Second)
+
+	t.Teardown()
+
+	sent, err := t.ProcessSentNonPowPackets(0, true)
+	if err != nil {
+		test.Fatal(err)
+	}
+	var total uint
+	for _, s := range sent {
+		total += s.Cnt
+	}
+	received := 0
+	for _, e := range t.Events {
+		if e.Msg == "received" {
+			received++
+		}
+	}
+	return total, received
+}
+
+func TestMainPlumtreeBandwidth(test *testing.T) {
+	if testing.Short() {
+		test.Skip("skipping the plumtree bandwidth comparison in short mode")
+	}
+	const msgs = 5
+	dummySent, dummyReceived := runStrategy(test, "dummy", "127.0.3.1", msgs, nil)
+	plumtreeSent, plumtreeReceived := runStrategy(test, "plumtree", "127.0.4.1", msgs, nil)
+	test.Logf("packets sent: dummy %d, plumtree %d", dummySent, plumtreeSent)
+
+	// all nodes but the announcing one receive each message
+	if dummyReceived != msgs*19 || plumtreeReceived != msgs*19 {
+		test.Fatalf("messages were received %d (dummy) and %d (plumtree) times (should be %d)", dummyReceived, plumtreeReceived, msgs*19)
+	}
+	if plumtreeSent >= dummySent {
+		test.Fatalf("plumtree sent %d packets, not less than dummy (%d)", plumtreeSent, dummySent)
+	}
+}
+
+func TestMainGossipSub(test *testing.T) {
+	const msgs = 5
+	sent, received := runStrategy(test, "gossipsub", "127.0.5.1", msgs, nil)
+	test.Logf("packets sent: %d", sent)
+
+	// all nodes but the announcing one receive each message
+	if received != msgs*19 {
+		test.Fatalf("messages were received %d times (should be %d)", received, msgs*19)
+	}
+}
+
+func TestMainLazyPush(test *testing.T) {
+	const msgs = 5
+	_, received := runStrategy(test, "dummy", "127.0.6.1", msgs, func(a *args.Args) {
+		a.Lazy_push_types = "1337"
+	})
+
+	// all nodes but the announcing one receive each message
+	if received != msgs*19 {
+		test.Fatalf("messages were received %d times (should be %d)", received, msgs*19)
+	}
+}
+
+func TestMainRumor(test *testing.T) {
+	const msgs = 5
+	sent, received := runStrategy(test, "rumor", "127.0.7.1", msgs, nil)
+	test.Logf("packets sent: %d", sent)
+
+	// all nodes but the announcing one receive each message
+	if received != msgs*19 {
+		test.Fatalf("messages were received %d times (should be %d)", received, msgs*19)
+	}
+}
@@ -0,0 +1,139 @@
+/*
+* gossip
+* Copyright (C) 2024 Fabio Gaiba and Lukas Heindl
+*
+* This program is free software: you can redistribute it and/or modify
+* it under the terms of the GNU General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* This program is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+* GNU General Public License for more details.
+*
+* You should have received a copy of the GNU General Public License
+* along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package gossip
+
+import (
+	"errors"
+	"fmt"
+	"gossip/internal/args"
+	"gossip/internal/logging"
+	gs "gossip/strats"
+	"log/slog"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// Options which can be changed while the node is running, all other options
+// need a restart
+var reloadableArgs = []string{"Degree", "Cache_size", "GossipTimer", "Eager_forward", "Forward_window", "Min_peers", "Target_peers", "Max_peers", "Peer_addrs", "Log_level", "Log_module_levels", "Log_test_events"}
+
+// Request to apply reloaded arguments, the result is sent on done
+type reloadRequest struct {
+	args args.Args
+	done chan<- error
+}
+
+// Read the configuration again (e.g. on SIGHUP) and apply the options which
+// can be changed at runtime (see [reloadableArgs]).
+//
+// Changes to other options are rejected and reported in the returned error,
+// the remaining changes are applied nevertheless. The cli arguments still
+// take precedence over the config file.
+func (m *Main) Reload() error {
+	if m.loadArgs == nil {
+		return errors.New("arguments were not read from the config file, nothing to reload")
+	}
+	a, err := m.loadArgs()
+	if err != nil {
+		m.mlog.Error("Reloading the configuration failed", "err", err)
+		return fmt.Errorf("reading the configuration: %w", err)
+	}
+
+	done := make(chan error, 1)
+	select {
+	case m.reload <- reloadRequest{args: a, done: done}:
+	case <-m.ctx.Done():
+		return errors.New("main already terminated")
+	}
+	return <-done
+}
+
+// Apply the changes of the reloaded arguments compared to the current
+// arguments, the result is sent on req.done. Must be run on the goroutine of
+// [Main.Run].
+func (m *Main) applyArgs(req reloadRequest, strategy gs.StrategyCloser) {
+	newArgs := req.args
+	m.mlog.Info("Reloading the configuration")
+	var rejected []string
+
+	// keep the old values of everything which cannot be changed
+	oldVal := reflect.ValueOf(m.args)
+	newVal := reflect.ValueOf(&newArgs).Elem()
+	for i := 0; i < oldVal.NumField(); i++ {
+		name := oldVal.Type().Field(i).Name
+		if reflect.DeepEqual(oldVal.Field(i).Interface(), newVal.Field(i).Interface()) {
+			continue
+		}
+		if !slices.Contains(reloadableArgs, name) {
+			m.mlog.Warn("Option cannot be changed at runtime, restart the node to apply it", "option", name)
+			rejected = append(rejected, name)
+			newVal.Field(i).Set(oldVal.Field(i))
+		}
+	}
+
+	if newArgs.Log_level != m.args.Log_level {
+		var level slog.Level
+		if m.logLevel == nil {
+			rejected = append(rejected, "Log_level")
+			newArgs.Log_level = m.args.Log_level
+		} else if err := level.UnmarshalText([]byte(newArgs.Log_level)); err != nil {
+			m.mlog.Warn("Invalid log level", "level", newArgs.Log_level, "err", err)
+			rejected = append(rejected, "Log_level")
+			newArgs.Log_level = m.args.Log_level
+		} else {
+			m.mlog.Info("Changing log level", "level", level)
+			m.logLevel.Set(level)
+		}
+	}
+
+	if newArgs.Log_module_levels != m.args.Log_module_levels || newArgs.Log_test_events != m.args.Log_test_events {
+		if m.logLevels == nil {
+			rejected = append(rejected, "Log_module_levels", "Log_test_events")
+			newArgs.Log_module_levels = m.args.Log_module_levels
+			newArgs.Log_test_events = m.args.Log_test_events
+		} else {
+			// already validated
+			modules, _ := logging.ParseModuleLevels(newArgs.Log_module_levels)
+			m.mlog.Info("Changing module log levels", "levels", newArgs.Log_module_levels, "test events", newArgs.Log_test_events)
+			m.logLevels.SetModules(modules)
+			m.logLevels.SetTestEvents(newArgs.Log_test_events)
+		}
+	}
+
+	m.argsMutex.Lock()
+	m.args = newArgs
+	m.argsMutex.Unlock()
+
+	var rejectErr error
+	if len(rejected) > 0 {
+		rejectErr = fmt.Errorf("options cannot be changed at runtime (restart the node): %s", strings.Join(rejected, ", "))
+	}
+
+	reloader, ok := strategy.(gs.StrategyReloader)
+	if !ok {
+		m.mlog.Warn("Strategy does not support reloading the configuration")
+		req.done <- rejectErr
+		return
+	}
+	// main needs to keep running, the strategy might wait for it meanwhile
+	go func() {
+		req.done <- errors.Join(reloader.Reload(newArgs), rejectErr)
+	}()
+}
@@ -0,0 +1,188 @@
+/*
+* gossip
+* Copyright (C) 2024 Fabio Gaiba and Lukas Heindl
+*
+* This program is free software: you can redistribute it and/or modify
+* it under the terms of the GNU General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* This program is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+* GNU General Public License for more details.
+*
+* You should have received a copy of the GNU General Public License
+* along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package strats
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"gossip/common"
+	horizontalapi "gossip/horizontalAPI"
+	ringbuffer "gossip/internal/ringbuffer"
+	"time"
+)
+
+// define potential errors
+var (
+	ErrUnknownPeer error = errors.New("no peer with this id")
+	ErrBannedPeer  error = errors.New("peer is banned")
+	ErrTerminated  error = errors.New("strategy already terminated")
+)
+
+// Information about a connection to a peer
+type PeerInfo struct {
+	Id string `json:"id"`
+	// toBeProved, inProgress or valid
+	State string `json:"state"`
+	// last time the PoW of the peer was checked
+	Timestamp  time.Time `json:"timestamp"`
+	SentPowReq bool      `json:"sentPowReq"`
+	// whether this node dialed the connection
+	Outgoing bool `json:"outgoing"`
+	// identifier of the node at the other end (hex), empty if not known yet
+	NodeId string `json:"nodeId,omitempty"`
+	// smoothed round trip time, 0 if not measured yet
+	RTT time.Duration `json:"rtt"`
+}
+
+// Information about a message in the cache of a strategy
+type CachedMessage struct {
+	// invalid (waiting for validation), valid (waiting to be forwarded) or sent
+	Queue      string            `json:"queue"`
+	MessageId  uint16            `json:"messageId"`
+	GossipType common.GossipType `json:"gossipType"`
+	TTL        uint8             `json:"ttl"`
+	Size       int               `json:"size"`
+	// connection the message was received on (empty if announced locally)
+	Sender   string    `json:"sender"`
+	Received time.Time `json:"received"`
+}
+
+// Strategies implementing this interface can be inspected and managed at
+// runtime (e.g. via the admin interface).
+type StrategyAdmin interface {
+	// list all connections to peers
+	Peers() ([]PeerInfo, error)
+	// close the connection with the given id
+	Disconnect(id string) error
+	// connect to a new neighbor at addr
+	AddPeer(addr string) error
+	// list the messages in the cache
+	Cache() ([]CachedMessage, error)
+}
+
+// Execute f on the goroutine of the strategy and wait for it to finish.
+//
+// This way f can safely access the state of the strategy.
+func (dummy *dummyStrat) runInLoop(f func()) error {
+	done := make(chan struct{})
+	select {
+	case dummy.admin <- func() { f(); close(done) }:
+	case <-dummy.rootStrat.ctx.Done():
+		return ErrTerminated
+	}
+	<-done
+	return nil
+}
+
+// list all connections to peers
+func (dummy *dummyStrat) Peers() ([]PeerInfo, error) {
+	var peers []PeerInfo
+	err := dummy.runInLoop(func() {
+		add := func(state string) func(x *gossipConnection) {
+			return func(x *gossipConnection) {
+				peers = append(peers, PeerInfo{
+					Id:         string(x.connection.Id),
+					State:      state,
+					Timestamp:  x.timestamp,
+					SentPowReq: x.sentPowReq,
+					Outgoing:   x.outgoing,
+					NodeId:     hex.EncodeToString(x.nodeId),
+					RTT:        x.rtt,
+				})
+			}
+		}
+		dummy.connManager.ActionOnToBeProved(add("toBeProved"))
+		dummy.connManager.ActionOnInProgress(add("inProgress"))
+		dummy.connManager.ActionOnValid(add("valid"))
+	})
+	return peers, err
+}
+
+// close the connection with the given id
+func (dummy *dummyStrat) Disconnect(id string) error {
+	var ret error
+	err := dummy.runInLoop(func() {
+		peer, err := dummy.connManager.Remove(horizontalapi.ConnectionId(id))
+		if err != nil {
+			ret = ErrUnknownPeer
+			return
+		}
+		dummy.rootStrat.log.Info("Disconnecting peer as instructed", "ConnId", id)
+		peer.connection.Cfunc()
+	})
+	if err != nil {
+		return err
+	}
+	return ret
+}
+
+// connect to a new neighbor at addr
+//
+// The peer is treated like the neighbors passed on startup, so this peer has
+// to provide a PoW.
+func (dummy *dummyStrat) AddPeer(addr string) error {
+	if dummy.rootStrat.reputation.banned(horizontalapi.ConnectionId(addr)) {
+		return ErrBannedPeer
+	}
+	dialer, err := dummy.rootStrat.dialer()
+	if err != nil {
+		return err
+	}
+	conns, err := dummy.rootStrat.hz.AddNeighbors(dialer, addr)
+	if err != nil {
+		return fmt.Errorf("connecting to %s failed: %w", addr, err)
+	}
+
+	return dummy.runInLoop(func() {
+		// remember the peer, so that it is connected to again if the
+		// connection is lost and there are too few peers
+		if _, ok := dummy.knownPeers[addr]; !ok {
+			dummy.knownPeers[addr] = &knownPeer{configured: true}
+		}
+		for _, c := range conns {
+			dummy.rootStrat.log.Info("Added peer as instructed", "ConnId", c.Id)
+			dummy.admission.connect(c)
+		}
+	})
+}
+
+// list the messages in the cache
+func (dummy *dummyStrat) Cache() ([]CachedMessage, error) {
+	var msgs []CachedMessage
+	err := dummy.runInLoop(func() {
+		add := func(queue string, r *ringbuffer.Ringbuffer[*storedMessage]) {
+			r.Do(func(m *storedMessage) {
+				msgs = append(msgs, CachedMessage{
+					Queue:      queue,
+					MessageId:  m.message.MessageID,
+					GossipType: m.message.GossipType,
+					TTL:        m.message.TTL,
+					Size:       len(m.message.Payload),
+					Sender:     string(m.message.Id),
+					Received:   m.timestamp,
+				})
+			})
+		}
+		add("invalid", dummy.invalidMessages)
+		add("valid", dummy.validMessages)
+		add("sent", dummy.sentMessages)
+	})
+	return msgs, err
+}
@@ -0,0 +1,550 @@
+/*
+* gossip
+* Copyright (C) 2024 Fabio Gaiba and Lukas Heindl
+*
+* This program is free software: you can redistribute it and/or modify
+* it under the terms of the GNU General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* This program is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+* GNU General Public License for more details.
+*
+* You should have received a copy of the GNU General Public License
+* along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package strats
+
+import (
+	"context"
+	"crypto/cipher"
+	"crypto/rand"
+	horizontalapi "gossip/horizontalAPI"
+	pow "gossip/pow"
+	"log/slog"
+	"reflect"
+	"time"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// The admission of peers is independent of the dissemination strategy: every
+// connection has to be proved with a PoW (ConnReq/ConnChall/ConnPoW) before
+// it becomes valid and the PoW has to be renewed periodically
+// (PowReq/PowChall/PowPoW), otherwise the connection is closed.
+//
+// The admission owns the state of the connections in the ConnectionManager.
+// It runs on its own goroutine in front of the strategy (see [admission.run])
+// and handles the handshake messages itself. All other messages are only
+// passed on to the strategy if they were sent by a valid peer. The strategy is
+// told about valid and closed connections via [admissionEvent]s.
+
+// Events of the admission which are passed to the strategy
+type admissionEvent interface {
+	isAdmissionEvent()
+}
+
+// The handshake with the peer succeeded, the connection is valid
+type peerValid struct {
+	id horizontalapi.ConnectionId
+}
+
+// The connection to the peer was closed. wasValid tells whether the
+// connection was valid until then, duplicate whether the node is still
+// connected over another valid connection (the peer closed a duplicate
+// connection, see [dummyStrat.closeDuplicates]).
+type peerClosed struct {
+	id        horizontalapi.ConnectionId
+	wasValid  bool
+	duplicate bool
+}
+
+func (peerValid) isAdmissionEvent()  {}
+func (peerClosed) isAdmissionEvent() {}
+
+// A PoW which was solved in the background, it is sent by the admission
+type solvedPoW struct {
+	peer *gossipConnection
+	pow  horizontalapi.ToHz
+}
+
+// Admission of the peers via PoWs, see [newAdmission]
+type admission struct {
+	ctx context.Context
+	log *slog.Logger
+	// Messages from the horizontal api
+	fromHz <-chan horizontalapi.FromHz
+	// Messages which are passed on to the strategy
+	admitted chan horizontalapi.FromHz
+	// Events for the strategy
+	events chan admissionEvent
+	// PoWs solved for the peers
+	solved chan solvedPoW
+	// Connections to the peers
+	connManager *ConnectionManager
+	// Reputation of the peers (may be nil)
+	reputation *reputationBook
+	metrics    *stratMetrics
+	// ChaCha20 cipher sealing the cookies of the challenges
+	cipher cipher.AEAD
+	// How long a peer has time to provide a PoW
+	powTimeout time.Duration
+	// How often peers are asked to renew their PoW
+	powRequestTime time.Duration
+	// Identifier of this node, exchanged in the ConnReq/ConnChall
+	nodeId []byte
+}
+
+// Create the admission of the peers of strategy. The messages of the peers are
+// read from fromHz, the connections are kept in connManager.
+//
+// The admission only starts working with [admission.run].
+func newAdmission(strategy Strategy, fromHz <-chan horizontalapi.FromHz, connManager *ConnectionManager) *admission {
+	key := make([]byte, chacha20poly1305.KeySize)
+	if _, err := rand.Read(key); err != nil {
+		panic(err)
+	}
+
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		panic(err)
+	}
+
+	return &admission{
+		ctx:            strategy.ctx,
+		log:            strategy.log,
+		fromHz:         fromHz,
+		admitted:       make(chan horizontalapi.FromHz),
+		events:         make(chan admissionEvent),
+		solved:         make(chan solvedPoW),
+		connManager:    connManager,
+		reputation:     strategy.reputation,
+		metrics:        strategy.metrics,
+		cipher:         aead,
+		powTimeout:     time.Duration(strategy.stratArgs.Pow_timeout) * time.Second,
+		powRequestTime: time.Duration(strategy.stratArgs.Pow_request_time) * time.Second,
+		nodeId:         strategy.nodeId,
+	}
+}
+
+// Run the admission until the context of the strategy is done. Should be run
+// in its own goroutine.
+func (adm *admission) run() {
+	// Sending out initial challenges requests
+	adm.connManager.ActionOnToBeProved(func(x *gossipConnection) {
+		send(x.connection, horizontalapi.ConnReq{NodeId: adm.nodeId})
+	})
+	// A repeating signal for the renewing of connections
+	renewalTicker := time.NewTicker(adm.powRequestTime)
+	defer renewalTicker.Stop()
+	// A repeating signal for the checking (and culling) all open connections
+	timeoutTicker := time.NewTicker(adm.powTimeout)
+	defer timeoutTicker.Stop()
+
+	for {
+		select {
+		case x := <-adm.fromHz:
+			if adm.handle(x) {
+				continue
+			}
+			select {
+			case adm.admitted <- x:
+			case <-adm.ctx.Done():
+				return
+			}
+
+		case s := <-adm.solved:
+			adm.sendPoW(s)
+
+		case <-renewalTicker.C:
+			for _, conn := range adm.connManager.MarkPowRequested() {
+				send(conn, horizontalapi.PowReq{})
+			}
+
+		case <-timeoutTicker.C:
+			culled := adm.connManager.CullConnections(adm.isConnectionInvalid)
+			for _, peer := range culled {
+				adm.log.Info("Closing connection since the peer did not renew its PoW", "ConnId", peer.connection.Id)
+				peer.connection.Cfunc()
+				adm.emit(peerClosed{id: peer.connection.Id, wasValid: true})
+			}
+
+		case <-adm.ctx.Done():
+			// should terminate
+			return
+		}
+	}
+}
+
+// Pass an event on to the strategy (dropped if the strategy terminated). nil
+// events are ignored.
+func (adm *admission) emit(ev admissionEvent) {
+	if ev == nil {
+		return
+	}
+	select {
+	case adm.events <- ev:
+	case <-adm.ctx.Done():
+	}
+}
+
+// Handle a message of the handshake. Returns false if the message is not part
+// of it and has to be passed on to the strategy, this is only the case for
+// messages of valid peers.
+func (adm *admission) handle(x horizontalapi.FromHz) bool {
+	switch msg := x.(type) {
+	case horizontalapi.Unregister:
+		// now after removing the peer from all internal datastructures it is
+		// safe to fully close it
+		ev := adm.close(horizontalapi.ConnectionId(msg))
+		if ev == nil {
+			// the connection was already removed (and reported as closed)
+			ev = peerClosed{id: horizontalapi.ConnectionId(msg)}
+		}
+		adm.emit(ev)
+
+	case horizontalapi.NewConn:
+		// Refuse connections from banned peers
+		if adm.reputation.banned(msg.Id) {
+			adm.log.Info("Refusing connection of banned peer", "ConnId", msg.Id)
+			msg.Cfunc()
+			break
+		}
+		// Limit the amount of connections, possibly making room by
+		// evicting another peer
+		evict, admitted := adm.connManager.AdmitInbound(msg.Id)
+		if !admitted {
+			adm.log.Info("Refusing connection since there are too many peers", "ConnId", msg.Id)
+			msg.Cfunc()
+			break
+		}
+		if evict != nil {
+			adm.log.Info("Evicting peer to make room for a peer of another subnet", "ConnId", evict.connection.Id, "new ConnId", msg.Id)
+			adm.emit(adm.close(evict.connection.Id))
+		}
+		// Accept the connection and put it in the inProgress slice.
+		conn := gossipConnection{
+			connection: horizontalapi.Conn[chan<- horizontalapi.ToHz](msg),
+		}
+		adm.connManager.AddInProgress(&conn)
+
+	case horizontalapi.ConnReq:
+		peer, IsInProgress := adm.connManager.FindInProgress(msg.Id)
+
+		if !IsInProgress {
+			adm.log.Warn("ConnReq received from a connection not present in the inProgress connections", "ConnId", msg.Id)
+			break
+		}
+
+		if len(msg.NodeId) != NODE_ID_LEN {
+			adm.log.Warn("ConnReq with an invalid node id received", "ConnId", msg.Id)
+			adm.dropPeer(msg.Id, reputationUnsolicited)
+			break
+		}
+
+		// Create ConnChall message with the encrypted cookie, the node id of
+		// the peer is only trusted once it solved the PoW for it
+		cookie := NewConnCookie(msg.Id, msg.NodeId)
+		m := horizontalapi.ConnChall{
+			Id:     msg.Id,
+			Cookie: cookie.CreateCookie(adm.cipher),
+			NodeId: adm.nodeId,
+		}
+
+		send(peer.connection, m)
+
+	case horizontalapi.ConnChall:
+		// Checks weather the Chall is coming from a toBeProvedConnection
+		peer, isToBeProved := adm.connManager.FindToBeProved(msg.Id)
+		if !isToBeProved {
+			adm.log.Warn("ConnChall received from a not toBeProved connection", "ConnId", msg.Id)
+			adm.dropPeer(msg.Id, reputationUnsolicited)
+			break
+		}
+
+		if len(msg.NodeId) != NODE_ID_LEN {
+			adm.log.Warn("ConnChall with an invalid node id received", "ConnId", msg.Id)
+			adm.dropPeer(msg.Id, reputationUnsolicited)
+			break
+		}
+		// the peer answered on the connection this node dialed, so it is the
+		// one behind the node id
+		if err := adm.connManager.Identify(msg.Id, msg.NodeId); err != nil {
+			break
+		}
+
+		go adm.solvePoW(peer, msg.Cookie, func(nonce uint64) horizontalapi.ToHz {
+			return horizontalapi.ConnPoW{PowNonce: nonce, Cookie: msg.Cookie}
+		})
+
+	// Checks incoming PoWs
+	case horizontalapi.ConnPoW:
+		_, connValidty := adm.connManager.FindInProgress(msg.Id)
+		if !connValidty {
+			adm.log.Warn("ConnPow received was from a connection which is not actually in Progress", "ConnId", msg.Id)
+			adm.dropPeer(msg.Id, reputationUnsolicited)
+			break
+		}
+
+		cookieRead, ok := adm.checkPoW(msg.Id, msg.PowNonce, msg.Cookie)
+		if !ok {
+			break
+		}
+
+		// check if time taken for giving pow is within the limits
+		diff := time.Now().Sub(cookieRead.timestamp)
+
+		if diff > adm.powTimeout {
+			adm.log.Info("POW for accepting connection was given not within the time limit", "expected conn Id", cookieRead.dest, "ConnId", msg.Id)
+			adm.dropPeer(msg.Id, reputationLatePow)
+			break
+		}
+
+		adm.ratePeer(msg.Id, reputationValidPow)
+		if err := adm.connManager.Identify(msg.Id, cookieRead.nodeId); err != nil {
+			// banned in the meantime
+			break
+		}
+		adm.connManager.MakeValid(msg.Id, cookieRead.timestamp)
+		adm.emit(peerValid{id: msg.Id})
+
+	case horizontalapi.PowReq:
+		// Create PowChall message with the encrypted cookie
+		cookie := NewConnCookie(msg.Id, nil)
+
+		peer, isValid := adm.connManager.FindValid(msg.Id)
+		if !isValid {
+			adm.log.Warn("Id not found in the connection manager", "ConnId", msg.Id)
+			break
+		}
+
+		m := horizontalapi.PowChall{
+			Id:     msg.Id,
+			Cookie: cookie.CreateCookie(adm.cipher),
+		}
+
+		send(peer.connection, m)
+
+	case horizontalapi.PowChall:
+		// Checks weather the Chall is from an openConnection (renewal)
+		peer, isValid := adm.connManager.FindValid(msg.Id)
+
+		if !isValid {
+			adm.log.Warn("PowChall received from a not valid connection", "ConnId", msg.Id)
+			adm.dropPeer(msg.Id, reputationUnsolicited)
+			break
+		}
+
+		// Check if the there was a Req sent (only one Chall is answered)
+		if !adm.connManager.ClearPowRequested(msg.Id) {
+			adm.log.Warn("PowChall received but no PowReq was sent, possible DoS", "ConnId", msg.Id)
+			adm.dropPeer(msg.Id, reputationUnsolicited)
+			break
+		}
+
+		go adm.solvePoW(peer, msg.Cookie, func(nonce uint64) horizontalapi.ToHz {
+			return horizontalapi.PowPoW{PowNonce: nonce, Cookie: msg.Cookie}
+		})
+
+	case horizontalapi.PowPoW:
+		// Checks weather the PoW is from an openConnection (renewal)
+		_, connValidty := adm.connManager.FindValid(msg.Id)
+		if !connValidty {
+			adm.log.Warn("PoWPoW received was from a connection which is not actually valid", "ConnId", msg.Id)
+			adm.dropPeer(msg.Id, reputationUnsolicited)
+			break
+		}
+
+		cookieRead, ok := adm.checkPoW(msg.Id, msg.PowNonce, msg.Cookie)
+		if !ok {
+			break
+		}
+
+		adm.ratePeer(msg.Id, reputationValidPow)
+		adm.connManager.MakeValid(msg.Id, cookieRead.timestamp)
+
+	default:
+		// all other messages are only accepted from admitted peers
+		id := messageSender(x)
+		if _, isValid := adm.connManager.FindValid(id); isValid {
+			return false
+		}
+		if _, known := adm.connManager.Find(id); !known {
+			// e.g. sent before the connection was culled
+			adm.log.Debug("Message of a closed connection dropped", "type", reflect.TypeOf(x), "ConnId", id)
+			break
+		}
+		adm.log.Warn("Message received before the handshake was completed, dropping connection", "type", reflect.TypeOf(x), "ConnId", id)
+		adm.dropPeer(id, reputationUnsolicited)
+	}
+	return true
+}
+
+// Returns the connection a message of a peer was received on
+func messageSender(x horizontalapi.FromHz) horizontalapi.ConnectionId {
+	switch msg := x.(type) {
+	case horizontalapi.Push:
+		return msg.Id
+	case horizontalapi.ConnReq:
+		return msg.Id
+	case horizontalapi.ConnChall:
+		return msg.Id
+	case horizontalapi.ConnPoW:
+		return msg.Id
+	case horizontalapi.PowReq:
+		return msg.Id
+	case horizontalapi.PowChall:
+		return msg.Id
+	case horizontalapi.PowPoW:
+		return msg.Id
+	case horizontalapi.IHave:
+		return msg.Id
+	case horizontalapi.Graft:
+		return msg.Id
+	case horizontalapi.Prune:
+		return msg.Id
+	case horizontalapi.IWant:
+		return msg.Id
+	case horizontalapi.Subscribe:
+		return msg.Id
+	case horizontalapi.Extension:
+		return msg.Id
+	case horizontalapi.Unregister:
+		return horizontalapi.ConnectionId(msg)
+	case horizontalapi.NewConn:
+		return msg.Id
+	}
+	return ""
+}
+
+// Check the PoW of the peer with connection id for the cookie which was sent
+// to it. The peer is dropped if the PoW is invalid.
+//
+// Returns the content of the cookie and whether the PoW is valid.
+func (adm *admission) checkPoW(id horizontalapi.ConnectionId, nonce uint64, cookie []byte) (*connCookie, bool) {
+	mypow := powMarsh{PowNonce: nonce, Cookie: cookie}
+	cookieRead, err := ReadCookie(adm.cipher, mypow.Cookie)
+
+	if err != nil {
+		adm.log.Warn("Failed to decrypt cookie, dropping connection", "ConnId", id)
+		adm.dropPeer(id, reputationInvalidPow)
+		return nil, false
+	}
+
+	// Check proof of work
+	powValidity := pow.CheckProofOfWork(func(digest []byte) bool {
+		return pow.First8bits0(digest)
+	}, &mypow)
+
+	if !powValidity {
+		adm.log.Warn("Invalid pow, dropping connection", "ConnId", id)
+		adm.dropPeer(id, reputationInvalidPow)
+		return nil, false
+	}
+
+	// check if dest is valid
+	if cookieRead.dest != id {
+		adm.log.Warn("Mismatched connectionId between received connPow and sender", "expected conn Id", cookieRead.dest, "ConnId", id)
+		adm.dropPeer(id, reputationInvalidPow)
+		return nil, false
+	}
+	return cookieRead, true
+}
+
+// Start the handshake on a connection this node dialed
+func (adm *admission) connect(conn horizontalapi.Conn[chan<- horizontalapi.ToHz]) {
+	adm.connManager.AddToBeProved(&gossipConnection{connection: conn, outgoing: true})
+	send(conn, horizontalapi.ConnReq{NodeId: adm.nodeId})
+}
+
+// Solve the PoW for cookie and record the time it took. Should be run in its
+// own goroutine, the message created by wrap is sent by the admission (see
+// [admission.sendPoW]).
+func (adm *admission) solvePoW(peer *gossipConnection, cookie []byte, wrap func(nonce uint64) horizontalapi.ToHz) {
+	start := time.Now()
+	nonce := ComputePoW(cookie)
+	adm.metrics.powSolve.Observe(time.Since(start).Seconds())
+	select {
+	case adm.solved <- solvedPoW{peer: peer, pow: wrap(nonce)}:
+	case <-peer.connection.Ctx.Done():
+	// connection was already closed in the meantime
+	case <-adm.ctx.Done():
+	}
+}
+
+// Send a solved PoW. A connection proved with a ConnPoW becomes valid.
+//
+// This is done on the goroutine of the admission, so the connection is valid
+// before the peer can answer the PoW and nothing else is sent to the peer
+// before the PoW.
+func (adm *admission) sendPoW(s solvedPoW) {
+	id := s.peer.connection.Id
+	if _, isConnPoW := s.pow.(horizontalapi.ConnPoW); isConnPoW {
+		if _, toBeProved := adm.connManager.FindToBeProved(id); !toBeProved {
+			// connection was closed in the meantime
+			return
+		}
+		send(s.peer.connection, s.pow)
+		adm.connManager.MakeValid(id, time.Now())
+		adm.emit(peerValid{id: id})
+		return
+	}
+	send(s.peer.connection, s.pow)
+}
+
+// Record a reputation event for the peer with connection id. If the peer gets
+// banned because of this, its connection is removed and closed.
+//
+// Returns the event for the strategy if the connection was closed (nil
+// otherwise). It is not emitted since this is also used by the strategy (see
+// [dummyStrat.ratePeer]), use [admission.ratePeer] on the goroutine of the
+// admission.
+func (adm *admission) rate(id horizontalapi.ConnectionId, event reputationEvent) admissionEvent {
+	if !adm.reputation.record(id, event) {
+		return nil
+	}
+	adm.log.Warn("Peer banned, closing connection", "ConnId", id)
+	return adm.close(id)
+}
+
+// Drop the connection id because the peer misbehaved. The misbehaviour is
+// recorded in the reputation of the peer as well.
+//
+// Returns the event for the strategy like [admission.rate].
+func (adm *admission) drop(id horizontalapi.ConnectionId, event reputationEvent) admissionEvent {
+	if ev := adm.rate(id, event); ev != nil {
+		return ev
+	}
+	return adm.close(id)
+}
+
+// Remove and close the connection id. Returns the event for the strategy (nil
+// if the connection was not found).
+func (adm *admission) close(id horizontalapi.ConnectionId) admissionEvent {
+	_, wasValid := adm.connManager.FindValid(id)
+	peer, err := adm.connManager.Remove(id)
+	if err != nil {
+		return nil
+	}
+	peer.connection.Cfunc()
+	return peerClosed{id: id, wasValid: wasValid, duplicate: wasValid && adm.connManager.StillConnected(peer)}
+}
+
+// Like [admission.rate], the event is emitted
+func (adm *admission) ratePeer(id horizontalapi.ConnectionId, event reputationEvent) {
+	adm.emit(adm.rate(id, event))
+}
+
+// Like [admission.drop], the event is emitted
+func (adm *admission) dropPeer(id horizontalapi.ConnectionId, event reputationEvent) {
+	adm.emit(adm.drop(id, event))
+}
+
+// Returns weather the connection is valid or not
+func (adm *admission) isConnectionInvalid(peer *gossipConnection) bool {
+	diff := time.Now().Sub(peer.timestamp)
+	return !(diff < adm.powTimeout)
+}
@@ -0,0 +1,124 @@
+/*
+* gossip
+* Copyright (C) 2024 Fabio Gaiba and Lukas Heindl
+*
+* This program is free software: you can redistribute it and/or modify
+* it under the terms of the GNU General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* This program is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+* GNU General Public License for more details.
+*
+* You should have received a copy of the GNU General Public License
+* along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package strats
+
+import (
+	"bytes"
+	"context"
+	horizontalapi "gossip/horizontalAPI"
+	"gossip/internal/args"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/neilotoole/slogt"
+)
+
+// Pass the messages sent on a connection to the admission of the peer, as
+// the horizontal api would do
+func relay(ctx context.Context, from <-chan horizontalapi.ToHz, to chan<- horizontalapi.FromHz, id horizontalapi.ConnectionId) {
+	for {
+		var msg horizontalapi.ToHz
+		select {
+		case msg = <-from:
+		case <-ctx.Done():
+			return
+		}
+		switch m := msg.(type) {
+		case horizontalapi.ConnReq:
+			m.Id = id
+			to <- m
+		case horizontalapi.ConnChall:
+			m.Id = id
+			to <- m
+		case horizontalapi.ConnPoW:
+			m.Id = id
+			to <- m
+		}
+	}
+}
+
+func TestAdmission(test *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	strategy := Strategy{ctx: ctx, stratArgs: args.NewFromDefaults(), log: slogt.New(test), metrics: newStratMetrics(nil)}
+
+	expect := func(adm *admission, want admissionEvent) {
+		test.Helper()
+		select {
+		case got := <-adm.events:
+			if !reflect.DeepEqual(got, want) {
+				test.Fatalf("got event %+v instead of %+v", got, want)
+			}
+		case <-time.After(5 * time.Second):
+			test.Fatalf("did not get event %+v", want)
+		}
+	}
+
+	// a dialed b
+	toA := make(chan horizontalapi.ToHz, 8)
+	toB := make(chan horizontalapi.ToHz, 8)
+	fromHzA := make(chan horizontalapi.FromHz)
+	fromHzB := make(chan horizontalapi.FromHz)
+	managerA := NewConnectionManager([]horizontalapi.Conn[chan<- horizontalapi.ToHz]{{Id: "b", Data: toB, Ctx: ctx, Cfunc: func() {}}}, nil)
+	managerB := NewConnectionManager(nil, nil)
+	admA := newAdmission(strategy, fromHzA, &managerA)
+	admB := newAdmission(strategy, fromHzB, &managerB)
+	admA.nodeId = bytes.Repeat([]byte{0x0a}, NODE_ID_LEN)
+	admB.nodeId = bytes.Repeat([]byte{0x0b}, NODE_ID_LEN)
+
+	go admB.run()
+	connToA := horizontalapi.Conn[chan<- horizontalapi.ToHz]{Id: "a", Data: toA, Ctx: ctx, Cfunc: func() {}}
+	fromHzB <- horizontalapi.NewConn(connToA)
+
+	// the handshake makes the connection valid on both ends
+	go relay(ctx, toB, fromHzB, "a")
+	go relay(ctx, toA, fromHzA, "b")
+	go admA.run()
+	expect(admA, peerValid{id: "b"})
+	expect(admB, peerValid{id: "a"})
+	// the node ids were exchanged in the handshake
+	if peer, _ := managerA.FindValid("b"); !bytes.Equal(peer.nodeId, admB.nodeId) {
+		test.Fatalf("a recorded node id %x for b instead of %x", peer.nodeId, admB.nodeId)
+	}
+	if peer, _ := managerB.FindValid("a"); !bytes.Equal(peer.nodeId, admA.nodeId) {
+		test.Fatalf("b recorded node id %x for a instead of %x", peer.nodeId, admA.nodeId)
+	}
+
+	// only messages of valid peers are passed on, a peer which sends
+	// messages before completing the handshake is dropped
+	fromHzB <- horizontalapi.Push{Id: "c", MessageID: 1}
+	fromHzB <- horizontalapi.NewConn{Id: "d", Data: make(chan horizontalapi.ToHz, 8), Ctx: ctx, Cfunc: func() {}}
+	fromHzB <- horizontalapi.Subscribe{Id: "d"}
+	expect(admB, peerClosed{id: "d", wasValid: false})
+	if _, known := managerB.Find("d"); known {
+		test.Fatalf("peer which did not complete the handshake was not dropped")
+	}
+	push := horizontalapi.Push{Id: "a", MessageID: 2}
+	fromHzB <- push
+	if got := <-admB.admitted; !reflect.DeepEqual(got, horizontalapi.FromHz(push)) {
+		test.Fatalf("passed on %+v instead of %+v", got, push)
+	}
+
+	fromHzB <- horizontalapi.Unregister("a")
+	expect(admB, peerClosed{id: "a", wasValid: true})
+	if _, isValid := managerB.FindValid("a"); isValid {
+		test.Fatalf("closed connection is still valid")
+	}
+}
@@ -19,17 +19,61 @@
 package strats
 
 import (
+	"bytes"
 	"errors"
+	"gossip/common"
 	horizontalapi "gossip/horizontalAPI"
-	mrand "math/rand"
 	"sync"
 	"time"
 )
 
 type gossipConnection struct {
 	connection horizontalapi.Conn[chan<- horizontalapi.ToHz]
+	// state of the handshake, only changed with the connection manager
+	// locked for writing (it is read on the goroutine of the strategy as well)
 	timestamp  time.Time
 	sentPowReq bool
+	// whether this node dialed the connection
+	outgoing bool
+	// whether a Shuffle was sent which was not answered yet
+	sentShuffle bool
+	// when the last Shuffle of the peer was answered
+	lastShuffle time.Time
+	// identifier of the node at the other end (sent in the ConnReq or
+	// ConnChall), nil as long as it is unknown
+	nodeId []byte
+	// address under which the node at the other end accepts connections
+	// (sent in its Hello), empty as long as it is unknown
+	addr string
+	// whether messages are only announced to the peer instead of being
+	// pushed (plumtree strategy)
+	lazy bool
+	// gossip types the peer is subscribed to (sent in its Subscribe), nil as
+	// long as it did not send any
+	subscriptions []common.GossipType
+	// send times of the Pings which were not answered yet by their nonce (at
+	// most PING_MAX_OUTSTANDING, see [dummyStrat.sendPings])
+	pings map[uint64]time.Time
+	// smoothed round trip time to the peer (see [ConnectionManager.UpdateRTT]),
+	// 0 as long as it was not measured
+	rtt time.Duration
+	// last time a message was pushed or announced to the peer (see
+	// [lruPolicy])
+	lastUsed time.Time
+}
+
+// Send msg on the connection. Returns false if the connection was closed in
+// the meantime (the message is dropped then).
+//
+// Sending directly on the Data channel would block forever if the connection
+// is closed, since no one reads from the channel anymore.
+func send(conn horizontalapi.Conn[chan<- horizontalapi.ToHz], msg horizontalapi.ToHz) bool {
+	select {
+	case conn.Data <- msg:
+		return true
+	case <-conn.Ctx.Done():
+		return false
+	}
 }
 
 // This object is used to manage the connection used by the gossip strategy
@@ -47,6 +91,12 @@ type ConnectionManager struct {
 	openConnectionsMap map[horizontalapi.ConnectionId]*gossipConnection
 	// Map of invalid connection (for fast access) that needs to be validated
 	powInProgress map[horizontalapi.ConnectionId]*gossipConnection
+	// Reputation of the peers, used to prefer well-behaving peers (may be nil)
+	reputation *reputationBook
+	// Limits of the amount of connections, see [ConnectionManager.SetPeerLimits]
+	minPeers    uint
+	targetPeers uint
+	maxPeers    uint
 
 	// Mutex to synchronize between proving connections and validating connections
 	connMutex sync.RWMutex
@@ -57,16 +107,21 @@ type ConnectionManager struct {
 // They are needed so more complex function can chain them after
 
 // Return a new instance of the Connection Manager.
-func NewConnectionManager(toBeProved []horizontalapi.Conn[chan<- horizontalapi.ToHz]) ConnectionManager {
+//
+// reputation is used to prefer peers with a good reputation when selecting
+// peers. It may be nil, then all peers are treated equally.
+func NewConnectionManager(toBeProved []horizontalapi.Conn[chan<- horizontalapi.ToHz], reputation *reputationBook) ConnectionManager {
 	toBeProvedMap := make(map[horizontalapi.ConnectionId]*gossipConnection)
 	for _, conn := range toBeProved {
-		toBeProvedMap[conn.Id] = &gossipConnection{connection: conn}
+		// connections to be proved are the ones this node dialed
+		toBeProvedMap[conn.Id] = &gossipConnection{connection: conn, outgoing: true}
 	}
 
 	return ConnectionManager{
 		toBeProvedConnections: toBeProvedMap,
 		openConnectionsMap:    make(map[horizontalapi.ConnectionId]*gossipConnection),
 		powInProgress:         make(map[horizontalapi.ConnectionId]*gossipConnection),
+		reputation:            reputation,
 	}
 }
 
@@ -80,6 +135,16 @@ func (manager *ConnectionManager) ActionOnToBeProved(f func(x *gossipConnection)
 	}
 }
 
+// Perform a function f on every In Progress connection
+func (manager *ConnectionManager) ActionOnInProgress(f func(x *gossipConnection)) {
+	manager.connMutex.RLock()
+	defer manager.connMutex.RUnlock()
+
+	for _, conn := range manager.powInProgress {
+		f(conn)
+	}
+}
+
 // Perform an function f on every valid (open) connection
 func (manager *ConnectionManager) ActionOnValid(f func(x *gossipConnection)) {
 	manager.connMutex.RLock()
@@ -92,11 +157,20 @@ func (manager *ConnectionManager) ActionOnValid(f func(x *gossipConnection)) {
 
 // Function which perform a function f on a permutation of the valid connections.
 // Max is the number of elements we want to perform the action on
+//
+// The permutation is weighted by the reputation of the peers, so peers with a
+// higher reputation are more likely to be among the first max elements.
 func (manager *ConnectionManager) ActionOnPermutedValid(f func(x *gossipConnection), max int) {
+	manager.ActionOnSelectedValid(reputationPolicy(manager.reputation), f, max)
+}
+
+// Perform a function f on the first max valid connections in the order
+// given by policy.
+func (manager *ConnectionManager) ActionOnSelectedValid(policy selectionPolicy, f func(x *gossipConnection), max int) {
 	manager.connMutex.RLock()
 	defer manager.connMutex.RUnlock()
 
-	perm := mrand.Perm(len(manager.openConnections))
+	perm := policy(manager.openConnections)
 	amount := min(max, len(manager.openConnections))
 
 	for i := 0; i < amount; i++ {
@@ -105,6 +179,15 @@ func (manager *ConnectionManager) ActionOnPermutedValid(f func(x *gossipConnecti
 	}
 }
 
+// Returns the amount of connections in each state: to be proved, in progress
+// and valid (open)
+func (manager *ConnectionManager) Counts() (toBeProved int, inProgress int, valid int) {
+	manager.connMutex.RLock()
+	defer manager.connMutex.RUnlock()
+
+	return len(manager.toBeProvedConnections), len(manager.powInProgress), len(manager.openConnections)
+}
+
 // Returns the connection with matching ID from the to be proved connections and a boolean indicating
 // the presence of the value
 func (manager *ConnectionManager) FindToBeProved(id horizontalapi.ConnectionId) (*gossipConnection, bool) {
@@ -135,6 +218,173 @@ func (manager *ConnectionManager) FindValid(id horizontalapi.ConnectionId) (*gos
 	return value, ok
 }
 
+// Returns the connection with matching ID in any state and a boolean
+// indicating the presence of the value
+func (manager *ConnectionManager) Find(id horizontalapi.ConnectionId) (*gossipConnection, bool) {
+	manager.connMutex.RLock()
+	defer manager.connMutex.RUnlock()
+
+	return manager.unsafeFind(id)
+}
+
+// Mark that a renewal of the PoW is requested from all valid connections.
+// Returns the connections so that the PowReqs can be sent (without holding
+// the lock).
+func (manager *ConnectionManager) MarkPowRequested() []horizontalapi.Conn[chan<- horizontalapi.ToHz] {
+	manager.connMutex.Lock()
+	defer manager.connMutex.Unlock()
+
+	conns := make([]horizontalapi.Conn[chan<- horizontalapi.ToHz], 0, len(manager.openConnections))
+	for _, peer := range manager.openConnections {
+		peer.sentPowReq = true
+		conns = append(conns, peer.connection)
+	}
+	return conns
+}
+
+// Forget the request of a renewal of the PoW of the valid connection with the
+// given ID. Returns whether a renewal was requested.
+func (manager *ConnectionManager) ClearPowRequested(id horizontalapi.ConnectionId) bool {
+	manager.connMutex.Lock()
+	defer manager.connMutex.Unlock()
+
+	peer, ok := manager.openConnectionsMap[id]
+	if !ok || !peer.sentPowReq {
+		return false
+	}
+	peer.sentPowReq = false
+	return true
+}
+
+// Record the identifier of the node at the other end of the connection with
+// the given ID. The identifier has to be bound to the handshake (see
+// [admission.handle]).
+func (manager *ConnectionManager) Identify(id horizontalapi.ConnectionId, nodeId []byte) error {
+	manager.connMutex.Lock()
+	defer manager.connMutex.Unlock()
+
+	peer, ok := manager.unsafeFind(id)
+	if !ok {
+		return errors.New("No element found when setting the node id")
+	}
+	peer.nodeId = nodeId
+	return nil
+}
+
+// Returns another valid connection to the node at the other end of the valid
+// connection with the given ID, or nil if there is none.
+//
+// Only connections from the same host count as duplicates, a node reachable
+// under several addresses (e.g. IPv4 and IPv6) keeps its connections.
+func (manager *ConnectionManager) Duplicate(id horizontalapi.ConnectionId) (*gossipConnection, error) {
+	manager.connMutex.RLock()
+	defer manager.connMutex.RUnlock()
+
+	peer, ok := manager.openConnectionsMap[id]
+	if !ok {
+		return nil, errors.New("No valid connection found when looking for duplicates")
+	}
+	return manager.unsafeDuplicate(peer), nil
+}
+
+// Returns whether the node at the other end of the removed connection peer is
+// still connected over another valid connection (see
+// [ConnectionManager.Duplicate]), i.e. the connection was a duplicate.
+func (manager *ConnectionManager) StillConnected(peer *gossipConnection) bool {
+	manager.connMutex.RLock()
+	defer manager.connMutex.RUnlock()
+	return manager.unsafeDuplicate(peer) != nil
+}
+
+// Returns another valid connection to the node at the other end of peer, or
+// nil if there is none (without locking resources)
+func (manager *ConnectionManager) unsafeDuplicate(peer *gossipConnection) *gossipConnection {
+	if peer.nodeId == nil {
+		return nil
+	}
+	for _, x := range manager.openConnections {
+		if x != peer && bytes.Equal(x.nodeId, peer.nodeId) && hostOf(x.connection.Id) == hostOf(peer.connection.Id) {
+			return x
+		}
+	}
+	return nil
+}
+
+// Record the address under which the node at the other end of the connection
+// with the given ID accepts connections (sent in its Hello)
+func (manager *ConnectionManager) SetAddr(id horizontalapi.ConnectionId, addr string) error {
+	manager.connMutex.Lock()
+	defer manager.connMutex.Unlock()
+
+	peer, ok := manager.unsafeFind(id)
+	if !ok {
+		return errors.New("No element found when setting the address")
+	}
+	peer.addr = addr
+	return nil
+}
+
+// Record a measured round trip time to the peer of the connection with the
+// given ID. Like in TCP, the kept RTT is smoothed (7/8 old value, 1/8 new
+// sample) so that single outliers don't matter much.
+//
+// Returns the new smoothed RTT and whether the connection was found.
+func (manager *ConnectionManager) UpdateRTT(id horizontalapi.ConnectionId, sample time.Duration) (time.Duration, bool) {
+	manager.connMutex.Lock()
+	defer manager.connMutex.Unlock()
+
+	peer, ok := manager.unsafeFind(id)
+	if !ok {
+		return 0, false
+	}
+	if peer.rtt == 0 {
+		peer.rtt = sample
+	} else {
+		peer.rtt = (7*peer.rtt + sample) / 8
+	}
+	return peer.rtt, true
+}
+
+// Returns the smoothed round trip time to the peer of the connection with the
+// given ID and whether it was measured already
+func (manager *ConnectionManager) RTT(id horizontalapi.ConnectionId) (time.Duration, bool) {
+	manager.connMutex.RLock()
+	defer manager.connMutex.RUnlock()
+
+	peer, ok := manager.unsafeFind(id)
+	if !ok || peer.rtt == 0 {
+		return 0, false
+	}
+	return peer.rtt, true
+}
+
+// Returns the connection with matching ID in any state, without locking
+// resources
+func (manager *ConnectionManager) unsafeFind(id horizontalapi.ConnectionId) (*gossipConnection, bool) {
+	if peer, ok := manager.toBeProvedConnections[id]; ok {
+		return peer, true
+	}
+	if peer, ok := manager.powInProgress[id]; ok {
+		return peer, true
+	}
+	peer, ok := manager.openConnectionsMap[id]
+	return peer, ok
+}
+
+// Perform a function f on every connection regardless of its state, without
+// locking resources
+func (manager *ConnectionManager) unsafeAction(f func(x *gossipConnection)) {
+	for _, conn := range manager.toBeProvedConnections {
+		f(conn)
+	}
+	for _, conn := range manager.powInProgress {
+		f(conn)
+	}
+	for _, conn := range manager.openConnections {
+		f(conn)
+	}
+}
+
 // Remove the connection with a specific ID, without locking resources
 //
 // (Wrapper around unsafeRemove with locking for thread safety)
@@ -203,6 +453,14 @@ func (manager *ConnectionManager) MakeValid(id horizontalapi.ConnectionId, times
 	}
 }
 
+// Add a gossip connection to the To Be Proved ones
+func (manager *ConnectionManager) AddToBeProved(peer *gossipConnection) {
+	manager.connMutex.Lock()
+	defer manager.connMutex.Unlock()
+
+	manager.toBeProvedConnections[peer.connection.Id] = peer
+}
+
 // Add a gossip connection to the In Progress ones
 func (manager *ConnectionManager) AddInProgress(peer *gossipConnection) {
 	manager.connMutex.Lock()
@@ -211,17 +469,20 @@ func (manager *ConnectionManager) AddInProgress(peer *gossipConnection) {
 	manager.powInProgress[peer.connection.Id] = peer
 }
 
-// Remove all valid connection on which f return true
-func (manager *ConnectionManager) CullConnections(f func(x *gossipConnection) bool) {
+// Remove all valid connection on which f return true. The removed
+// connections are returned so that they can be closed.
+func (manager *ConnectionManager) CullConnections(f func(x *gossipConnection) bool) []*gossipConnection {
 	manager.connMutex.Lock()
 	defer manager.connMutex.Unlock()
 
 	toRemove := make([]int, 0)
+	culled := make([]*gossipConnection, 0)
 	for i, peer := range manager.openConnections {
 		if f(peer) {
 			//Remove it from the map
 			delete(manager.openConnectionsMap, peer.connection.Id)
 			toRemove = append(toRemove, i)
+			culled = append(culled, peer)
 		}
 	}
 
@@ -230,5 +491,5 @@ func (manager *ConnectionManager) CullConnections(f func(x *gossipConnection) bo
 		manager.openConnections[toRemove[i]] = manager.openConnections[len(manager.openConnections)-1]
 		manager.openConnections = manager.openConnections[:len(manager.openConnections)-1]
 	}
-
+	return culled
 }
@@ -32,7 +32,7 @@ func TestConnectoinManager(test *testing.T) {
 	for i, _ := range conns {
 		conns[i].Id = horizontalapi.ConnectionId("ciao" + fmt.Sprint(i))
 	}
-	manager := NewConnectionManager(conns)
+	manager := NewConnectionManager(conns, nil)
 
 	var ids string
 	manager.ActionOnToBeProved(func(x *gossipConnection) {
@@ -37,10 +37,14 @@ type connCookie struct {
 	chall     []byte
 	timestamp time.Time
 	dest      horizontalapi.ConnectionId
+	// node id the requesting peer sent in its ConnReq (nil for the renewal
+	// of a PoW)
+	nodeId []byte
 }
 
-// Return a new cookie object with provided destination (timestamp is set to now)
-func NewConnCookie(dest horizontalapi.ConnectionId) connCookie {
+// Return a new cookie object with provided destination and node id of the
+// requesting peer (timestamp is set to now)
+func NewConnCookie(dest horizontalapi.ConnectionId, nodeId []byte) connCookie {
 	nonce := make([]byte, chacha20poly1305.NonceSizeX)
 	if _, err := rand.Read(nonce); err != nil {
 		panic(err)
@@ -50,13 +54,14 @@ func NewConnCookie(dest horizontalapi.ConnectionId) connCookie {
 		chall:     nonce,
 		timestamp: time.Unix(0, time.Now().UnixNano()),
 		dest:      dest,
+		nodeId:    nodeId,
 	}
 }
 
 // This function will marshal the cookie object and return the resulting byte slice
 func (x *connCookie) Marshal() []byte {
 	timestamp := x.timestamp.UnixNano()
-	buf := make([]byte, len(x.chall)+binary.Size(timestamp)+len(x.dest))
+	buf := make([]byte, len(x.chall)+binary.Size(timestamp)+1+len(x.nodeId)+len(x.dest))
 
 	idx := 0
 
@@ -66,6 +71,12 @@ func (x *connCookie) Marshal() []byte {
 	copy(buf[idx:], x.chall[:])
 	idx += len(x.chall)
 
+	// the node id is prefixed with its length
+	buf[idx] = byte(len(x.nodeId))
+	idx++
+	copy(buf[idx:], x.nodeId)
+	idx += len(x.nodeId)
+
 	copy(buf[idx:], x.dest[:])
 	idx += len(x.dest)
 
@@ -85,6 +96,14 @@ func (x *connCookie) Unmarshal(buf []byte) {
 	copy(x.chall, buf[idx:idx+chacha20poly1305.NonceSizeX])
 	idx += chacha20poly1305.NonceSizeX
 
+	nodeIdLen := int(buf[idx])
+	idx++
+	if nodeIdLen > 0 {
+		x.nodeId = make([]byte, nodeIdLen)
+		copy(x.nodeId, buf[idx:idx+nodeIdLen])
+	}
+	idx += nodeIdLen
+
 	dest := make([]byte, len(buf[idx:]))
 	copy(dest, buf[idx:])
 
@@ -40,7 +40,7 @@ func TestCookie(test *testing.T) {
 		panic(err)
 	}
 
-	cookie := NewConnCookie(horizontalapi.ConnectionId("MIAMIbeach"))
+	cookie := NewConnCookie(horizontalapi.ConnectionId("MIAMIbeach"), []byte{0x01, 0x02, 0x03})
 	payload := cookie.CreateCookie(aead)
 	readCookie, err := ReadCookie(aead, payload)
 
@@ -60,6 +60,10 @@ func TestCookie(test *testing.T) {
 		test.Fatalf("Read dest different from dest (%s, %s)", readCookie.dest, cookie.dest)
 	}
 
+	if !reflect.DeepEqual(readCookie.nodeId, cookie.nodeId) {
+		test.Fatalf("Read node id different from node id (%x, %x)", readCookie.nodeId, cookie.nodeId)
+	}
+
 	nonce := ComputePoW(payload)
 	mypow := powMarsh{PowNonce: nonce, Cookie: payload}
 
@@ -0,0 +1,136 @@
+/*
+* gossip
+* Copyright (C) 2024 Fabio Gaiba and Lukas Heindl
+*
+* This program is free software: you can redistribute it and/or modify
+* it under the terms of the GNU General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* This program is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+* GNU General Public License for more details.
+*
+* You should have received a copy of the GNU General Public License
+* along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package strats
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	horizontalapi "gossip/horizontalAPI"
+)
+
+// length of the random identifier of a node (in bytes)
+const NODE_ID_LEN = 16
+
+// Draw a new random identifier for this node.
+//
+// The identifier is exchanged in the handshake of each connection (see
+// [admission.handle]) so that multiple connections to the same node (e.g. if
+// both nodes dialed each other) can be detected.
+func newNodeId() ([]byte, error) {
+	id := make([]byte, NODE_ID_LEN)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	return id, nil
+}
+
+// Decide which of two connections to the same node shall be closed.
+//
+// Only the node with the greater identifier closes a connection, so that the
+// two nodes never close both connections at the same time. It keeps the
+// connection dialed by the node with the smaller identifier (i.e. the
+// incoming one). If both connections were dialed by the same node, the one
+// which was known first (other) is kept.
+//
+// Returns nil if this node must not close any of the connections.
+func duplicateToClose(ownId []byte, conn *gossipConnection, other *gossipConnection) *gossipConnection {
+	if bytes.Compare(ownId, conn.nodeId) < 0 {
+		return nil
+	}
+	if !conn.outgoing && other.outgoing {
+		return other
+	}
+	return conn
+}
+
+// Hello message (extension of kind EXTENSION_HELLO), it is sent once the
+// connection is valid and tells the peer the address under which the node
+// accepts connections
+type helloMsg struct {
+	addr string
+}
+
+func (helloMsg) Kind() horizontalapi.ExtensionKind { return EXTENSION_HELLO }
+
+// the address is sent as is (ip:port)
+func (m helloMsg) MarshalBinary() ([]byte, error) {
+	return []byte(m.addr), nil
+}
+
+func decodeHello(data []byte) (horizontalapi.ExtensionMessage, error) {
+	return helloMsg{addr: string(data)}, nil
+}
+
+// Send the Hello message with the advertised address of this node once a
+// connection became valid. The subscribed gossip types are sent right after
+// it.
+func (dummy *dummyStrat) sendHello(conn horizontalapi.Conn[chan<- horizontalapi.ToHz]) {
+	send(conn, horizontalapi.Extension{Message: helloMsg{
+		addr: dummy.rootStrat.stratArgs.AdvertisedAddr(),
+	}})
+	send(conn, horizontalapi.Subscribe{GossipTypes: dummy.subscriptions})
+}
+
+// Close the connection to the peer if it is a connection to the node itself or
+// a duplicate connection to the same node. Should be called once the
+// connection became valid, only then the node id is bound to the PoW of the
+// peer.
+//
+// Returns whether the connection to the peer was closed.
+func (dummy *dummyStrat) closeDuplicates(peer *gossipConnection) bool {
+	if bytes.Equal(peer.nodeId, dummy.rootStrat.nodeId) {
+		dummy.rootStrat.log.Info("Closing connection to the node itself", "ConnId", peer.connection.Id)
+		dummy.closeDuplicate(peer)
+		return true
+	}
+
+	other, err := dummy.connManager.Duplicate(peer.connection.Id)
+	if err != nil || other == nil {
+		return false
+	}
+
+	toClose := duplicateToClose(dummy.rootStrat.nodeId, peer, other)
+	if toClose == nil {
+		dummy.rootStrat.log.Debug("Duplicate connection to node, the peer closes one of them", "ConnId", peer.connection.Id, "other ConnId", other.connection.Id, "nodeId", hex.EncodeToString(peer.nodeId))
+		return false
+	}
+	dummy.rootStrat.log.Info("Closing duplicate connection to node", "ConnId", toClose.connection.Id, "nodeId", hex.EncodeToString(peer.nodeId))
+	dummy.closeDuplicate(toClose)
+	return toClose == peer
+}
+
+// Handle the Hello message of a peer: the address of the peer is remembered so
+// that it can be connected to later on (see [dummyStrat.maintainOverlay]).
+func (dummy *dummyStrat) handleHello(peer *gossipConnection, msg helloMsg) {
+	addr := advertisedAddr(msg.addr, peer.connection.Id)
+	if err := dummy.connManager.SetAddr(peer.connection.Id, addr); err != nil {
+		// closed in the meantime
+		return
+	}
+	dummy.learnAddr(addr, true)
+}
+
+// Close a connection without affecting the reputation of the peer
+func (dummy *dummyStrat) closeDuplicate(peer *gossipConnection) {
+	if _, err := dummy.connManager.Remove(peer.connection.Id); err == nil {
+		peer.connection.Cfunc()
+	}
+	dummy.rootStrat.metrics.duplicateConns.Inc()
+}
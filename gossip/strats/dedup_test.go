@@ -0,0 +1,101 @@
+/*
+* gossip
+* Copyright (C) 2024 Fabio Gaiba and Lukas Heindl
+*
+* This program is free software: you can redistribute it and/or modify
+* it under the terms of the GNU General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* This program is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+* GNU General Public License for more details.
+*
+* You should have received a copy of the GNU General Public License
+* along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package strats
+
+import (
+	horizontalapi "gossip/horizontalAPI"
+	"testing"
+	"time"
+)
+
+func TestDuplicateToClose(test *testing.T) {
+	idA := []byte{0x01}
+	idB := []byte{0x02}
+	newConn := func(id string, nodeId []byte, outgoing bool) *gossipConnection {
+		return &gossipConnection{
+			connection: horizontalapi.Conn[chan<- horizontalapi.ToHz]{Id: horizontalapi.ConnectionId(id)},
+			nodeId:     nodeId,
+			outgoing:   outgoing,
+		}
+	}
+
+	// A and B dialed each other: conn1 was dialed by A, conn2 by B
+	atA1, atA2 := newConn("conn1", idB, true), newConn("conn2", idB, false)
+	atB1, atB2 := newConn("conn1", idA, false), newConn("conn2", idA, true)
+
+	// the node with the smaller id never closes a connection
+	if c := duplicateToClose(idA, atA1, atA2); c != nil {
+		test.Fatalf("Node with the smaller id should not close a connection but closed %v", c.connection.Id)
+	}
+	if c := duplicateToClose(idA, atA2, atA1); c != nil {
+		test.Fatalf("Node with the smaller id should not close a connection but closed %v", c.connection.Id)
+	}
+
+	// independent of the order the Hellos arrive in, the connection dialed by
+	// the node with the smaller id is kept
+	if c := duplicateToClose(idB, atB1, atB2); c != atB2 {
+		test.Fatalf("Node with the greater id should close conn2 but closed %v", c)
+	}
+	if c := duplicateToClose(idB, atB2, atB1); c != atB2 {
+		test.Fatalf("Node with the greater id should close conn2 but closed %v", c)
+	}
+
+	// both connections dialed by the same node -> keep the one known first
+	atB3 := newConn("conn3", idA, false)
+	if c := duplicateToClose(idB, atB3, atB1); c != atB3 {
+		test.Fatalf("The connection known first should be kept, but closed %v", c)
+	}
+
+	// only valid connections from the same host are duplicates
+	manager := NewConnectionManager(nil, nil)
+	for _, c := range []*gossipConnection{
+		newConn("10.0.0.1:1000", idA, false),
+		newConn("10.0.0.1:2000", idA, true),
+		newConn("[fd00::1]:3000", idA, false),
+		newConn("10.0.0.1:4000", idA, false),
+	} {
+		manager.AddInProgress(c)
+	}
+	manager.MakeValid("10.0.0.1:1000", time.Now())
+	if other, err := manager.Duplicate("10.0.0.1:1000"); err != nil || other != nil {
+		test.Fatalf("There should be no duplicate of the first connection (other: %v, err: %v)", other, err)
+	}
+	manager.MakeValid("10.0.0.1:2000", time.Now())
+	manager.MakeValid("[fd00::1]:3000", time.Now())
+	if other, err := manager.Duplicate("10.0.0.1:2000"); err != nil || other == nil || other.connection.Id != "10.0.0.1:1000" {
+		test.Fatalf("The first connection should be found as duplicate (other: %v, err: %v)", other, err)
+	}
+	if other, err := manager.Duplicate("[fd00::1]:3000"); err != nil || other != nil {
+		test.Fatalf("Connections from other hosts should not be duplicates (other: %v, err: %v)", other, err)
+	}
+	if _, err := manager.Duplicate("10.0.0.1:4000"); err == nil {
+		test.Fatalf("Looking for duplicates of a connection which is not valid should fail")
+	}
+	if err := manager.Identify("unknown", idA); err == nil {
+		test.Fatalf("Setting the node id of an unknown connection should fail")
+	}
+
+	// the node is still connected if only the duplicate connection is closed
+	if dup, _ := manager.Remove("10.0.0.1:2000"); !manager.StillConnected(dup) {
+		test.Fatalf("The node should still be connected after closing the duplicate connection")
+	}
+	if last, _ := manager.Remove("10.0.0.1:1000"); manager.StillConnected(last) {
+		test.Fatalf("The node should not be connected after closing its last connection from the host")
+	}
+}
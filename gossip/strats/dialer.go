@@ -0,0 +1,69 @@
+/*
+* gossip
+* Copyright (C) 2024 Fabio Gaiba and Lukas Heindl
+*
+* This program is free software: you can redistribute it and/or modify
+* it under the terms of the GNU General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* This program is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+* GNU General Public License for more details.
+*
+* You should have received a copy of the GNU General Public License
+* along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package strats
+
+import (
+	"net"
+)
+
+// Dials from a local address of the same family (IPv4/IPv6) as the address
+// which is dialed.
+type familyDialer struct {
+	v4 *net.Dialer
+	v6 *net.Dialer
+}
+
+// Connect to address, see [net.Dialer.Dial]
+func (d familyDialer) Dial(network string, address string) (net.Conn, error) {
+	tcpAddr, err := net.ResolveTCPAddr(network, address)
+	if err != nil {
+		return nil, err
+	}
+	if tcpAddr.IP.To4() != nil {
+		return d.v4.Dial(network, tcpAddr.String())
+	}
+	return d.v6.Dial(network, tcpAddr.String())
+}
+
+// dialer which is used to connect to new neighbors
+//
+// The local address of each family is taken from the addresses the node
+// listens on, so that peers see the connection coming from the same host. If
+// there is no such address (or it is unspecified like 0.0.0.0) the system
+// chooses the local address.
+func (strt *Strategy) dialer() (familyDialer, error) {
+	d := familyDialer{v4: &net.Dialer{}, v6: &net.Dialer{}}
+	for _, addr := range strt.stratArgs.HzAddrs() {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return d, err
+		}
+		ip := net.ParseIP(host)
+		if ip == nil || ip.IsUnspecified() {
+			continue
+		}
+		local := &net.TCPAddr{IP: ip, Port: 0}
+		if ip.To4() != nil && d.v4.LocalAddr == nil {
+			d.v4.LocalAddr = local
+		} else if ip.To4() == nil && d.v6.LocalAddr == nil {
+			d.v6.LocalAddr = local
+		}
+	}
+	return d, nil
+}
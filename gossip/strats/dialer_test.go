@@ -0,0 +1,56 @@
+/*
+* gossip
+* Copyright (C) 2024 Fabio Gaiba and Lukas Heindl
+*
+* This program is free software: you can redistribute it and/or modify
+* it under the terms of the GNU General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* This program is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+* GNU General Public License for more details.
+*
+* You should have received a copy of the GNU General Public License
+* along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package strats
+
+import (
+	"gossip/internal/args"
+	"net"
+	"testing"
+)
+
+func TestFamilyDialer(test *testing.T) {
+	a := args.NewFromDefaults()
+	a.Hz_addr = "0.0.0.0:6001,127.0.0.2:6001,[::1]:6001"
+	strategy := Strategy{stratArgs: a}
+	d, err := strategy.dialer()
+	if err != nil {
+		test.Fatalf("creating the dialer failed: %v", err)
+	}
+	if d.v4.LocalAddr.String() != "127.0.0.2:0" || d.v6.LocalAddr.String() != "[::1]:0" {
+		test.Fatalf("wrong local addresses: %v %v", d.v4.LocalAddr, d.v6.LocalAddr)
+	}
+
+	for _, addr := range []string{"127.0.0.1:0", "[::1]:0"} {
+		ln, err := net.Listen("tcp", addr)
+		if err != nil {
+			test.Logf("skipping %s: %v", addr, err)
+			continue
+		}
+		conn, err := d.Dial("tcp", ln.Addr().String())
+		if err != nil {
+			test.Fatalf("dialing %s failed: %v", ln.Addr(), err)
+		}
+		local := conn.LocalAddr().(*net.TCPAddr)
+		if (local.IP.To4() != nil) != (ln.Addr().(*net.TCPAddr).IP.To4() != nil) {
+			test.Errorf("dialed %s from %s of the wrong family", ln.Addr(), local)
+		}
+		conn.Close()
+		ln.Close()
+	}
+}
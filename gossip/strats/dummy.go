@@ -24,15 +24,13 @@ import (
 	"gossip/common"
 	horizontalapi "gossip/horizontalAPI"
 	ringbuffer "gossip/internal/ringbuffer"
-	pow "gossip/pow"
+	"gossip/internal/trace"
 	"reflect"
+	"slices"
 
-	"crypto/cipher"
 	"crypto/rand"
 	"math/big"
 	"time"
-
-	"golang.org/x/crypto/chacha20poly1305"
 )
 
 // define potential errors
@@ -41,20 +39,25 @@ var (
 )
 
 var (
-	POW_TIMEOUT      = 7 * time.Second
-	POW_REQUEST_TIME = 2 * time.Second
+	// how often the queues are checked while draining
+	DRAIN_INTERVAL = 100 * time.Millisecond
 )
 
 // Struct containing the Push messages for future expansion
 type storedMessage struct {
 	message horizontalapi.Push
+	// when the message was received (or announced)
+	timestamp time.Time
+	// set for messages which are only known to have been seen (e.g. restored
+	// from the message store without being validated)
+	unvalidated bool
 }
 
 // This struct contains all the fields used by the Dummy Strategy.
 type dummyStrat struct {
 	// The base strategy, which takes care of instantiating the HZ API and contains many common fields
 	rootStrat Strategy
-	// Channel where peer messages arrive
+	// Channel where peer messages arrive (passed on by the admission)
 	fromHz <-chan horizontalapi.FromHz
 	// Connection Manager object
 	connManager *ConnectionManager
@@ -65,34 +68,98 @@ type dummyStrat struct {
 	validMessages *ringbuffer.Ringbuffer[*storedMessage]
 	// Collection of messages already relayed to other peers
 	sentMessages *ringbuffer.Ringbuffer[*storedMessage]
-	// ChaCha20 cipher
-	cipher cipher.AEAD
+	// Admission of the peers via PoWs, it passes the messages of the peers
+	// on to fromHz
+	admission *admission
+	// Rate limiting of incoming and forwarded push messages
+	limiter *pushLimiter
+	// Policies choosing the peers messages are forwarded to (set per node,
+	// see [peerSelection])
+	selection *peerSelection
+	// Functions which need to be run on the goroutine of the strategy (e.g.
+	// to inspect its state)
+	admin chan func()
+	// Triggers the recurrent behavior (every GossipTimer), set by Listen
+	ticker *time.Ticker
+	// Fires at the end of the forward window while validated messages wait to
+	// be forwarded eagerly, nil otherwise (see [dummyStrat.forwardEagerly])
+	forwardC <-chan time.Time
+	// set while draining, no new messages are accepted and the rate limits
+	// for forwarding are ignored
+	draining bool
+	// Addresses of peers which can be connected to if there are too few
+	// connections (see maintainOverlay)
+	knownPeers map[string]*knownPeer
+	// How the validated messages are spread, the dummy strategy itself
+	// unless a strategy built on top of it replaces it (see [disseminator])
+	dissemination disseminator
+	// Announcements and requests of messages which are not pushed
+	lazyPush *lazyPushState
+	// Gossip types the modules are registered for, advertised to the peers
+	subscriptions []common.GossipType
+	// Handlers of the registered extension message kinds (see
+	// [dummyStrat.registerExtension])
+	extensions map[horizontalapi.ExtensionKind]extensionHandler
 }
 
-// Function to instantiate a new DummyStrategy.
+// The part of a strategy which decides how the validated messages are spread.
 //
-// strategy must be the baseStrategy. toBeProvedConnections a list of ToHz channels, one for each peer, that
-// current peer needs to send PoWs to
-func NewDummy(strategy Strategy, fromHz <-chan horizontalapi.FromHz, connManager *ConnectionManager) dummyStrat {
-	key := make([]byte, chacha20poly1305.KeySize)
-	if _, err := rand.Read(key); err != nil {
-		panic(err)
-	}
-
-	aead, err := chacha20poly1305.NewX(key)
-	if err != nil {
-		panic(err)
-	}
+// The other strategies are built on top of the dummy strategy, which takes
+// care of the admission of the peers, the caches, the membership and the lazy
+// push, and only replace (some of) these methods. The dummy strategy itself
+// pushes each message to Degree peers.
+type disseminator interface {
+	// Send a validated message to the peers. Returns false if the rate
+	// limits of the peers prevented sending it to any of them.
+	disseminate(msg *storedMessage) bool
+	// A new message was received from peer
+	receivedNew(peer *gossipConnection, msg horizontalapi.Push)
+	// A known message was received again from peer
+	receivedDuplicate(peer *gossipConnection, msg horizontalapi.Push)
+	// Recurrent behavior, run every gossip round before the valid messages
+	// are forwarded
+	gossipRound()
+	// Ask peer for the messages with msgIds which it announced but which did
+	// not arrive (see [dummyStrat.requestMissing])
+	requestFrom(peer *gossipConnection, msgIds []uint16)
+	// Whether announced messages are requested right away instead of waiting
+	// whether they arrive on their own
+	requestsAnnounced() bool
+	// Handle a message of the (valid) peer which only this strategy uses
+	// (e.g. Graft). Returns false if the strategy does not know the message.
+	handleMessage(peer *gossipConnection, msg horizontalapi.FromHz) bool
+}
 
-	return dummyStrat{
+// Function to instantiate a new DummyStrategy.
+//
+// strategy must be the baseStrategy. The messages from fromHz pass the
+// admission of the peers (see [admission]) before the strategy gets them.
+func NewDummy(strategy Strategy, fromHz <-chan horizontalapi.FromHz, connManager *ConnectionManager) *dummyStrat {
+	admission := newAdmission(strategy, fromHz, connManager)
+	dummy := &dummyStrat{
 		rootStrat:       strategy,
-		fromHz:          fromHz,
+		fromHz:          admission.admitted,
 		connManager:     connManager,
 		invalidMessages: ringbuffer.NewRingbuffer[*storedMessage](strategy.stratArgs.Cache_size),
 		validMessages:   ringbuffer.NewRingbuffer[*storedMessage](strategy.stratArgs.Cache_size),
 		sentMessages:    ringbuffer.NewRingbuffer[*storedMessage](strategy.stratArgs.Cache_size),
-		cipher:          aead,
+		admission:       admission,
+		limiter:         newPushLimiter(strategy.log, strategy.stratArgs),
+		admin:           make(chan func()),
+		knownPeers:      make(map[string]*knownPeer),
+		lazyPush:        newLazyPushState(strategy.stratArgs),
+		extensions:      make(map[horizontalapi.ExtensionKind]extensionHandler),
+	}
+	for _, addr := range append(slices.Clone(strategy.stratArgs.Peer_addrs), strategy.stratArgs.Bootstrapper) {
+		if addr != "" {
+			dummy.knownPeers[addr] = &knownPeer{configured: true}
+		}
 	}
+	dummy.registerBuiltinExtensions()
+	dummy.dissemination = dummy
+	dummy.selection = newPeerSelection(strategy.stratArgs, strategy.reputation, dummy.limiter)
+	dummy.restore()
+	return dummy
 }
 
 // Listen for messages incoming on either StrategyChannels (from the base strategy, such as the
@@ -101,36 +168,44 @@ func NewDummy(strategy Strategy, fromHz <-chan horizontalapi.FromHz, connManager
 // This function spawn a new goroutine. Incoming messages will be processed by the Dummy Strategy.
 func (dummy *dummyStrat) Listen() {
 	go func() {
-		// Sending out initial challenges requests
-		dummy.connManager.ActionOnToBeProved(func(x *gossipConnection) {
-			req := horizontalapi.ConnReq{}
-			x.connection.Data <- req
-		})
+		// The handshake with the peers is done by the admission
+		go dummy.admission.run()
 		// A repeating signal to trigger a recurrent behavior.
-		ticker := time.NewTicker(time.Duration(dummy.rootStrat.stratArgs.GossipTimer) * time.Second)
-		// A repeating signal for the renewing of connections
-		renewalTicker := time.NewTicker(POW_REQUEST_TIME)
-		// A repeating signal for the checking (and culling) all open connections
-		timeoutTicker := time.NewTicker(POW_TIMEOUT)
+		dummy.ticker = time.NewTicker(dummy.rootStrat.stratArgs.GossipTimer)
+		// A repeating signal for checking the amount of connections
+		overlayTicker := time.NewTicker(OVERLAY_INTERVAL)
+		// A repeating signal for exchanging known peers (nil if disabled)
+		var shuffleC <-chan time.Time
+		if dummy.rootStrat.stratArgs.Shuffle_interval > 0 {
+			shuffleTicker := time.NewTicker(time.Duration(dummy.rootStrat.stratArgs.Shuffle_interval) * time.Second)
+			defer shuffleTicker.Stop()
+			shuffleC = shuffleTicker.C
+		}
+		// A repeating signal for pinging the peers (nil if disabled)
+		var pingC <-chan time.Time
+		if dummy.rootStrat.stratArgs.Ping_interval > 0 {
+			pingTicker := time.NewTicker(dummy.rootStrat.stratArgs.Ping_interval)
+			defer pingTicker.Stop()
+			pingC = pingTicker.C
+		}
 
 		// Keep listening on all channels
 		for {
 			select {
-			// Message received from a peer.
+			// Message received from a (valid) peer.
 			case x := <-dummy.fromHz:
-				switch msg := x.(type) {
-				case horizontalapi.Unregister:
-					peer, err := dummy.connManager.Remove(horizontalapi.ConnectionId(msg))
-					if err == nil {
-						// now after removing the peer from all internal datastructures it is safe to fully close it
-						peer.connection.Cfunc()
-					}
+				peer, isValid := dummy.connManager.FindValid(messageSender(x))
+				if !isValid {
+					dummy.rootStrat.log.Debug("HZ Message dropped since the connection was closed in the meantime", "type", reflect.TypeOf(x), "Peer ID", messageSender(x))
+					continue
+				}
 
+				switch msg := x.(type) {
 				case horizontalapi.Push:
-					_, isValid := dummy.connManager.FindValid(msg.Id)
-
-					if !isValid {
-						dummy.rootStrat.log.Warn("PUSH message not processed because peer was not PoW valid", "Peer ID", msg.Id)
+					// Each peer may only send a limited amount of messages
+					if !dummy.limiter.allowIncomingPeer(msg) {
+						dummy.rootStrat.log.Debug("PUSH message dropped because of the rate limit of the peer", "Peer ID", msg.Id)
+						dummy.rootStrat.traceEvent(trace.Dropped, msg, &peer.connection)
 						continue
 					}
 
@@ -142,255 +217,343 @@ func (dummy *dummyStrat) Listen() {
 					// If the message was not already received, move it to the invalidMessages
 					// and send a notification to vert API
 					if err1 != nil && err2 != nil && err3 != nil {
-						dummy.invalidMessages.Insert(&storedMessage{msg})
+						// New messages would only prolong the drain
+						if dummy.draining {
+							dummy.rootStrat.log.Debug("PUSH message dropped because the strategy is draining", "Peer ID", msg.Id)
+							dummy.rootStrat.traceEvent(trace.Dropped, msg, &peer.connection)
+							continue
+						}
+						// Only a limited amount of new messages per gossip
+						// type is passed on to the modules
+						if !dummy.limiter.allowIncomingType(msg) {
+							dummy.rootStrat.log.Debug("PUSH message dropped because of the rate limit of the gossip type", "Peer ID", msg.Id, "type", msg.GossipType)
+							dummy.rootStrat.traceEvent(trace.Dropped, msg, &peer.connection)
+							continue
+						}
+						stored := &storedMessage{message: msg, timestamp: time.Now()}
+						dummy.invalidMessages.Insert(stored)
+						dummy.persist(messageSeen, stored)
 						dummy.rootStrat.log.Log(context.Background(), common.LevelTest, "received", "msgId", notification.MessageId, "msgType", notification.DataType)
 						dummy.rootStrat.strategyChannels.FromStrat <- notification
 						dummy.rootStrat.log.Debug("HZ Message received:", "type", reflect.TypeOf(msg), "Message", msg)
+						dummy.rootStrat.metrics.received.Inc(typeLabel(msg.GossipType))
+						dummy.rootStrat.traceEvent(trace.Received, msg, &peer.connection)
+						dummy.dissemination.receivedNew(peer, msg)
+						dummy.forgetMissing(msg.MessageID)
+					} else {
+						dummy.rootStrat.metrics.duplicate.Inc(typeLabel(msg.GossipType))
+						dummy.rootStrat.traceEvent(trace.Duplicate, msg, &peer.connection)
+						dummy.dissemination.receivedDuplicate(peer, msg)
 					}
 
-				case horizontalapi.ConnReq:
-					// Create ConnChall message with the encrypted cookie
-					cookie := NewConnCookie(msg.Id)
+				case horizontalapi.IHave:
+					dummy.handleIHave(peer, msg)
 
-					peer, IsInProgress := dummy.connManager.FindInProgress(msg.Id)
+				case horizontalapi.IWant:
+					dummy.handleIWant(peer, msg)
 
-					if !IsInProgress {
-						dummy.rootStrat.log.Warn("ConnReq received from a connection not present in the inProgress connections", "ConnId", msg.Id)
-						continue
-					}
-
-					m := horizontalapi.ConnChall{
-						Id:     msg.Id,
-						Cookie: cookie.CreateCookie(dummy.cipher),
-					}
+				case horizontalapi.Subscribe:
+					dummy.handleSubscribe(peer, msg)
 
-					peer.connection.Data <- m
+				case horizontalapi.Extension:
+					dummy.handleExtension(peer, msg)
 
-				case horizontalapi.ConnChall:
-					// Checks weather the Chall is coming from a toBeProvedConnection
-					peer, isToBeProved := dummy.connManager.FindToBeProved(msg.Id)
-					if !isToBeProved {
-						dummy.rootStrat.log.Warn("ConnChall received from a not toBeProved connection", "ConnId", msg.Id)
-						dummy.connManager.Remove(msg.Id)
-						continue
+				default:
+					// e.g. Graft and Prune
+					if !dummy.dissemination.handleMessage(peer, msg) {
+						dummy.rootStrat.log.Debug("HZ Message ignored since the strategy does not use it", "type", reflect.TypeOf(msg))
 					}
+				}
 
+				// Message from the vertical API
+			case x := <-dummy.rootStrat.strategyChannels.ToStrat:
+				switch x := x.(type) {
+				case common.GossipAnnounce:
+					pushMsg := convertAnnounceToPush(x)
+					dummy.rootStrat.log.Log(context.Background(), common.LevelTest, "announce", "msgId", pushMsg.MessageID, "msgType", pushMsg.GossipType)
+					// We consider Announce messages automatically valid
+					stored := &storedMessage{message: pushMsg, timestamp: time.Now()}
+					dummy.validMessages.Insert(stored)
+					dummy.persist(messageValid, stored)
+					dummy.rootStrat.metrics.announced.Inc(typeLabel(pushMsg.GossipType))
+					dummy.rootStrat.traceEvent(trace.Announced, pushMsg, nil)
+					dummy.forwardEagerly()
+				case common.GossipSubscriptions:
+					dummy.updateSubscriptions(x.DataTypes)
+				case common.GossipCatchupRequest:
+					reply := common.GossipCatchupReply{
+						Notifications: dummy.catchup(x.Data),
+						Module:        x.Module,
+					}
+					// don't block the strategy while main is busy
 					go func() {
-						nonce := ComputePoW(msg.Cookie)
-						pow := horizontalapi.ConnPoW{PowNonce: nonce, Cookie: msg.Cookie}
 						select {
-						case <-peer.connection.Ctx.Done():
-						// connection was already closed in the meantime
-						default:
-							peer.connection.Data <- pow
-							dummy.connManager.MakeValid(peer.connection.Id, time.Now())
+						case dummy.rootStrat.strategyChannels.FromStrat <- reply:
+						case <-dummy.rootStrat.ctx.Done():
 						}
 					}()
+				case common.GossipValidation:
+					dummy.handleValidation(x)
+				}
 
-				// Checks incoming PoWs
-				case horizontalapi.ConnPoW:
-					_, connValidty := dummy.connManager.FindInProgress(msg.Id)
-					if !connValidty {
-						dummy.rootStrat.log.Warn("ConnPow received was from a connection which is not actually in Progress", "ConnId", msg.Id)
-						dummy.connManager.Remove(msg.Id)
-						continue
-					}
-
-					mypow := powMarsh{PowNonce: msg.PowNonce, Cookie: msg.Cookie}
-					cookieRead, err := ReadCookie(dummy.cipher, mypow.Cookie)
-
-					if err != nil {
-						dummy.rootStrat.log.Warn("Failed to decrypt cookie, dropping connection", "ConnId", msg.Id)
-						dummy.connManager.Remove(msg.Id)
-						continue
-					}
-
-					// Check proof of work
-					powValidity := pow.CheckProofOfWork(func(digest []byte) bool {
-						return pow.First8bits0(digest)
-					}, &mypow)
-
-					if !powValidity {
-						dummy.rootStrat.log.Warn("Invalid pow, dropping connection", "ConnId", msg.Id)
-						dummy.connManager.Remove(msg.Id)
-						continue
-					}
-
-					// check if dest is valid
-					if cookieRead.dest != msg.Id {
-						dummy.rootStrat.log.Warn("Mismatched connectionId between received connPow and sender", "expected conn Id", cookieRead.dest, "ConnId", msg.Id)
-						dummy.connManager.Remove(msg.Id)
-						continue
-					}
-
-					// check if time taken for giving pow is within the limits
-					diff := time.Now().Sub(cookieRead.timestamp)
-
-					if diff > POW_TIMEOUT {
-						dummy.rootStrat.log.Info("POW for accepting connection was given not within the time limit", "expected conn Id", cookieRead.dest, "ConnId", msg.Id)
-						dummy.connManager.Remove(msg.Id)
-						continue
-					}
+				// Recurrent timer signal
+			case <-dummy.ticker.C:
+				dummy.dissemination.gossipRound()
+				dummy.forwardValid()
+				dummy.requestMissing()
+				dummy.rootStrat.metrics.queueDepth.Set(float64(dummy.invalidMessages.Len()), "invalid")
+				dummy.rootStrat.metrics.queueDepth.Set(float64(dummy.validMessages.Len()), "valid")
+				dummy.rootStrat.metrics.queueDepth.Set(float64(dummy.sentMessages.Len()), "sent")
+				if err := dummy.rootStrat.messageStore.Sync(); err != nil {
+					dummy.rootStrat.log.Warn("Syncing the message store failed", "err", err)
+				}
+				if err := dummy.rootStrat.tracer.Flush(); err != nil {
+					dummy.rootStrat.log.Warn("Flushing the trace failed", "err", err)
+				}
 
-					dummy.connManager.MakeValid(msg.Id, cookieRead.timestamp)
+				// End of the forward window
+			case <-dummy.forwardC:
+				dummy.forwardC = nil
+				dummy.forwardValid()
 
-				case horizontalapi.PowReq:
-					// Create PowChall message with the encrypted cookie
-					cookie := NewConnCookie(msg.Id)
+			case ev := <-dummy.admission.events:
+				dummy.handleAdmission(ev)
 
-					peer, isValid := dummy.connManager.FindValid(msg.Id)
-					if !isValid {
-						dummy.rootStrat.log.Warn("Id not found in the connection manager", "ConnId", msg.Id)
-						continue
-					}
+			case <-overlayTicker.C:
+				if !dummy.draining {
+					dummy.maintainOverlay()
+				}
 
-					m := horizontalapi.PowChall{
-						Id:     msg.Id,
-						Cookie: cookie.CreateCookie(dummy.cipher),
-					}
+			case <-shuffleC:
+				dummy.startShuffle()
 
-					peer.connection.Data <- m
+			case <-pingC:
+				dummy.sendPings()
 
-				case horizontalapi.PowChall:
-					// Checks weather the Chall is from an openConnection (renewal)
-					peer, isValid := dummy.connManager.FindValid(msg.Id)
+			case f := <-dummy.admin:
+				f()
 
-					if !isValid {
-						dummy.rootStrat.log.Warn("PowChall received from a not valid connection", "ConnId", msg.Id)
-						dummy.connManager.Remove(msg.Id)
-						continue
-					}
+			case <-dummy.rootStrat.ctx.Done():
+				// should terminate
+				return
+			}
+		}
+	}()
+}
 
-					// Check if the there was a Req sent
-					if !peer.sentPowReq {
-						dummy.rootStrat.log.Warn("PowChall received but no PowReq was sent, possible DoS", "ConnId", msg.Id)
-						dummy.connManager.Remove(msg.Id)
-						continue
-					}
+// React to the events of the admission: greet valid peers, forget the state
+// of closed connections and replace failed connections of active peers
+func (dummy *dummyStrat) handleAdmission(ev admissionEvent) {
+	switch ev := ev.(type) {
+	case peerValid:
+		dummy.rootStrat.log.Debug("Peer admitted", "ConnId", ev.id)
+		if peer, isValid := dummy.connManager.FindValid(ev.id); isValid && !dummy.closeDuplicates(peer) {
+			dummy.sendHello(peer.connection)
+		}
+	case peerClosed:
+		dummy.limiter.forget(ev.id)
+		dummy.forgetAnnounced(ev.id)
+		// the node is still an active peer if only a duplicate connection
+		// to it was closed, replacing it would just dial it again
+		if ev.wasValid && !ev.duplicate {
+			dummy.replacePeer()
+		}
+	}
+}
 
-					go func() {
-						nonce := ComputePoW(msg.Cookie)
-						pow := horizontalapi.PowPoW{PowNonce: nonce, Cookie: msg.Cookie}
-						select {
-						case <-peer.connection.Ctx.Done():
-						// connection was already closed in the meantime
-						default:
-							peer.sentPowReq = false
-							peer.connection.Data <- pow
-						}
-					}()
+// Record a reputation event for the peer with connection id (see
+// [admission.rate]), a connection closed because of it is handled right away
+func (dummy *dummyStrat) ratePeer(id horizontalapi.ConnectionId, event reputationEvent) {
+	if ev := dummy.admission.rate(id, event); ev != nil {
+		dummy.handleAdmission(ev)
+	}
+}
 
-				case horizontalapi.PowPoW:
-					// Checks weather the PoW is from an openConnection (renewal)
-					_, connValidty := dummy.connManager.FindValid(msg.Id)
-					if !connValidty {
-						dummy.rootStrat.log.Warn("PoWPoW received was from a connection which is not actually valid", "ConnId", msg.Id)
-						dummy.connManager.Remove(msg.Id)
-						continue
-					}
+// Drop the connection id because the peer misbehaved (see [admission.drop]),
+// the closed connection is handled right away
+func (dummy *dummyStrat) dropPeer(id horizontalapi.ConnectionId, event reputationEvent) {
+	if ev := dummy.admission.drop(id, event); ev != nil {
+		dummy.handleAdmission(ev)
+	}
+}
 
-					mypow := powMarsh{PowNonce: msg.PowNonce, Cookie: msg.Cookie}
-					cookieRead, err := ReadCookie(dummy.cipher, mypow.Cookie)
+// Handle the verdict of the modules on a received message: valid messages
+// are forwarded, invalid ones are dropped and the peer they were received
+// from is rated accordingly
+func (dummy *dummyStrat) handleValidation(x common.GossipValidation) {
+	msg, err := findFirstMessage(dummy.invalidMessages, x.MessageId)
+	dummy.invalidMessages.Remove(msg)
 
-					if err != nil {
-						dummy.rootStrat.log.Warn("Failed to decrypt cookie, dropping connection", "ConnId", msg.Id)
-						dummy.connManager.Remove(msg.Id)
-						continue
-					}
+	if err != nil {
+		dummy.rootStrat.log.Warn("Tried to validate a message which did not exists", "Message ID", x.MessageId)
+		return
+	}
 
-					// Check proof of work
-					powValidity := pow.CheckProofOfWork(func(digest []byte) bool {
-						return pow.First8bits0(digest)
-					}, &mypow)
+	// msg.message.Id is the connection the message was received on
+	if x.Unhandled {
+		// no module is registered for the type, this is no fault of the
+		// peer
+		dummy.rootStrat.log.Debug("Dropping message no module is registered for", "Message ID", x.MessageId, "GossipType", msg.message.GossipType)
+	} else if !x.Valid {
+		dummy.ratePeer(msg.message.Id, reputationInvalidMessage)
+		dummy.rootStrat.metrics.invalid.Inc(typeLabel(msg.message.GossipType))
+		dummy.rootStrat.traceEvent(trace.Invalid, msg.message, nil)
+	} else {
+		dummy.ratePeer(msg.message.Id, reputationValidMessage)
+		dummy.rootStrat.metrics.valid.Inc(typeLabel(msg.message.GossipType))
+		dummy.rootStrat.traceEvent(trace.Validated, msg.message, nil)
+		if msg.message.TTL == 1 {
+			dummy.sentMessages.Insert(msg)
+			dummy.persist(messageSent, msg)
+		} else {
+			msg.message.TTL = max(msg.message.TTL-1, 0)
+
+			dummy.validMessages.Insert(msg)
+			dummy.persist(messageValid, msg)
+			dummy.forwardEagerly()
+		}
+	}
+}
 
-					if !powValidity {
-						dummy.rootStrat.log.Warn("Invalid pow, dropping connection", "ConnId", msg.Id)
-						dummy.connManager.Remove(msg.Id)
-						continue
-					}
+// Forward the messages in the valid queue to the peers.
+//
+// The messages are taken round-robin per gossip type so that a single busy
+// gossip type cannot starve the others. Messages exceeding the rate limit of
+// their gossip type stay in the valid queue for the next round.
+func (dummy *dummyStrat) forwardValid() {
+	queues := make(map[common.GossipType][]*storedMessage)
+	types := make([]common.GossipType, 0)
+	for _, msg := range dummy.validMessages.ExtractToSlice() {
+		gtype := msg.message.GossipType
+		if _, ok := queues[gtype]; !ok {
+			types = append(types, gtype)
+		}
+		queues[gtype] = append(queues[gtype], msg)
+	}
+	// use a fixed order for the round-robin
+	slices.Sort(types)
+
+	for len(types) > 0 {
+		remaining := types[:0]
+		for _, gtype := range types {
+			if !dummy.draining && !dummy.limiter.allowOutgoingType(gtype) {
+				// budget of this type is used up for this round
+				continue
+			}
+			dummy.sendValid(queues[gtype][0])
+			queues[gtype] = queues[gtype][1:]
+			if len(queues[gtype]) > 0 {
+				remaining = append(remaining, gtype)
+			}
+		}
+		types = remaining
+	}
+	dummy.flushAnnouncements()
+}
 
-					// check if dest is valid
-					if cookieRead.dest != msg.Id {
-						dummy.rootStrat.log.Warn("Mismatched connectionId between received connPow and sender", "expected conn Id", cookieRead.dest, "ConnId", msg.Id)
-						dummy.connManager.Remove(msg.Id)
-						continue
-					}
+// Forward the valid messages without waiting for the next gossip round if
+// Eager_forward is set.
+//
+// The messages are forwarded right away or, if Forward_window is set, at the
+// end of the window together with the messages validated in the meantime.
+// Messages held back by the rate limits are forwarded in the next gossip
+// round.
+func (dummy *dummyStrat) forwardEagerly() {
+	if !dummy.rootStrat.stratArgs.Eager_forward || dummy.forwardC != nil {
+		return
+	}
+	if dummy.rootStrat.stratArgs.Forward_window == 0 {
+		dummy.forwardValid()
+		return
+	}
+	dummy.forwardC = time.After(dummy.rootStrat.stratArgs.Forward_window)
+}
 
-					dummy.connManager.MakeValid(msg.Id, cookieRead.timestamp)
+// Send a message to the peers (see [disseminator]) and move it from the
+// valid queue to the sent messages
+func (dummy *dummyStrat) sendValid(msg *storedMessage) {
+	if !dummy.dissemination.disseminate(msg) {
+		// stays in the valid queue for the next round
+		dummy.rootStrat.log.Debug("HZ Message kept since the rate limits of all selected peers are exceeded", "Message", msg)
+		return
+	}
 
-				case horizontalapi.NewConn:
-					// Accept any connection and put it in the inProgress slice.
-					conn := gossipConnection{
-						connection: horizontalapi.Conn[chan<- horizontalapi.ToHz](msg),
-					}
-					dummy.connManager.AddInProgress(&conn)
-				}
+	dummy.validMessages.Remove(msg)
+	dummy.sentMessages.Insert(msg)
+	dummy.persist(messageSent, msg)
+}
 
-				// Message from the vertical API
-			case x := <-dummy.rootStrat.strategyChannels.ToStrat:
-				switch x := x.(type) {
-				case common.GossipAnnounce:
-					pushMsg := convertAnnounceToPush(x)
-					dummy.rootStrat.log.Log(context.Background(), common.LevelTest, "announce", "msgId", pushMsg.MessageID, "msgType", pushMsg.GossipType)
-					// We consider Announce messages automatically valid
-					dummy.validMessages.Insert(&storedMessage{pushMsg})
-				case common.GossipValidation:
-					msg, err := findFirstMessage(dummy.invalidMessages, x.MessageId)
-					dummy.invalidMessages.Remove(msg)
+// Push a message to Degree peers chosen by the policy of its gossip type (or
+// announce it, see [dummyStrat.lazyPushFor])
+func (dummy *dummyStrat) disseminate(msg *storedMessage) bool {
+	lazy := dummy.lazyPushFor(msg.message)
+	selected, sent := 0, 0
+	dummy.connManager.ActionOnSelectedValid(dummy.selection.forType(msg.message.GossipType), func(peer *gossipConnection) {
+		selected++
+		if lazy {
+			dummy.announce(peer, msg.message.MessageID)
+			sent++
+		} else if dummy.push(peer, msg) {
+			sent++
+		}
+	}, int(dummy.rootStrat.stratArgs.Degree))
+	return selected == 0 || sent > 0
+}
 
-					if err != nil {
-						dummy.rootStrat.log.Warn("Tried to validate a message which did not exists", "Message ID", x.MessageId)
-						break
-					}
+// The dummy strategy does not care where messages come from
+func (dummy *dummyStrat) receivedNew(peer *gossipConnection, msg horizontalapi.Push) {}
 
-					if x.Valid {
-						if msg.message.TTL == 1 {
-							dummy.sentMessages.Insert(msg)
-						} else {
-							msg.message.TTL = max(msg.message.TTL-1, 0)
+func (dummy *dummyStrat) receivedDuplicate(peer *gossipConnection, msg horizontalapi.Push) {}
 
-							dummy.validMessages.Insert(msg)
-						}
-					}
-				}
+// The dummy strategy forwards the valid messages only
+func (dummy *dummyStrat) gossipRound() {}
 
-				// Recurrent timer signal
-			case <-ticker.C:
-				validMessages := dummy.validMessages.ExtractToSlice()
-
-				// For each message in the valid queue, send it to peers and remove it
-				for _, msg := range validMessages {
-					dummy.connManager.ActionOnPermutedValid(func(peer *gossipConnection) {
-						peer.connection.Data <- msg.message
-						dummy.rootStrat.log.Debug("HZ Message sent:", "dst", peer.connection.Id, "Message", msg)
-					}, int(dummy.rootStrat.stratArgs.Degree))
-
-					dummy.validMessages.Remove(msg)
-					dummy.sentMessages.Insert(msg)
-				}
+// The dummy strategy does not use any messages of its own
+func (dummy *dummyStrat) handleMessage(peer *gossipConnection, msg horizontalapi.FromHz) bool {
+	return false
+}
 
-			case <-renewalTicker.C:
-				dummy.connManager.ActionOnValid(func(x *gossipConnection) {
-					req := horizontalapi.PowReq{}
-					x.sentPowReq = true
-					x.connection.Data <- req
-				})
+// Push a message to peer unless the rate limit of the peer is exceeded.
+// Returns whether the message was sent.
+func (dummy *dummyStrat) push(peer *gossipConnection, msg *storedMessage) bool {
+	if !dummy.draining && !dummy.limiter.allowOutgoingPeer(peer.connection.Id) {
+		dummy.rootStrat.log.Debug("HZ Message not sent because of the rate limit of the peer", "dst", peer.connection.Id, "Message", msg)
+		return false
+	}
+	send(peer.connection, msg.message)
+	peer.lastUsed = time.Now()
+	dummy.rootStrat.log.Debug("HZ Message sent:", "dst", peer.connection.Id, "Message", msg)
+	dummy.rootStrat.metrics.forwarded.Inc(typeLabel(msg.message.GossipType))
+	dummy.rootStrat.traceEvent(trace.Forwarded, msg.message, &peer.connection)
+	return true
+}
 
-			case <-timeoutTicker.C:
-				dummy.connManager.CullConnections(isConnectionInvalid)
+// Collect the validated messages requested by a catchup request, sorted by
+// the time they were received (oldest first).
+//
+// Only messages still present in the cache can be returned.
+func (dummy *dummyStrat) catchup(req common.GossipCatchup) []common.GossipNotification {
+	deadline := time.Time{}
+	if req.MaxAge != 0 {
+		deadline = time.Now().Add(-time.Duration(req.MaxAge) * time.Second)
+	}
+	matches := func(m *storedMessage) bool {
+		return !m.unvalidated && m.message.GossipType == req.DataType && m.timestamp.After(deadline)
+	}
 
-			case <-dummy.rootStrat.ctx.Done():
-				// should terminate
-				return
-			}
-		}
-	}()
-}
+	msgs := append(dummy.sentMessages.Filter(matches), dummy.validMessages.Filter(matches)...)
+	slices.SortFunc(msgs, func(a, b *storedMessage) int {
+		return a.timestamp.Compare(b.timestamp)
+	})
+	if req.Count != 0 && len(msgs) > int(req.Count) {
+		msgs = msgs[len(msgs)-int(req.Count):]
+	}
 
-// Returns weather the connection is valid or not
-func isConnectionInvalid(peer *gossipConnection) bool {
-	diff := time.Now().Sub(peer.timestamp)
-	return !(diff < POW_TIMEOUT)
+	ret := make([]common.GossipNotification, 0, len(msgs))
+	for _, m := range msgs {
+		ret = append(ret, convertPushToNotification(m.message))
+	}
+	return ret
 }
 
 // Go through a ringbuffer of messages and return the one with a matching ID, error if none is found
@@ -427,7 +590,38 @@ func convertPushToNotification(pushMsg horizontalapi.Push) common.GossipNotifica
 	return notification
 }
 
+// Drain the strategy before closing it.
+//
+// New messages from peers are dropped from now on. The valid messages are
+// forwarded right away (ignoring the rate limits) until no message is waiting
+// for its validation or to be forwarded anymore.
+func (dummy *dummyStrat) Drain(ctx context.Context) error {
+	ticker := time.NewTicker(DRAIN_INTERVAL)
+	defer ticker.Stop()
+	for {
+		var pending uint
+		err := dummy.runInLoop(func() {
+			dummy.draining = true
+			dummy.forwardValid()
+			pending = dummy.invalidMessages.Len() + dummy.validMessages.Len()
+		})
+		if err != nil {
+			return err
+		}
+		if pending == 0 {
+			return nil
+		}
+		dummy.rootStrat.log.Debug("Draining", "pending", pending)
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
 // Close the root strategy
 func (dummy *dummyStrat) Close() {
 	dummy.rootStrat.Close()
+	dummy.limiter.finalize()
 }
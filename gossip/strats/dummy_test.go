@@ -0,0 +1,289 @@
+/*
+* gossip
+* Copyright (C) 2024 Fabio Gaiba and Lukas Heindl
+*
+* This program is free software: you can redistribute it and/or modify
+* it under the terms of the GNU General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* This program is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+* GNU General Public License for more details.
+*
+* You should have received a copy of the GNU General Public License
+* along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package strats
+
+import (
+	"context"
+	"errors"
+	"gossip/common"
+	horizontalapi "gossip/horizontalAPI"
+	"gossip/internal/args"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/neilotoole/slogt"
+)
+
+func TestCatchup(test *testing.T) {
+	a := args.NewFromDefaults()
+	dummy := NewDummy(Strategy{stratArgs: a, log: slogt.New(test), metrics: newStratMetrics(nil)}, nil, nil)
+
+	now := time.Now()
+	insert := func(id uint16, gtype common.GossipType, age time.Duration) *storedMessage {
+		return &storedMessage{
+			message:   horizontalapi.Push{MessageID: id, GossipType: gtype},
+			timestamp: now.Add(-age),
+		}
+	}
+	dummy.sentMessages.Insert(insert(1, 42, 3*time.Minute))
+	dummy.sentMessages.Insert(insert(2, 42, 2*time.Minute))
+	dummy.sentMessages.Insert(insert(3, 7, time.Minute))
+	dummy.validMessages.Insert(insert(4, 42, time.Second))
+	unvalidated := insert(5, 42, time.Second)
+	unvalidated.unvalidated = true
+	dummy.sentMessages.Insert(unvalidated)
+
+	ids := func(ns []common.GossipNotification) []uint16 {
+		ret := make([]uint16, 0, len(ns))
+		for _, n := range ns {
+			ret = append(ret, n.MessageId)
+		}
+		return ret
+	}
+
+	if got := ids(dummy.catchup(common.GossipCatchup{DataType: 42})); len(got) != 3 || got[0] != 1 || got[1] != 2 || got[2] != 4 {
+		test.Fatalf("catchup without limits returned wrong messages: %v", got)
+	}
+	if got := ids(dummy.catchup(common.GossipCatchup{DataType: 42, Count: 1})); len(got) != 1 || got[0] != 4 {
+		test.Fatalf("catchup with count returned wrong messages: %v", got)
+	}
+	if got := ids(dummy.catchup(common.GossipCatchup{DataType: 42, MaxAge: 150})); len(got) != 2 || got[0] != 2 || got[1] != 4 {
+		test.Fatalf("catchup with max age returned wrong messages: %v", got)
+	}
+}
+
+func TestDrain(test *testing.T) {
+	a := args.NewFromDefaults()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	connManager := NewConnectionManager(nil, nil)
+	dummy := NewDummy(Strategy{ctx: ctx, cancel: cancel, stratArgs: a, log: slogt.New(test), metrics: newStratMetrics(nil)}, nil, &connManager)
+
+	dummy.validMessages.Insert(&storedMessage{message: horizontalapi.Push{MessageID: 1, GossipType: 42}, timestamp: time.Now()})
+	dummy.Listen()
+
+	// the valid message is forwarded right away
+	if err := dummy.Drain(context.Background()); err != nil {
+		test.Fatalf("draining failed: %v", err)
+	}
+	var sent bool
+	if err := dummy.runInLoop(func() {
+		_, err := findFirstMessage(dummy.sentMessages, 1)
+		sent = err == nil
+	}); err != nil || !sent {
+		test.Fatalf("valid message was not forwarded while draining")
+	}
+
+	// a message which is never validated keeps the drain from finishing
+	if err := dummy.runInLoop(func() {
+		dummy.invalidMessages.Insert(&storedMessage{message: horizontalapi.Push{MessageID: 2, GossipType: 42}, timestamp: time.Now()})
+	}); err != nil {
+		test.Fatalf("running in the loop failed: %v", err)
+	}
+	dctx, dcancel := context.WithTimeout(context.Background(), 3*DRAIN_INTERVAL)
+	defer dcancel()
+	if err := dummy.Drain(dctx); !errors.Is(err, context.DeadlineExceeded) {
+		test.Fatalf("draining should have timed out, got: %v", err)
+	}
+
+	cancel()
+	if err := dummy.Drain(context.Background()); !errors.Is(err, ErrTerminated) {
+		test.Fatalf("draining a terminated strategy should fail, got: %v", err)
+	}
+}
+
+func TestReload(test *testing.T) {
+	a := args.NewFromDefaults()
+	a.Peer_addrs = []string{"10.0.0.2:6001"}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	connManager := NewConnectionManager(nil, nil)
+	dummy := NewDummy(Strategy{ctx: ctx, cancel: cancel, stratArgs: a, log: slogt.New(test), metrics: newStratMetrics(nil)}, nil, &connManager)
+
+	// the configured peer connected to this node
+	closed := make(chan struct{})
+	connManager.AddInProgress(&gossipConnection{connection: horizontalapi.Conn[chan<- horizontalapi.ToHz]{Id: "10.0.0.2:50000", Cfunc: func() { close(closed) }}, addr: "10.0.0.2:6001"})
+	connManager.MakeValid("10.0.0.2:50000", time.Now())
+
+	for i := uint16(0); i < 10; i++ {
+		dummy.sentMessages.Insert(&storedMessage{message: horizontalapi.Push{MessageID: i, GossipType: 42}, timestamp: time.Now()})
+	}
+	dummy.Listen()
+
+	b := a
+	b.Degree = 3
+	b.Cache_size = 4
+	b.GossipTimer = 2 * time.Second
+	b.Peer_addrs = nil
+	// must not be applied
+	b.Hz_addr = "127.0.0.1:1"
+	if err := dummy.Reload(b); err != nil {
+		test.Fatalf("reloading failed: %v", err)
+	}
+
+	// the removed peer is found by its advertised address
+	select {
+	case <-closed:
+	default:
+		test.Fatalf("connection of the removed peer was not closed")
+	}
+
+	if err := dummy.runInLoop(func() {
+		got := dummy.rootStrat.stratArgs
+		if got.Degree != 3 || got.Cache_size != 4 || got.GossipTimer != 2*time.Second {
+			test.Errorf("options were not applied: %+v", got)
+		}
+		if got.Hz_addr != a.Hz_addr {
+			test.Errorf("option which cannot be changed was applied")
+		}
+		if dummy.sentMessages.Len() != 4 {
+			test.Errorf("cache was not resized, contains %v messages", dummy.sentMessages.Len())
+		}
+	}); err != nil {
+		test.Fatalf("running in the loop failed: %v", err)
+	}
+}
+
+func TestEagerForward(test *testing.T) {
+	a := args.NewFromDefaults()
+	// the gossip round never comes
+	a.GossipTimer = time.Hour
+	a.Eager_forward = true
+	a.Forward_window = 100 * time.Millisecond
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	connManager := NewConnectionManager(nil, nil)
+	dummy := NewDummy(Strategy{ctx: ctx, cancel: cancel, stratArgs: a, log: slogt.New(test), metrics: newStratMetrics(nil)}, nil, &connManager)
+
+	peer := make(chan horizontalapi.ToHz, 8)
+	connManager.AddInProgress(&gossipConnection{connection: horizontalapi.Conn[chan<- horizontalapi.ToHz]{Id: "a", Data: peer, Ctx: context.Background()}})
+	connManager.MakeValid("a", time.Now())
+	dummy.Listen()
+
+	validate := func(id uint16) {
+		test.Helper()
+		if err := dummy.runInLoop(func() {
+			dummy.validMessages.Insert(&storedMessage{message: horizontalapi.Push{MessageID: id, GossipType: 42}, timestamp: time.Now()})
+			dummy.forwardEagerly()
+		}); err != nil {
+			test.Fatalf("running in the loop failed: %v", err)
+		}
+	}
+
+	// both messages are forwarded together at the end of the window
+	validate(1)
+	validate(2)
+	select {
+	case msg := <-peer:
+		test.Fatalf("%+v was forwarded before the end of the window", msg)
+	default:
+	}
+	forwarded := make(map[uint16]bool)
+	for len(forwarded) < 2 {
+		select {
+		case msg := <-peer:
+			forwarded[msg.(horizontalapi.Push).MessageID] = true
+		case <-time.After(time.Second):
+			test.Fatalf("only %v were forwarded", forwarded)
+		}
+	}
+	if !forwarded[1] || !forwarded[2] {
+		test.Fatalf("wrong messages were forwarded: %v", forwarded)
+	}
+
+	// without a window messages are forwarded right away
+	b := a
+	b.Forward_window = 0
+	if err := dummy.Reload(b); err != nil {
+		test.Fatalf("reloading failed: %v", err)
+	}
+	validate(3)
+	select {
+	case msg := <-peer:
+		if msg.(horizontalapi.Push).MessageID != 3 {
+			test.Fatalf("forwarded %+v instead of message 3", msg)
+		}
+	default:
+		test.Fatalf("message was not forwarded right away")
+	}
+}
+
+func TestForwardRateLimited(test *testing.T) {
+	a := args.NewFromDefaults()
+	a.GossipTimer = time.Hour
+	a.Out_peer_rate = 0.001
+	a.Out_peer_burst = 1
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	connManager := NewConnectionManager(nil, nil)
+	dummy := NewDummy(Strategy{ctx: ctx, cancel: cancel, stratArgs: a, log: slogt.New(test), metrics: newStratMetrics(nil)}, nil, &connManager)
+
+	peer := make(chan horizontalapi.ToHz, 8)
+	connManager.AddInProgress(&gossipConnection{connection: horizontalapi.Conn[chan<- horizontalapi.ToHz]{Id: "a", Data: peer, Ctx: context.Background()}})
+	connManager.MakeValid("a", time.Now())
+	dummy.validMessages.Insert(&storedMessage{message: horizontalapi.Push{MessageID: 1, GossipType: 42}, timestamp: time.Now()})
+	dummy.validMessages.Insert(&storedMessage{message: horizontalapi.Push{MessageID: 2, GossipType: 42}, timestamp: time.Now()})
+	dummy.Listen()
+
+	// the only peer may receive one message, the other one stays valid
+	if err := dummy.runInLoop(func() {
+		dummy.forwardValid()
+		if dummy.sentMessages.Len() != 1 || dummy.validMessages.Len() != 1 {
+			test.Errorf("%v messages were sent and %v kept, want 1 and 1", dummy.sentMessages.Len(), dummy.validMessages.Len())
+		}
+	}); err != nil {
+		test.Fatalf("running in the loop failed: %v", err)
+	}
+	if len(peer) != 1 {
+		test.Fatalf("%v messages were forwarded to the peer", len(peer))
+	}
+}
+
+func TestUnhandledValidation(test *testing.T) {
+	a := args.NewFromDefaults()
+	a.Ban_file = filepath.Join(test.TempDir(), "bans.json")
+	book, err := newReputationBook(slogt.New(test), a)
+	if err != nil {
+		test.Fatalf("Creating the reputation book failed: %v", err)
+	}
+	connManager := NewConnectionManager(nil, book)
+	dummy := NewDummy(Strategy{stratArgs: a, log: slogt.New(test), metrics: newStratMetrics(nil), reputation: book}, nil, &connManager)
+	receive := func(id uint16) {
+		dummy.invalidMessages.Insert(&storedMessage{message: horizontalapi.Push{Id: "10.0.0.1:1234", MessageID: id, GossipType: 42, TTL: 3}, timestamp: time.Now()})
+	}
+
+	// no module is registered for the type: the message is dropped, but the
+	// peer is not blamed for it
+	receive(1)
+	dummy.handleValidation(common.GossipValidation{MessageId: 1, Unhandled: true})
+	if dummy.invalidMessages.Len() != 0 || dummy.validMessages.Len() != 0 {
+		test.Fatalf("unhandled message was kept")
+	}
+	if score := book.scores["10.0.0.1"]; score != 0 {
+		test.Fatalf("peer was rated %v for an unhandled message", score)
+	}
+
+	// the verdict of a module is held against the peer
+	receive(2)
+	dummy.handleValidation(common.GossipValidation{MessageId: 2})
+	if score := book.scores["10.0.0.1"]; score >= 0 {
+		test.Fatalf("peer was rated %v for an invalid message", score)
+	}
+}
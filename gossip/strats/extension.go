@@ -0,0 +1,97 @@
+/*
+* gossip
+* Copyright (C) 2024 Fabio Gaiba and Lukas Heindl
+*
+* This program is free software: you can redistribute it and/or modify
+* it under the terms of the GNU General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* This program is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+* GNU General Public License for more details.
+*
+* You should have received a copy of the GNU General Public License
+* along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package strats
+
+import (
+	horizontalapi "gossip/horizontalAPI"
+)
+
+// Kinds of the extension messages the strategies use themselves. The kinds are
+// part of the protocol, so a kind must never be reused for another message.
+const (
+	EXTENSION_PING          horizontalapi.ExtensionKind = 1
+	EXTENSION_PONG          horizontalapi.ExtensionKind = 2
+	EXTENSION_FEEDBACK      horizontalapi.ExtensionKind = 3
+	EXTENSION_HELLO         horizontalapi.ExtensionKind = 4
+	EXTENSION_SHUFFLE       horizontalapi.ExtensionKind = 5
+	EXTENSION_SHUFFLE_REPLY horizontalapi.ExtensionKind = 6
+)
+
+// Handles a received extension message of the kind it was registered for. It
+// runs on the goroutine of the strategy (like all other handlers) and is only
+// called for valid peers.
+type extensionHandler func(peer *gossipConnection, msg horizontalapi.ExtensionMessage)
+
+// Register a new kind of horizontal messages. Received messages of this kind
+// are decoded with decode and passed on to handle.
+//
+// Must be called before [dummyStrat.Listen] (e.g. in the function
+// instantiating the strategy). Messages of the kind can be sent by wrapping
+// them in a [horizontalapi.Extension].
+func (dummy *dummyStrat) registerExtension(kind horizontalapi.ExtensionKind, decode horizontalapi.ExtensionDecoder, handle extensionHandler) error {
+	// tests which call the handlers directly don't use a horizontal api
+	if dummy.rootStrat.hz != nil {
+		if err := dummy.rootStrat.hz.RegisterExtension(kind, decode); err != nil {
+			return err
+		}
+	}
+	dummy.extensions[kind] = handle
+	return nil
+}
+
+// Register an extension message the strategy uses itself (see
+// [dummyStrat.registerExtension]). Panics if the kind is registered already,
+// the strategy would not work without it.
+func (dummy *dummyStrat) mustRegisterExtension(kind horizontalapi.ExtensionKind, decode horizontalapi.ExtensionDecoder, handle extensionHandler) {
+	if err := dummy.registerExtension(kind, decode, handle); err != nil {
+		panic(err)
+	}
+}
+
+// Register the extension messages which all strategies use
+func (dummy *dummyStrat) registerBuiltinExtensions() {
+	dummy.mustRegisterExtension(EXTENSION_PING, decodePing, func(peer *gossipConnection, msg horizontalapi.ExtensionMessage) {
+		dummy.handlePing(peer, msg.(pingMsg))
+	})
+	dummy.mustRegisterExtension(EXTENSION_PONG, decodePong, func(peer *gossipConnection, msg horizontalapi.ExtensionMessage) {
+		dummy.handlePong(peer, msg.(pongMsg))
+	})
+	dummy.mustRegisterExtension(EXTENSION_HELLO, decodeHello, func(peer *gossipConnection, msg horizontalapi.ExtensionMessage) {
+		dummy.handleHello(peer, msg.(helloMsg))
+	})
+	dummy.mustRegisterExtension(EXTENSION_SHUFFLE, decodeShuffle, func(peer *gossipConnection, msg horizontalapi.ExtensionMessage) {
+		dummy.handleShuffle(peer, msg.(shuffleMsg))
+	})
+	dummy.mustRegisterExtension(EXTENSION_SHUFFLE_REPLY, decodeShuffleReply, func(peer *gossipConnection, msg horizontalapi.ExtensionMessage) {
+		dummy.handleShuffleReply(peer, msg.(shuffleReplyMsg))
+	})
+}
+
+// Pass the extension message on to the handler registered for its kind
+func (dummy *dummyStrat) handleExtension(peer *gossipConnection, msg horizontalapi.Extension) {
+	handle, ok := dummy.extensions[msg.Message.Kind()]
+	if !ok {
+		// the decoder is registered together with the handler, so this is
+		// only reached with a message of another kind than the decoder
+		// was registered for
+		dummy.rootStrat.log.Warn("Extension received without a handler", "ConnId", msg.Id, "kind", msg.Message.Kind())
+		return
+	}
+	handle(peer, msg.Message)
+}
@@ -0,0 +1,64 @@
+/*
+* gossip
+* Copyright (C) 2024 Fabio Gaiba and Lukas Heindl
+*
+* This program is free software: you can redistribute it and/or modify
+* it under the terms of the GNU General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* This program is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+* GNU General Public License for more details.
+*
+* You should have received a copy of the GNU General Public License
+* along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package strats
+
+import (
+	"context"
+	horizontalapi "gossip/horizontalAPI"
+	"gossip/internal/args"
+	"testing"
+	"time"
+
+	"github.com/neilotoole/slogt"
+)
+
+// extension message used for testing
+type pingExtension struct{}
+
+func (pingExtension) Kind() horizontalapi.ExtensionKind { return 7 }
+func (pingExtension) MarshalBinary() ([]byte, error)    { return nil, nil }
+
+func TestExtension(test *testing.T) {
+	log := slogt.New(test)
+	connManager := NewConnectionManager(nil, nil)
+	strategy := Strategy{hz: horizontalapi.NewHorizontalApi(log, nil), stratArgs: args.NewFromDefaults(), log: log, metrics: newStratMetrics(nil)}
+	defer strategy.hz.Close()
+	dummy := NewDummy(strategy, nil, &connManager)
+
+	var handled []horizontalapi.ConnectionId
+	decode := func([]byte) (horizontalapi.ExtensionMessage, error) { return pingExtension{}, nil }
+	handle := func(peer *gossipConnection, msg horizontalapi.ExtensionMessage) {
+		handled = append(handled, peer.connection.Id)
+	}
+	if err := dummy.registerExtension(7, decode, handle); err != nil {
+		test.Fatalf("registering the extension failed with %v", err)
+	}
+	if err := dummy.registerExtension(7, decode, handle); err == nil {
+		test.Fatalf("registering the extension twice succeeded")
+	}
+
+	connManager.AddInProgress(&gossipConnection{connection: horizontalapi.Conn[chan<- horizontalapi.ToHz]{Id: "a", Ctx: context.Background()}})
+	connManager.MakeValid("a", time.Now())
+
+	peer, _ := connManager.FindValid("a")
+	dummy.handleExtension(peer, horizontalapi.Extension{Id: "a", Message: pingExtension{}})
+	if len(handled) != 1 || handled[0] != "a" {
+		test.Fatalf("handled extensions of %v instead of [a]", handled)
+	}
+}
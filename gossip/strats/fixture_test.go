@@ -0,0 +1,96 @@
+/*
+* gossip
+* Copyright (C) 2024 Fabio Gaiba and Lukas Heindl
+*
+* This program is free software: you can redistribute it and/or modify
+* it under the terms of the GNU General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* This program is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+* GNU General Public License for more details.
+*
+* You should have received a copy of the GNU General Public License
+* along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package strats
+
+import (
+	"context"
+	horizontalapi "gossip/horizontalAPI"
+	"gossip/internal/args"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/neilotoole/slogt"
+)
+
+// Strategy with the arguments a which logs to the test
+func testStrategy(test *testing.T, a args.Args) Strategy {
+	return Strategy{stratArgs: a, log: slogt.New(test), metrics: newStratMetrics(nil)}
+}
+
+// Valid peers of the strategy under test. Instead of a real connection each
+// peer has a buffered channel which collects the messages sent to it.
+type testPeers struct {
+	test        *testing.T
+	connManager *ConnectionManager
+	data        map[horizontalapi.ConnectionId]chan horizontalapi.ToHz
+}
+
+// Add valid peers with ids to connManager
+func newTestPeers(test *testing.T, connManager *ConnectionManager, ids ...horizontalapi.ConnectionId) *testPeers {
+	peers := &testPeers{test: test, connManager: connManager, data: make(map[horizontalapi.ConnectionId]chan horizontalapi.ToHz)}
+	for _, id := range ids {
+		peers.data[id] = make(chan horizontalapi.ToHz, 8)
+		connManager.AddInProgress(&gossipConnection{connection: horizontalapi.Conn[chan<- horizontalapi.ToHz]{Id: id, Data: peers.data[id], Ctx: context.Background()}})
+		connManager.MakeValid(id, time.Now())
+	}
+	return peers
+}
+
+// The connection of peer id
+func (peers *testPeers) valid(id horizontalapi.ConnectionId) *gossipConnection {
+	peer, _ := peers.connManager.FindValid(id)
+	return peer
+}
+
+// Check that want is the next message sent to peer id (nil: that nothing was
+// sent)
+func (peers *testPeers) expect(id horizontalapi.ConnectionId, want horizontalapi.ToHz) {
+	peers.test.Helper()
+	select {
+	case got := <-peers.data[id]:
+		if !reflect.DeepEqual(got, want) {
+			peers.test.Fatalf("%s received %+v instead of %+v", id, got, want)
+		}
+	default:
+		if want != nil {
+			peers.test.Fatalf("%s did not receive %+v", id, want)
+		}
+	}
+}
+
+// All messages sent to peer id which were not checked yet
+func (peers *testPeers) received(id horizontalapi.ConnectionId) []horizontalapi.ToHz {
+	var ret []horizontalapi.ToHz
+	for {
+		select {
+		case x := <-peers.data[id]:
+			ret = append(ret, x)
+		default:
+			return ret
+		}
+	}
+}
+
+// Put msgs in the valid queue of dummy
+func insertValid(dummy *dummyStrat, msgs ...horizontalapi.Push) {
+	for _, msg := range msgs {
+		dummy.validMessages.Insert(&storedMessage{message: msg, timestamp: time.Now()})
+	}
+}
@@ -0,0 +1,176 @@
+/*
+* gossip
+* Copyright (C) 2024 Fabio Gaiba and Lukas Heindl
+*
+* This program is free software: you can redistribute it and/or modify
+* it under the terms of the GNU General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* This program is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+* GNU General Public License for more details.
+*
+* You should have received a copy of the GNU General Public License
+* along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package strats
+
+import (
+	"errors"
+	"gossip/common"
+	horizontalapi "gossip/horizontalAPI"
+	"math"
+	"slices"
+)
+
+// The gossipsub strategy (after the GossipSub protocol of libp2p) takes the
+// gossip types into account: each node advertises the types its modules are
+// registered for (Subscribe) to its peers. Messages of a type are only sent
+// to peers subscribed to that type, since the other peers would drop them
+// anyway (the message is marked invalid if no module is registered).
+//
+// For each subscribed type the node keeps a mesh of up to Degree subscribed
+// peers. Messages are pushed to the members of the mesh of their type and
+// only announced (IHave) to the remaining subscribed peers, which request
+// them (IWant) if they do not arrive via their own mesh.
+//
+// Different from GossipSub, the meshes are not negotiated with the peers
+// (no GRAFT/PRUNE), each node picks the members of its meshes on its own.
+
+// This struct contains the fields used by the GossipSub Strategy in addition
+// to the ones of the Dummy Strategy.
+type gossipSubStrat struct {
+	*dummyStrat
+	// members of the mesh of each subscribed gossip type
+	meshes map[common.GossipType][]horizontalapi.ConnectionId
+}
+
+// Function to instantiate a new GossipSub Strategy.
+//
+// Takes the same arguments as [NewDummy].
+func NewGossipSub(strategy Strategy, fromHz <-chan horizontalapi.FromHz, connManager *ConnectionManager) *gossipSubStrat {
+	gs := &gossipSubStrat{
+		dummyStrat: NewDummy(strategy, fromHz, connManager),
+		meshes:     make(map[common.GossipType][]horizontalapi.ConnectionId),
+	}
+	gs.dissemination = gs
+	return gs
+}
+
+// Record the gossip types the peer with connection id is subscribed to
+func (manager *ConnectionManager) Subscribe(id horizontalapi.ConnectionId, gtypes []common.GossipType) error {
+	manager.connMutex.Lock()
+	defer manager.connMutex.Unlock()
+
+	peer, ok := manager.unsafeFind(id)
+	if !ok {
+		return errors.New("No element found when setting the subscriptions")
+	}
+	peer.subscriptions = gtypes
+	return nil
+}
+
+// Whether the peer might be interested in messages of gtype. Peers which
+// did not advertise their subscriptions are assumed to be interested in all
+// types.
+func (peer *gossipConnection) interestedIn(gtype common.GossipType) bool {
+	return peer.subscriptions == nil || slices.Contains(peer.subscriptions, gtype)
+}
+
+// Handle the Subscribe message of a peer
+func (dummy *dummyStrat) handleSubscribe(peer *gossipConnection, msg horizontalapi.Subscribe) {
+	gtypes := slices.Clone(msg.GossipTypes)
+	if gtypes == nil {
+		gtypes = []common.GossipType{}
+	}
+	slices.Sort(gtypes)
+	gtypes = slices.Compact(gtypes)
+	if err := dummy.connManager.Subscribe(msg.Id, gtypes); err != nil {
+		// closed in the meantime
+		return
+	}
+	dummy.rootStrat.log.Debug("Peer subscribed", "ConnId", msg.Id, "types", gtypes)
+}
+
+// Advertise the gossip types the modules are registered for to all peers if
+// they changed
+func (dummy *dummyStrat) updateSubscriptions(gtypes []common.GossipType) {
+	if slices.Equal(gtypes, dummy.subscriptions) {
+		return
+	}
+	dummy.subscriptions = gtypes
+	msg := horizontalapi.Subscribe{GossipTypes: gtypes}
+	advertise := func(x *gossipConnection) {
+		send(x.connection, msg)
+	}
+	dummy.connManager.ActionOnToBeProved(advertise)
+	dummy.connManager.ActionOnInProgress(advertise)
+	dummy.connManager.ActionOnValid(advertise)
+}
+
+// Announced messages are only requested if they don't arrive via the mesh
+func (gs *gossipSubStrat) requestsAnnounced() bool {
+	return false
+}
+
+// Remove the meshes of types which are not subscribed anymore and update the
+// meshes of the subscribed types (see [gossipSubStrat.refreshMesh])
+func (gs *gossipSubStrat) gossipRound() {
+	for gtype := range gs.meshes {
+		if !slices.Contains(gs.subscriptions, gtype) {
+			delete(gs.meshes, gtype)
+		}
+	}
+	for _, gtype := range gs.subscriptions {
+		gs.refreshMesh(gtype)
+	}
+}
+
+// Remove the peers from the mesh of gtype which are gone or not subscribed
+// anymore and fill it up to Degree subscribed peers chosen by the selection
+// policy of gtype.
+//
+// Returns the members of the mesh.
+func (gs *gossipSubStrat) refreshMesh(gtype common.GossipType) []horizontalapi.ConnectionId {
+	mesh := slices.DeleteFunc(gs.meshes[gtype], func(id horizontalapi.ConnectionId) bool {
+		peer, isValid := gs.connManager.FindValid(id)
+		return !isValid || !peer.interestedIn(gtype)
+	})
+	missing := int(gs.rootStrat.stratArgs.Degree) - len(mesh)
+	if missing > 0 {
+		gs.connManager.ActionOnSelectedValid(gs.selection.forType(gtype), func(peer *gossipConnection) {
+			if missing > 0 && peer.interestedIn(gtype) && !slices.Contains(mesh, peer.connection.Id) {
+				mesh = append(mesh, peer.connection.Id)
+				missing--
+			}
+		}, math.MaxInt)
+	}
+	gs.meshes[gtype] = mesh
+	return mesh
+}
+
+// Push msg to the mesh of its gossip type and announce it to the other
+// subscribed peers. The peer the message was received from is skipped.
+func (gs *gossipSubStrat) disseminate(msg *storedMessage) bool {
+	gtype := msg.message.GossipType
+	// the mesh is refreshed outside of ActionOnValid, it locks the
+	// connection manager itself
+	mesh := gs.refreshMesh(gtype)
+	selected, sent := 0, 0
+	gs.connManager.ActionOnValid(func(peer *gossipConnection) {
+		if peer.connection.Id == msg.message.Id || !peer.interestedIn(gtype) {
+			return
+		}
+		selected++
+		if !slices.Contains(mesh, peer.connection.Id) {
+			gs.announce(peer, msg.message.MessageID)
+			sent++
+		} else if gs.push(peer, msg) {
+			sent++
+		}
+	})
+	return selected == 0 || sent > 0
+}
@@ -0,0 +1,110 @@
+/*
+* gossip
+* Copyright (C) 2024 Fabio Gaiba and Lukas Heindl
+*
+* This program is free software: you can redistribute it and/or modify
+* it under the terms of the GNU General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* This program is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+* GNU General Public License for more details.
+*
+* You should have received a copy of the GNU General Public License
+* along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package strats
+
+import (
+	"gossip/common"
+	horizontalapi "gossip/horizontalAPI"
+	"gossip/internal/args"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestGossipSub(test *testing.T) {
+	a := args.NewFromDefaults()
+	a.Degree = 2
+	connManager := NewConnectionManager(nil, nil)
+	dummy := NewGossipSub(testStrategy(test, a), nil, &connManager)
+
+	// legacy never advertises its subscriptions
+	ids := []horizontalapi.ConnectionId{"sender", "a", "b", "other", "legacy"}
+	peers := newTestPeers(test, &connManager, ids...)
+	valid, received := peers.valid, peers.received
+	for _, id := range []horizontalapi.ConnectionId{"sender", "a", "b"} {
+		dummy.handleSubscribe(valid(id), horizontalapi.Subscribe{Id: id, GossipTypes: []common.GossipType{42, 7, 42}})
+	}
+	dummy.handleSubscribe(valid("other"), horizontalapi.Subscribe{Id: "other", GossipTypes: []common.GossipType{7}})
+
+	// the subscriptions are advertised to all peers once
+	dummy.updateSubscriptions([]common.GossipType{42})
+	dummy.updateSubscriptions([]common.GossipType{42})
+	for _, id := range ids {
+		want := []horizontalapi.ToHz{horizontalapi.Subscribe{GossipTypes: []common.GossipType{42}}}
+		if got := received(id); !reflect.DeepEqual(got, want) {
+			test.Fatalf("%s received %+v instead of %+v", id, got, want)
+		}
+	}
+
+	// pushed to the mesh, announced to the other subscribed peers
+	msg := horizontalapi.Push{Id: "sender", MessageID: 1, GossipType: 42, TTL: 3}
+	insertValid(dummy.dummyStrat, msg)
+	dummy.forwardValid()
+	mesh := dummy.meshes[42]
+	if len(mesh) != 2 || slices.Contains(mesh, "other") {
+		test.Fatalf("wrong mesh %v", mesh)
+	}
+	if got := received("sender"); got != nil {
+		test.Fatalf("sender received %+v", got)
+	}
+	if got := received("other"); got != nil {
+		test.Fatalf("unsubscribed peer received %+v", got)
+	}
+	pushed := 0
+	var announcedTo horizontalapi.ConnectionId
+	for _, id := range []horizontalapi.ConnectionId{"a", "b", "legacy"} {
+		got := received(id)
+		switch {
+		case reflect.DeepEqual(got, []horizontalapi.ToHz{msg}):
+			pushed++
+			if !slices.Contains(mesh, id) {
+				test.Fatalf("%s got the message pushed without being in the mesh", id)
+			}
+		case reflect.DeepEqual(got, []horizontalapi.ToHz{horizontalapi.IHave{MessageIds: []uint16{1}}}):
+			announcedTo = id
+		default:
+			test.Fatalf("%s received %+v", id, got)
+		}
+	}
+	// the sender might be part of the mesh
+	if pushed < 1 || pushed > 2 {
+		test.Fatalf("message was pushed to %d peers", pushed)
+	}
+
+	// requested messages are sent
+	dummy.handleIWant(valid(announcedTo), horizontalapi.IWant{Id: announcedTo, MessageIds: []uint16{1, 2}})
+	if got := received(announcedTo); !reflect.DeepEqual(got, []horizontalapi.ToHz{msg}) {
+		test.Fatalf("%s received %+v instead of the requested message", announcedTo, got)
+	}
+
+	// peers which unsubscribed are removed from the mesh
+	dummy.handleSubscribe(valid("a"), horizontalapi.Subscribe{Id: "a", GossipTypes: []common.GossipType{}})
+	dummy.handleSubscribe(valid("b"), horizontalapi.Subscribe{Id: "b"})
+	dummy.gossipRound()
+	if mesh := dummy.meshes[42]; !slices.Equal(mesh, []horizontalapi.ConnectionId{"sender", "legacy"}) && !slices.Equal(mesh, []horizontalapi.ConnectionId{"legacy", "sender"}) {
+		test.Fatalf("wrong mesh after unsubscribing %v", mesh)
+	}
+
+	// meshes of types which are not subscribed anymore are removed
+	dummy.updateSubscriptions([]common.GossipType{7})
+	dummy.gossipRound()
+	if _, ok := dummy.meshes[42]; ok {
+		test.Fatalf("mesh of an unsubscribed type was kept")
+	}
+}
@@ -0,0 +1,130 @@
+/*
+* gossip
+* Copyright (C) 2024 Fabio Gaiba and Lukas Heindl
+*
+* This program is free software: you can redistribute it and/or modify
+* it under the terms of the GNU General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* This program is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+* GNU General Public License for more details.
+*
+* You should have received a copy of the GNU General Public License
+* along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package strats
+
+import (
+	"encoding/binary"
+	"fmt"
+	horizontalapi "gossip/horizontalAPI"
+	mrand "math/rand"
+	"time"
+)
+
+// Each valid peer is pinged every Ping_interval. Besides the round trip time
+// (kept in the connection manager) this makes sure that something is sent on
+// quiet connections, so that the horizontal api only closes connections of
+// peers which really vanished (see [horizontalapi.HorizontalApi.SetIdleTimeout]).
+
+var (
+	// how many unanswered Pings per peer are remembered, so that a Pong which
+	// arrives after the next Ping was sent is still measured
+	PING_MAX_OUTSTANDING = 4
+)
+
+// Ping message (extension of kind EXTENSION_PING). The receiver answers with
+// a Pong carrying the same nonce.
+type pingMsg struct {
+	nonce uint64
+}
+
+func (pingMsg) Kind() horizontalapi.ExtensionKind { return EXTENSION_PING }
+func (m pingMsg) MarshalBinary() ([]byte, error) {
+	return binary.BigEndian.AppendUint64(nil, m.nonce), nil
+}
+
+// the keepalive is control traffic, it is never delayed by the egress limits
+func (pingMsg) IsControl() bool { return true }
+
+// Pong message (extension of kind EXTENSION_PONG) answering the Ping with the
+// same nonce
+type pongMsg struct {
+	nonce uint64
+}
+
+func (pongMsg) Kind() horizontalapi.ExtensionKind { return EXTENSION_PONG }
+func (m pongMsg) MarshalBinary() ([]byte, error) {
+	return binary.BigEndian.AppendUint64(nil, m.nonce), nil
+}
+func (pongMsg) IsControl() bool { return true }
+
+// Decode the nonce of a Ping or Pong
+func decodeNonce(data []byte) (uint64, error) {
+	if len(data) != 8 {
+		return 0, fmt.Errorf("nonce has %d bytes instead of 8", len(data))
+	}
+	return binary.BigEndian.Uint64(data), nil
+}
+
+func decodePing(data []byte) (horizontalapi.ExtensionMessage, error) {
+	nonce, err := decodeNonce(data)
+	return pingMsg{nonce: nonce}, err
+}
+
+func decodePong(data []byte) (horizontalapi.ExtensionMessage, error) {
+	nonce, err := decodeNonce(data)
+	return pongMsg{nonce: nonce}, err
+}
+
+// Send a Ping to each valid peer. The oldest unanswered Ping is forgotten if
+// there are PING_MAX_OUTSTANDING already, a late Pong to it is ignored.
+func (dummy *dummyStrat) sendPings() {
+	dummy.connManager.ActionOnValid(func(x *gossipConnection) {
+		nonce := mrand.Uint64()
+		if !send(x.connection, horizontalapi.Extension{Message: pingMsg{nonce: nonce}}) {
+			return
+		}
+		// stamped once the writer of the connection took the Ping (control
+		// messages are sent right away), waiting for a busy writer is not
+		// part of the round trip time
+		if x.pings == nil {
+			x.pings = make(map[uint64]time.Time)
+		}
+		if len(x.pings) >= PING_MAX_OUTSTANDING {
+			var oldest uint64
+			var oldestSent time.Time
+			for n, sent := range x.pings {
+				if oldestSent.IsZero() || sent.Before(oldestSent) {
+					oldest, oldestSent = n, sent
+				}
+			}
+			delete(x.pings, oldest)
+		}
+		x.pings[nonce] = time.Now()
+	})
+}
+
+// Answer the Ping of a peer
+func (dummy *dummyStrat) handlePing(peer *gossipConnection, msg pingMsg) {
+	send(peer.connection, horizontalapi.Extension{Message: pongMsg{nonce: msg.nonce}})
+}
+
+// Record the round trip time of the answered Ping
+func (dummy *dummyStrat) handlePong(peer *gossipConnection, msg pongMsg) {
+	id := peer.connection.Id
+	sent, ok := peer.pings[msg.nonce]
+	if !ok {
+		dummy.rootStrat.log.Debug("Ignoring Pong which does not answer an outstanding Ping", "ConnId", id)
+		return
+	}
+	sample := time.Since(sent)
+	delete(peer.pings, msg.nonce)
+	rtt, _ := dummy.connManager.UpdateRTT(id, sample)
+	dummy.rootStrat.metrics.rtt.Observe(sample.Seconds())
+	dummy.rootStrat.log.Debug("Measured RTT", "ConnId", id, "sample", sample, "rtt", rtt)
+}
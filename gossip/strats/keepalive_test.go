@@ -0,0 +1,90 @@
+/*
+* gossip
+* Copyright (C) 2024 Fabio Gaiba and Lukas Heindl
+*
+* This program is free software: you can redistribute it and/or modify
+* it under the terms of the GNU General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* This program is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+* GNU General Public License for more details.
+*
+* You should have received a copy of the GNU General Public License
+* along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package strats
+
+import (
+	horizontalapi "gossip/horizontalAPI"
+	"gossip/internal/args"
+	"testing"
+	"time"
+)
+
+func TestKeepalive(test *testing.T) {
+	connManager := NewConnectionManager(nil, nil)
+	dummy := NewDummy(testStrategy(test, args.NewFromDefaults()), nil, &connManager)
+	peers := newTestPeers(test, &connManager, "a")
+	valid := peers.valid
+	peer := peers.data["a"]
+	nextPing := func() pingMsg {
+		return (<-peer).(horizontalapi.Extension).Message.(pingMsg)
+	}
+
+	// the nonce survives the encoding
+	data, _ := pingMsg{nonce: 1 << 63}.MarshalBinary()
+	if m, err := decodePing(data); err != nil || m != (pingMsg{nonce: 1 << 63}) {
+		test.Fatalf("decoded %+v (err %v) instead of the ping", m, err)
+	}
+	if _, err := decodePong(data[1:]); err == nil {
+		test.Fatalf("decoding a truncated pong succeeded")
+	}
+
+	// pings of the peer are answered
+	dummy.handlePing(valid("a"), pingMsg{nonce: 5})
+	if got := <-peer; got != (horizontalapi.Extension{Message: pongMsg{nonce: 5}}) {
+		test.Fatalf("answered with %+v instead of the pong", got)
+	}
+
+	// a pong with the wrong nonce is no measurement
+	dummy.sendPings()
+	ping := nextPing()
+	dummy.handlePong(valid("a"), pongMsg{nonce: ping.nonce + 1})
+	if _, ok := connManager.RTT("a"); ok {
+		test.Fatalf("RTT was measured with a wrong nonce")
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	dummy.handlePong(valid("a"), pongMsg{nonce: ping.nonce})
+	rtt, ok := connManager.RTT("a")
+	if !ok || rtt < 10*time.Millisecond {
+		test.Fatalf("RTT is %v (measured: %v), should be at least 10ms", rtt, ok)
+	}
+
+	// a late Pong is still measured after the next Ping was sent, the
+	// oldest of too many unanswered Pings is forgotten
+	var pings []pingMsg
+	for i := 0; i <= PING_MAX_OUTSTANDING; i++ {
+		dummy.sendPings()
+		pings = append(pings, nextPing())
+		time.Sleep(time.Millisecond)
+	}
+	dummy.handlePong(valid("a"), pongMsg{nonce: pings[0].nonce})
+	if len(valid("a").pings) != PING_MAX_OUTSTANDING {
+		test.Fatalf("the oldest Ping should have been forgotten, %d are outstanding", len(valid("a").pings))
+	}
+	dummy.handlePong(valid("a"), pongMsg{nonce: pings[1].nonce})
+	if len(valid("a").pings) != PING_MAX_OUTSTANDING-1 {
+		test.Fatalf("the late Pong was not measured, %d Pings are outstanding", len(valid("a").pings))
+	}
+	rtt, _ = connManager.RTT("a")
+
+	// further samples are smoothed
+	if smoothed, _ := connManager.UpdateRTT("a", rtt+8*time.Millisecond); smoothed != rtt+time.Millisecond {
+		test.Fatalf("smoothed RTT is %v instead of %v", smoothed, rtt+time.Millisecond)
+	}
+}
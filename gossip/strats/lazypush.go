@@ -0,0 +1,251 @@
+/*
+* gossip
+* Copyright (C) 2024 Fabio Gaiba and Lukas Heindl
+*
+* This program is free software: you can redistribute it and/or modify
+* it under the terms of the GNU General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* This program is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+* GNU General Public License for more details.
+*
+* You should have received a copy of the GNU General Public License
+* along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package strats
+
+import (
+	"gossip/common"
+	horizontalapi "gossip/horizontalAPI"
+	"gossip/internal/args"
+	ringbuffer "gossip/internal/ringbuffer"
+	"slices"
+	"time"
+)
+
+// Lazy push: instead of pushing a message, only its id is announced to a peer
+// (IHave, batched per gossip round). A message which was announced but not
+// received within MISSING_ROUNDS gossip rounds is requested from the
+// announcing peer (IWant, or Graft for the plumtree strategy, see
+// [disseminator]).
+//
+// The dummy strategy announces the messages of the gossip types in
+// Lazy_push_types and those with a payload of at least Lazy_push_size bytes
+// to the selected peers instead of pushing them. Since these messages are only
+// announced, they are requested right away from the first peer which
+// announced them, so the payload is transferred roughly once per node.
+
+var (
+	// how many gossip rounds a message announced via IHave may take to arrive
+	// before it is requested from the announcing peer
+	MISSING_ROUNDS = 2
+)
+
+// A message which is only known from IHave announcements
+type missingMessage struct {
+	// connections which announced the message, in the order of the
+	// announcements
+	announcers []horizontalapi.ConnectionId
+	// gossip rounds since the message was announced (or since it was last
+	// requested)
+	rounds int
+}
+
+// State of the lazy push, see [dummyStrat.announce]
+type lazyPushState struct {
+	// messages announced by peers which were not received yet
+	missing map[uint16]*missingMessage
+	// message ids which will be announced to the peers at the end of the
+	// current round
+	announcements map[horizontalapi.ConnectionId][]uint16
+	// message ids which were announced to the peers and were not requested
+	// yet (oldest first, at most Cache_size per peer). Only these are sent
+	// when requested.
+	announced map[horizontalapi.ConnectionId][]uint16
+	// gossip types whose messages are announced instead of pushed (dummy
+	// strategy)
+	types []common.GossipType
+	// payload size from which messages are announced instead of pushed, 0
+	// if disabled (dummy strategy)
+	size uint
+}
+
+func newLazyPushState(a args.Args) *lazyPushState {
+	// already validated
+	types, _ := a.LazyPushTypes()
+	return &lazyPushState{
+		missing:       make(map[uint16]*missingMessage),
+		announcements: make(map[horizontalapi.ConnectionId][]uint16),
+		announced:     make(map[horizontalapi.ConnectionId][]uint16),
+		types:         types,
+		size:          a.Lazy_push_size,
+	}
+}
+
+// Whether msg is announced instead of pushed by the dummy strategy
+func (dummy *dummyStrat) lazyPushFor(msg horizontalapi.Push) bool {
+	if slices.Contains(dummy.lazyPush.types, msg.GossipType) {
+		return true
+	}
+	return dummy.lazyPush.size > 0 && uint(len(msg.Payload)) >= dummy.lazyPush.size
+}
+
+// The dummy strategy requests announced messages right away, the messages it
+// announces are not pushed to anyone else
+func (dummy *dummyStrat) requestsAnnounced() bool {
+	return true
+}
+
+// Remember to announce the message with msgId to peer at the end of the round
+func (dummy *dummyStrat) announce(peer *gossipConnection, msgId uint16) {
+	dummy.lazyPush.announcements[peer.connection.Id] = append(dummy.lazyPush.announcements[peer.connection.Id], msgId)
+	peer.lastUsed = time.Now()
+}
+
+// Send the collected announcements, one IHave per peer
+func (dummy *dummyStrat) flushAnnouncements() {
+	for id, msgIds := range dummy.lazyPush.announcements {
+		if peer, isValid := dummy.connManager.FindValid(id); isValid {
+			send(peer.connection, horizontalapi.IHave{MessageIds: msgIds})
+			announced := append(dummy.lazyPush.announced[id], msgIds...)
+			if excess := len(announced) - int(dummy.rootStrat.stratArgs.Cache_size); excess > 0 {
+				announced = announced[excess:]
+			}
+			dummy.lazyPush.announced[id] = announced
+		}
+		delete(dummy.lazyPush.announcements, id)
+	}
+}
+
+// Whether the message with msgId was announced to the peer with connection
+// id and not sent on request yet
+func (dummy *dummyStrat) wasAnnounced(id horizontalapi.ConnectionId, msgId uint16) bool {
+	return slices.Contains(dummy.lazyPush.announced[id], msgId)
+}
+
+// Use up the announcement of the message with msgId to the peer with
+// connection id once the message was sent, so each message is sent at most
+// once per peer on request.
+func (dummy *dummyStrat) takeAnnounced(id horizontalapi.ConnectionId, msgId uint16) {
+	announced := dummy.lazyPush.announced[id]
+	if idx := slices.Index(announced, msgId); idx >= 0 {
+		dummy.lazyPush.announced[id] = slices.Delete(announced, idx, idx+1)
+	}
+}
+
+// Remove all state of the lazy push kept for the connection id
+func (dummy *dummyStrat) forgetAnnounced(id horizontalapi.ConnectionId) {
+	delete(dummy.lazyPush.announcements, id)
+	delete(dummy.lazyPush.announced, id)
+}
+
+// The message with msgId was received, so it does not need to be requested
+func (dummy *dummyStrat) forgetMissing(msgId uint16) {
+	delete(dummy.lazyPush.missing, msgId)
+}
+
+// Remember the announced messages which were not received yet
+func (dummy *dummyStrat) handleIHave(peer *gossipConnection, msg horizontalapi.IHave) {
+	var request []uint16
+	for _, msgId := range msg.MessageIds {
+		if dummy.knownMessage(msgId) {
+			continue
+		}
+		m, ok := dummy.lazyPush.missing[msgId]
+		if !ok {
+			// don't keep track of more messages than fit in the cache
+			if len(dummy.lazyPush.missing) >= int(dummy.rootStrat.stratArgs.Cache_size) {
+				continue
+			}
+			m = &missingMessage{}
+			dummy.lazyPush.missing[msgId] = m
+			if dummy.dissemination.requestsAnnounced() {
+				// the announcer is asked now, the following announcers
+				// are asked if it does not deliver the message
+				request = append(request, msgId)
+				continue
+			}
+		}
+		m.announcers = append(m.announcers, msg.Id)
+	}
+	if len(request) > 0 {
+		dummy.rootStrat.log.Debug("Requesting announced messages", "ConnId", msg.Id, "msgIds", request)
+		send(peer.connection, horizontalapi.IWant{MessageIds: request})
+		dummy.rootStrat.metrics.iwants.Inc()
+	}
+}
+
+// Send the messages requested by a peer
+func (dummy *dummyStrat) handleIWant(peer *gossipConnection, msg horizontalapi.IWant) {
+	dummy.sendRequested(peer, msg.MessageIds)
+}
+
+// Send the messages with msgIds which were announced to peer. Each announced
+// message is sent only once and is subject to the rate limit of the peer, so
+// requests cannot be used to make this node send more than it announced. A
+// message not sent because of the rate limit can be requested again.
+func (dummy *dummyStrat) sendRequested(peer *gossipConnection, msgIds []uint16) {
+	for _, msgId := range msgIds {
+		if !dummy.wasAnnounced(peer.connection.Id, msgId) {
+			dummy.rootStrat.log.Debug("Not sending requested message which was not announced", "ConnId", peer.connection.Id, "msgId", msgId)
+			continue
+		}
+		stored, err := findFirstMessage(dummy.sentMessages, msgId)
+		if err != nil || stored.unvalidated {
+			continue
+		}
+		if dummy.push(peer, stored) {
+			dummy.takeAnnounced(peer.connection.Id, msgId)
+		}
+	}
+}
+
+// Request the messages which were announced but did not arrive within
+// MISSING_ROUNDS rounds. Each message is requested from the first peer which
+// announced it, if that one does not deliver it either, from the next one.
+func (dummy *dummyStrat) requestMissing() {
+	requests := make(map[*gossipConnection][]uint16)
+	for msgId, m := range dummy.lazyPush.missing {
+		m.rounds++
+		if m.rounds < MISSING_ROUNDS {
+			continue
+		}
+		m.rounds = 0
+		for len(m.announcers) > 0 {
+			peer, isValid := dummy.connManager.FindValid(m.announcers[0])
+			m.announcers = m.announcers[1:]
+			if isValid {
+				requests[peer] = append(requests[peer], msgId)
+				break
+			}
+		}
+		if len(m.announcers) == 0 {
+			// nobody left to ask after this request
+			delete(dummy.lazyPush.missing, msgId)
+		}
+	}
+	for peer, msgIds := range requests {
+		dummy.dissemination.requestFrom(peer, msgIds)
+	}
+}
+
+// Request the missing messages with msgIds from peer
+func (dummy *dummyStrat) requestFrom(peer *gossipConnection, msgIds []uint16) {
+	dummy.rootStrat.log.Debug("Requesting missing messages", "ConnId", peer.connection.Id, "msgIds", msgIds)
+	send(peer.connection, horizontalapi.IWant{MessageIds: msgIds})
+	dummy.rootStrat.metrics.iwants.Inc()
+}
+
+// Whether the message with msgId is in one of the caches
+func (dummy *dummyStrat) knownMessage(msgId uint16) bool {
+	for _, ring := range []*ringbuffer.Ringbuffer[*storedMessage]{dummy.invalidMessages, dummy.validMessages, dummy.sentMessages} {
+		if _, err := findFirstMessage(ring, msgId); err == nil {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+/*
+* gossip
+* Copyright (C) 2024 Fabio Gaiba and Lukas Heindl
+*
+* This program is free software: you can redistribute it and/or modify
+* it under the terms of the GNU General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* This program is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+* GNU General Public License for more details.
+*
+* You should have received a copy of the GNU General Public License
+* along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package strats
+
+import (
+	horizontalapi "gossip/horizontalAPI"
+	"gossip/internal/args"
+	"testing"
+)
+
+func TestLazyPush(test *testing.T) {
+	a := args.NewFromDefaults()
+	a.Lazy_push_types = "7"
+	a.Lazy_push_size = 4
+	connManager := NewConnectionManager(nil, nil)
+	dummy := NewDummy(testStrategy(test, a), nil, &connManager)
+	peers := newTestPeers(test, &connManager, "a", "b")
+	valid, expect := peers.valid, peers.expect
+
+	// small messages of other types are pushed, the others announced
+	small := horizontalapi.Push{MessageID: 1, GossipType: 42, TTL: 3, Payload: []byte{1, 2, 3}}
+	large := horizontalapi.Push{MessageID: 2, GossipType: 42, TTL: 3, Payload: []byte{1, 2, 3, 4}}
+	typed := horizontalapi.Push{MessageID: 3, GossipType: 7, TTL: 3}
+	insertValid(dummy, small, large, typed)
+	dummy.forwardValid()
+	for _, id := range []horizontalapi.ConnectionId{"a", "b"} {
+		expect(id, small)
+		// type 7 is forwarded first
+		expect(id, horizontalapi.IHave{MessageIds: []uint16{3, 2}})
+		expect(id, nil)
+	}
+
+	// announced messages are sent on request
+	dummy.handleIWant(valid("a"), horizontalapi.IWant{Id: "a", MessageIds: []uint16{3}})
+	expect("a", typed)
+	// but only once and only if they were announced
+	dummy.handleIWant(valid("a"), horizontalapi.IWant{Id: "a", MessageIds: []uint16{3, 1}})
+	expect("a", nil)
+
+	// unknown announced messages are requested right away from the first
+	// announcer only
+	dummy.handleIHave(valid("a"), horizontalapi.IHave{Id: "a", MessageIds: []uint16{1, 5}})
+	expect("a", horizontalapi.IWant{MessageIds: []uint16{5}})
+	dummy.handleIHave(valid("b"), horizontalapi.IHave{Id: "b", MessageIds: []uint16{5}})
+	expect("b", nil)
+
+	// the next announcer is asked if the message does not arrive
+	for i := 0; i < MISSING_ROUNDS; i++ {
+		expect("b", nil)
+		dummy.requestMissing()
+	}
+	expect("b", horizontalapi.IWant{MessageIds: []uint16{5}})
+
+	// a request refused by the rate limit of the peer can be repeated
+	a.Out_peer_rate = 1
+	a.Out_peer_burst = 1
+	dummy = NewDummy(testStrategy(test, a), nil, &connManager)
+	other := horizontalapi.Push{MessageID: 4, GossipType: 7, TTL: 3}
+	insertValid(dummy, typed, other)
+	dummy.forwardValid()
+	expect("a", horizontalapi.IHave{MessageIds: []uint16{4, 3}})
+	dummy.handleIWant(valid("a"), horizontalapi.IWant{Id: "a", MessageIds: []uint16{3, 4}})
+	expect("a", typed)
+	expect("a", nil)
+	// the bucket of the peer is full again
+	dummy.limiter.forget("a")
+	dummy.handleIWant(valid("a"), horizontalapi.IWant{Id: "a", MessageIds: []uint16{4}})
+	expect("a", other)
+}
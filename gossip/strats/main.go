@@ -22,14 +22,18 @@ package strats
 import (
 	"context"
 	"encoding/binary"
+	"encoding/hex"
 	"fmt"
 	"gossip/common"
 	horizontalapi "gossip/horizontalAPI"
 	"gossip/internal/args"
+	"gossip/internal/metrics"
+	"gossip/internal/store"
+	"gossip/internal/trace"
 	pow "gossip/pow"
 	"io"
-	"net"
 	"slices"
+	"time"
 
 	"log/slog"
 )
@@ -45,6 +49,17 @@ type Strategy struct {
 	strategyChannels StrategyChannels
 	log              *slog.Logger
 	stratArgs        args.Args
+	// Reputation of the peers, shared by all strategies
+	reputation *reputationBook
+	// Persisted state of the messages (nil if disabled)
+	messageStore *store.Log[persistedMessage]
+	// Metrics of the strategy (no-ops if disabled)
+	metrics *stratMetrics
+	// Trace of the message propagation (nil if disabled)
+	tracer *trace.Sink
+	// Random identifier of this node, sent to the peers to detect duplicate
+	// connections
+	nodeId []byte
 }
 
 // Any strategy should implement the strategyCloser type, so a Listen method and a Close one.
@@ -53,6 +68,15 @@ type StrategyCloser interface {
 	Listen()
 }
 
+// Strategies implementing this interface can be drained before being closed,
+// so that no messages are lost on shutdown.
+type StrategyDrainer interface {
+	// Stop accepting new messages from peers, forward the pending messages and
+	// wait for the outstanding validations. Returns once this is done or ctx is
+	// done.
+	Drain(ctx context.Context) error
+}
+
 // Ingoing and outgoing channels of any strategy
 type StrategyChannels struct {
 	FromStrat chan common.FromStrat
@@ -65,9 +89,14 @@ type StrategyChannels struct {
 // listen on the given address.
 // It instantiate a strategy too. The caller has to call Listen to start the strategy and Close
 // to end it.
-func New(log *slog.Logger, args args.Args, stratChans StrategyChannels, initFinished chan<- struct{}) (StrategyCloser, error) {
-	fromHz := make(chan horizontalapi.FromHz, 1)
+//
+// The metrics of the strategy are registered on reg, which may be nil if no
+// metrics should be collected.
+func New(log *slog.Logger, args args.Args, stratChans StrategyChannels, reg *metrics.Registry, initFinished chan<- struct{}) (StrategyCloser, error) {
+	fromHz := make(chan horizontalapi.FromHz, args.Hz_queue_size)
 	hz := horizontalapi.NewHorizontalApi(log, fromHz)
+	hz.SetIdleTimeout(args.Idle_timeout)
+	hz.SetEgressLimits(args.Egress_rate, args.Egress_peer_rate)
 	// context is only used internally -> no need to pass it to the constructor
 	ctx, cancel := context.WithCancel(context.Background())
 	strategy := Strategy{
@@ -77,20 +106,87 @@ func New(log *slog.Logger, args args.Args, stratChans StrategyChannels, initFini
 		strategyChannels: stratChans,
 		stratArgs:        args,
 		log:              log.With("module", "strategy"),
+		metrics:          newStratMetrics(reg),
 	}
 
-	host, _, err := net.SplitHostPort(args.Hz_addr)
+	// release the store, the trace and the listeners opened so far if the
+	// strategy cannot be started
+	ok := false
+	defer func() {
+		if !ok {
+			strategy.Close()
+		}
+	}()
+
+	nodeId, err := newNodeId()
 	if err != nil {
 		return nil, err
 	}
-	openConnections, err := hz.AddNeighbors(&net.Dialer{LocalAddr: &net.TCPAddr{IP: net.ParseIP(host), Port: 0}}, args.Peer_addrs...)
+	strategy.nodeId = nodeId
+	strategy.log.Info("Generated node id", "nodeId", hex.EncodeToString(nodeId))
 
-	hzInitFin := make(chan struct{}, 1)
+	reputation, err := newReputationBook(strategy.log, args)
+	if err != nil {
+		return nil, err
+	}
+	strategy.reputation = reputation
 
-	hz.Listen(args.Hz_addr, hzInitFin)
+	if args.Store_file != "" {
+		strategy.messageStore, err = store.Open[persistedMessage](args.Store_file, time.Duration(args.Store_retention)*time.Second)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if args.Trace_file != "" {
+		strategy.tracer, err = trace.Open(args.Trace_file, args.AdvertisedAddr())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// don't even try to connect to banned peers
+	peerAddrs := make([]string, 0, len(args.Peer_addrs))
+	for _, addr := range args.Peer_addrs {
+		if strategy.reputation.banned(horizontalapi.ConnectionId(addr)) {
+			strategy.log.Warn("Not connecting to banned peer", "addr", addr)
+			continue
+		}
+		peerAddrs = append(peerAddrs, addr)
+	}
+
+	dialer, err := strategy.dialer()
+	if err != nil {
+		return nil, err
+	}
+	openConnections, err := hz.AddNeighbors(dialer, peerAddrs...)
+
+	// the bootstrapper is just another neighbor to start with, but the node
+	// may start without it (it is connected to later on like the other known
+	// peers if there are too few peers)
+	if err == nil && args.Bootstrapper != "" && !slices.Contains(args.Peer_addrs, args.Bootstrapper) {
+		if strategy.reputation.banned(horizontalapi.ConnectionId(args.Bootstrapper)) {
+			strategy.log.Warn("Not connecting to banned peer", "addr", args.Bootstrapper)
+		} else if conns, berr := hz.AddNeighbors(dialer, args.Bootstrapper); berr != nil {
+			strategy.log.Warn("Connecting to the bootstrapper failed", "addr", args.Bootstrapper, "err", berr)
+		} else {
+			openConnections = append(openConnections, conns...)
+		}
+	}
+
+	hzAddrs := args.HzAddrs()
+	hzInitFin := make(chan struct{}, len(hzAddrs))
+
+	for _, addr := range hzAddrs {
+		if lerr := hz.Listen(addr, hzInitFin); lerr != nil {
+			return nil, lerr
+		}
+	}
 
 	go func(initFinished chan<- struct{}, hzInitFin <-chan struct{}) {
-		<-hzInitFin
+		for range hzAddrs {
+			<-hzInitFin
+		}
 		initFinished <- struct{}{}
 	}(initFinished, hzInitFin)
 
@@ -98,17 +194,56 @@ func New(log *slog.Logger, args args.Args, stratChans StrategyChannels, initFini
 		return nil, fmt.Errorf("the error occured while initiating the gossip module %w", err)
 	}
 
-	connManager := NewConnectionManager(openConnections)
+	connManager := NewConnectionManager(openConnections, strategy.reputation)
+	connManager.SetPeerLimits(args.Min_peers, args.Target_peers, args.Max_peers)
+	registerConnectionMetrics(reg, &connManager)
 
-	// Hardcoded strategy, later switching on args argument
-	dummyStrat := NewDummy(strategy, fromHz, &connManager)
-	return &dummyStrat, nil
+	var strat StrategyCloser
+	switch args.Strategy {
+	case "dummy":
+		strat = NewDummy(strategy, fromHz, &connManager)
+	case "plumtree":
+		strat = NewPlumtree(strategy, fromHz, &connManager)
+	case "gossipsub":
+		strat = NewGossipSub(strategy, fromHz, &connManager)
+	case "rumor":
+		strat = NewRumor(strategy, fromHz, &connManager)
+	default:
+		return nil, fmt.Errorf("unknown strategy %q", args.Strategy)
+	}
+	ok = true
+	return strat, nil
 }
 
-// Simply closes the horizontal API
+// Closes the horizontal API and the message store
 func (strt *Strategy) Close() {
 	strt.cancel()
 	strt.hz.Close()
+	if err := strt.messageStore.Close(); err != nil {
+		strt.log.Warn("Closing the message store failed", "err", err)
+	}
+	if err := strt.tracer.Close(); err != nil {
+		strt.log.Warn("Closing the trace failed", "err", err)
+	}
+}
+
+// Record a trace event for msg. conn is the connection the message was
+// received on or sent to (nil if the event is not related to a peer).
+func (strt *Strategy) traceEvent(kind trace.Kind, msg horizontalapi.Push, conn *horizontalapi.Conn[chan<- horizontalapi.ToHz]) {
+	if strt.tracer == nil {
+		return
+	}
+	ev := trace.Event{
+		Kind:       kind,
+		MessageId:  msg.MessageID,
+		GossipType: msg.GossipType,
+		TTL:        msg.TTL,
+	}
+	if conn != nil {
+		ev.Peer = string(conn.Id)
+		ev.Local = string(conn.Local)
+	}
+	strt.tracer.Record(ev)
 }
 
 // Make the ConnPoW message implement the interface POWMarshaller
@@ -0,0 +1,218 @@
+/*
+* gossip
+* Copyright (C) 2024 Fabio Gaiba and Lukas Heindl
+*
+* This program is free software: you can redistribute it and/or modify
+* it under the terms of the GNU General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* This program is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+* GNU General Public License for more details.
+*
+* You should have received a copy of the GNU General Public License
+* along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package strats
+
+import (
+	"encoding/binary"
+	"fmt"
+	horizontalapi "gossip/horizontalAPI"
+	"math"
+	mrand "math/rand"
+	"time"
+)
+
+// The membership follows HyParView: the connections of the connection
+// manager form the (small) active view which is used for gossiping, the known
+// peers which are not connected form the (larger) passive view. Failed active
+// peers are replaced by passive ones (see [dummyStrat.replacePeer]) and the
+// passive view is kept fresh by periodically exchanging (shuffling) known
+// peers with a random active peer.
+//
+// Different from HyParView, the shuffle is not forwarded on a random walk but
+// directly answered by the neighbor, since answering the origin of the
+// shuffle would require a new connection (including a PoW).
+
+var (
+	// how many learned addresses of the passive view a single Shuffle (or
+	// ShuffleReply) may replace, so that a single peer cannot take over the
+	// passive view
+	SHUFFLE_MAX_REPLACE = 2
+)
+
+// Addresses of the peers with a valid connection (active view).
+//
+// For each peer the address it advertised is used, for outgoing connections
+// the dialed address if the peer did not advertise one.
+func (manager *ConnectionManager) ActiveAddrs() []string {
+	manager.connMutex.RLock()
+	defer manager.connMutex.RUnlock()
+
+	addrs := make([]string, 0, len(manager.openConnections))
+	for _, conn := range manager.openConnections {
+		if conn.addr != "" {
+			addrs = append(addrs, conn.addr)
+		} else if conn.outgoing {
+			addrs = append(addrs, string(conn.connection.Id))
+		}
+	}
+	return addrs
+}
+
+// Pick the addresses sent in a shuffle: the own address and alternately
+// random addresses of the active and the passive view, at most Shuffle_length
+// in total. exclude (the address of the receiver) is never sent.
+func (dummy *dummyStrat) shuffleSample(exclude string) []string {
+	n := int(dummy.rootStrat.stratArgs.Shuffle_length)
+	sample := []string{dummy.rootStrat.stratArgs.AdvertisedAddr()}
+
+	pick := func(addrs []string) []string {
+		mrand.Shuffle(len(addrs), func(i, j int) { addrs[i], addrs[j] = addrs[j], addrs[i] })
+		ret := addrs[:0]
+		for _, addr := range addrs {
+			if addr != exclude {
+				ret = append(ret, addr)
+			}
+		}
+		return ret
+	}
+	active := pick(dummy.connManager.ActiveAddrs())
+	passive := pick(dummy.passiveView())
+
+	for len(sample) < n && (len(active) > 0 || len(passive) > 0) {
+		if len(active) > 0 {
+			sample = append(sample, active[0])
+			active = active[1:]
+		}
+		if len(sample) < n && len(passive) > 0 {
+			sample = append(sample, passive[0])
+			passive = passive[1:]
+		}
+	}
+	return sample[:min(n, len(sample))]
+}
+
+// Shuffle message (extension of kind EXTENSION_SHUFFLE), it contains
+// addresses of peers known to the sender
+type shuffleMsg struct {
+	addrs []string
+}
+
+func (shuffleMsg) Kind() horizontalapi.ExtensionKind { return EXTENSION_SHUFFLE }
+func (m shuffleMsg) MarshalBinary() ([]byte, error)  { return encodeAddrs(m.addrs) }
+
+// ShuffleReply message (extension of kind EXTENSION_SHUFFLE_REPLY), the answer
+// to a shuffleMsg
+type shuffleReplyMsg struct {
+	addrs []string
+}
+
+func (shuffleReplyMsg) Kind() horizontalapi.ExtensionKind { return EXTENSION_SHUFFLE_REPLY }
+func (m shuffleReplyMsg) MarshalBinary() ([]byte, error)  { return encodeAddrs(m.addrs) }
+
+// the addresses are sent one after another, each prefixed with its length (2
+// bytes)
+func encodeAddrs(addrs []string) ([]byte, error) {
+	var data []byte
+	for _, addr := range addrs {
+		if len(addr) > math.MaxUint16 {
+			return nil, fmt.Errorf("address of %d bytes is too long", len(addr))
+		}
+		data = binary.BigEndian.AppendUint16(data, uint16(len(addr)))
+		data = append(data, addr...)
+	}
+	return data, nil
+}
+
+func decodeAddrs(data []byte) ([]string, error) {
+	var addrs []string
+	for len(data) > 0 {
+		if len(data) < 2 {
+			return nil, fmt.Errorf("truncated length of an address")
+		}
+		n := int(binary.BigEndian.Uint16(data))
+		data = data[2:]
+		if len(data) < n {
+			return nil, fmt.Errorf("address of %d bytes but only %d bytes left", n, len(data))
+		}
+		addrs = append(addrs, string(data[:n]))
+		data = data[n:]
+	}
+	return addrs, nil
+}
+
+func decodeShuffle(data []byte) (horizontalapi.ExtensionMessage, error) {
+	addrs, err := decodeAddrs(data)
+	return shuffleMsg{addrs: addrs}, err
+}
+
+func decodeShuffleReply(data []byte) (horizontalapi.ExtensionMessage, error) {
+	addrs, err := decodeAddrs(data)
+	return shuffleReplyMsg{addrs: addrs}, err
+}
+
+// Send a shuffle to a random active peer
+func (dummy *dummyStrat) startShuffle() {
+	var peer *gossipConnection
+	dummy.connManager.ActionOnPermutedValid(func(x *gossipConnection) {
+		peer = x
+	}, 1)
+	if peer == nil {
+		return
+	}
+	// sample outside of ActionOnPermutedValid, it locks the connection
+	// manager itself
+	peer.sentShuffle = true
+	send(peer.connection, horizontalapi.Extension{Message: shuffleMsg{addrs: dummy.shuffleSample(peer.addr)}})
+}
+
+// Minimum time between two Shuffles of the same peer. Each node sends a
+// Shuffle to one peer every Shuffle_interval, half of it leaves room for
+// delays.
+func (dummy *dummyStrat) shuffleGap() time.Duration {
+	return max(time.Second, time.Duration(dummy.rootStrat.stratArgs.Shuffle_interval)*time.Second/2)
+}
+
+// Answer the shuffle of a peer with own known peers and remember the
+// received ones. Peers sending Shuffles more often than every
+// [dummyStrat.shuffleGap] are penalized and not answered.
+func (dummy *dummyStrat) handleShuffle(peer *gossipConnection, msg shuffleMsg) {
+	now := time.Now()
+	if now.Sub(peer.lastShuffle) < dummy.shuffleGap() {
+		dummy.rootStrat.log.Warn("Shuffle received too early after the last one", "ConnId", peer.connection.Id)
+		dummy.ratePeer(peer.connection.Id, reputationUnsolicited)
+		return
+	}
+	peer.lastShuffle = now
+	send(peer.connection, horizontalapi.Extension{Message: shuffleReplyMsg{addrs: dummy.shuffleSample(peer.addr)}})
+	dummy.integrateShuffle(peer.connection.Id, msg.addrs)
+}
+
+// Remember the known peers of a peer which answered a shuffle
+func (dummy *dummyStrat) handleShuffleReply(peer *gossipConnection, msg shuffleReplyMsg) {
+	if !peer.sentShuffle {
+		dummy.rootStrat.log.Warn("ShuffleReply received but no Shuffle was sent", "ConnId", peer.connection.Id)
+		dummy.dropPeer(peer.connection.Id, reputationUnsolicited)
+		return
+	}
+	peer.sentShuffle = false
+	dummy.integrateShuffle(peer.connection.Id, msg.addrs)
+}
+
+// Add the addresses received in a shuffle on connection id to the passive
+// view. At most Shuffle_length addresses are taken into account, at most
+// SHUFFLE_MAX_REPLACE of them replace learned addresses.
+func (dummy *dummyStrat) integrateShuffle(id horizontalapi.ConnectionId, addrs []string) {
+	replaced := 0
+	for _, addr := range addrs[:min(len(addrs), int(dummy.rootStrat.stratArgs.Shuffle_length))] {
+		// the sender might advertise an unspecified address for itself
+		if dummy.learnAddr(advertisedAddr(addr, id), replaced < SHUFFLE_MAX_REPLACE) {
+			replaced++
+		}
+	}
+}
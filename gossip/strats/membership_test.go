@@ -0,0 +1,123 @@
+/*
+* gossip
+* Copyright (C) 2024 Fabio Gaiba and Lukas Heindl
+*
+* This program is free software: you can redistribute it and/or modify
+* it under the terms of the GNU General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* This program is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+* GNU General Public License for more details.
+*
+* You should have received a copy of the GNU General Public License
+* along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package strats
+
+import (
+	"context"
+	horizontalapi "gossip/horizontalAPI"
+	"gossip/internal/args"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/neilotoole/slogt"
+)
+
+func TestMembership(test *testing.T) {
+	a := args.NewFromDefaults()
+	a.Hz_addr = "10.0.0.1:6001"
+	a.Peer_addrs = []string{"10.0.0.2:6001"}
+	a.Passive_view_size = 3
+	a.Shuffle_length = 4
+	connManager := NewConnectionManager(nil, nil)
+	dummy := NewDummy(Strategy{stratArgs: a, log: slogt.New(test), metrics: newStratMetrics(nil)}, nil, &connManager)
+
+	// one active peer
+	active := &gossipConnection{connection: horizontalapi.Conn[chan<- horizontalapi.ToHz]{Id: "10.0.0.3:50000"}, addr: "10.0.0.3:6001"}
+	connManager.AddInProgress(active)
+	connManager.MakeValid(active.connection.Id, time.Now())
+
+	// the own address is not learned, the passive view is limited (configured
+	// peers don't count)
+	for _, addr := range []string{"10.0.0.1:6001", "10.0.1.1:6001", "10.0.1.2:6001", "10.0.1.3:6001", "10.0.1.4:6001"} {
+		dummy.learnAddr(addr, true)
+	}
+	if _, ok := dummy.knownPeers["10.0.0.1:6001"]; ok {
+		test.Fatalf("The own address should not be learned")
+	}
+	if len(dummy.knownPeers) != 4 {
+		test.Fatalf("Three learned and one configured peer should be known, but %d are", len(dummy.knownPeers))
+	}
+	if _, ok := dummy.knownPeers["10.0.1.4:6001"]; !ok {
+		test.Fatalf("A new address should replace an older one if the passive view is full")
+	}
+	if _, ok := dummy.knownPeers["10.0.0.2:6001"]; !ok {
+		test.Fatalf("Configured peers should never be replaced")
+	}
+
+	// the active peer is not part of the passive view
+	dummy.learnAddr("10.0.0.3:6001", true)
+	if slices.Contains(dummy.passiveView(), "10.0.0.3:6001") {
+		test.Fatalf("Connected peers should not be in the passive view")
+	}
+
+	sample := dummy.shuffleSample("10.0.0.2:6001")
+	if len(sample) != 4 || sample[0] != "10.0.0.1:6001" || sample[1] != "10.0.0.3:6001" {
+		test.Fatalf("Sample should consist of the own address, the active peer and passive peers, but is %v", sample)
+	}
+	if slices.Contains(sample, "10.0.0.2:6001") {
+		test.Fatalf("Sample should not contain the address of the receiver: %v", sample)
+	}
+
+	// unspecified addresses are replaced by the address of the sender
+	dummy.rootStrat.stratArgs.Passive_view_size = 10
+	dummy.integrateShuffle("10.0.2.1:50000", []string{"0.0.0.0:6001", "10.0.2.2:6001", "10.0.2.3:6001", "10.0.2.4:6001", "10.0.2.5:6001"})
+	if _, ok := dummy.knownPeers["10.0.2.1:6001"]; !ok {
+		test.Fatalf("The unspecified address of the sender should have been replaced")
+	}
+	if _, ok := dummy.knownPeers["10.0.2.5:6001"]; ok {
+		test.Fatalf("Only shuffle_length addresses should be taken into account")
+	}
+
+	// a single shuffle replaces only few addresses of a full passive view
+	known := len(dummy.knownPeers)
+	dummy.rootStrat.stratArgs.Passive_view_size = uint(known - 1)
+	dummy.integrateShuffle("10.0.3.1:50000", []string{"10.0.3.2:6001", "10.0.3.3:6001", "10.0.3.4:6001", "10.0.3.5:6001"})
+	// the randomly chosen victim may be an address of the same shuffle
+	learned := 0
+	for addr := range dummy.knownPeers {
+		if strings.HasPrefix(addr, "10.0.3.") {
+			learned++
+		}
+	}
+	if learned == 0 || learned > SHUFFLE_MAX_REPLACE || len(dummy.knownPeers) != known {
+		test.Fatalf("A shuffle should replace at most %d addresses, but %d of %d known addresses are new", SHUFFLE_MAX_REPLACE, learned, len(dummy.knownPeers))
+	}
+
+	// shuffles are answered at most every shuffleGap per peer
+	data := make(chan horizontalapi.ToHz, 2)
+	shuffler := &gossipConnection{connection: horizontalapi.Conn[chan<- horizontalapi.ToHz]{Id: "10.0.4.1:50000", Data: data, Ctx: context.Background()}}
+	connManager.AddInProgress(shuffler)
+	connManager.MakeValid(shuffler.connection.Id, time.Now())
+	dummy.handleShuffle(shuffler, shuffleMsg{})
+	dummy.handleShuffle(shuffler, shuffleMsg{})
+	if len(data) != 1 {
+		test.Fatalf("Only the first of two shuffles in a row should be answered, but %d replies were sent", len(data))
+	}
+	// the addresses survive the encoding
+	addrs := []string{"127.0.0.1:6001", "", "[::1]:6002"}
+	enc, _ := shuffleReplyMsg{addrs: addrs}.MarshalBinary()
+	if m, err := decodeShuffleReply(enc); err != nil || !slices.Equal(m.(shuffleReplyMsg).addrs, addrs) {
+		test.Fatalf("decoded %+v (err %v) instead of the addresses", m, err)
+	}
+	if _, err := decodeShuffle(enc[:len(enc)-1]); err == nil {
+		test.Fatalf("decoding a truncated shuffle succeeded")
+	}
+}
@@ -0,0 +1,96 @@
+/*
+* gossip
+* Copyright (C) 2024 Fabio Gaiba and Lukas Heindl
+*
+* This program is free software: you can redistribute it and/or modify
+* it under the terms of the GNU General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* This program is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+* GNU General Public License for more details.
+*
+* You should have received a copy of the GNU General Public License
+* along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package strats
+
+import (
+	"gossip/common"
+	"gossip/internal/metrics"
+	"strconv"
+)
+
+// Collection of the metrics exported by the strategies.
+//
+// If the registry passed to [newStratMetrics] is nil, all metrics are nil and
+// updating them is a no-op.
+type stratMetrics struct {
+	// new messages received from peers
+	received *metrics.Counter
+	// messages received from peers which were already known
+	duplicate *metrics.Counter
+	// messages marked valid/invalid by the modules
+	valid   *metrics.Counter
+	invalid *metrics.Counter
+	// messages announced by the modules
+	announced *metrics.Counter
+	// messages sent to peers (counted once per peer)
+	forwarded *metrics.Counter
+	// time needed to solve a PoW
+	powSolve *metrics.Histogram
+	// amount of messages in the internal queues
+	queueDepth *metrics.Gauge
+	// connections closed because they duplicate another one
+	duplicateConns *metrics.Counter
+	// links of the broadcast tree pruned/grafted (plumtree strategy)
+	prunes *metrics.Counter
+	grafts *metrics.Counter
+	// requests of announced messages which did not arrive (lazy push)
+	iwants *metrics.Counter
+	// pushed messages the peers already knew (rumor strategy)
+	feedback *metrics.Counter
+	// measured round trip times to the peers
+	rtt *metrics.Histogram
+}
+
+// Create the metrics of the strategy and register them on reg
+func newStratMetrics(reg *metrics.Registry) *stratMetrics {
+	return &stratMetrics{
+		received:   reg.NewCounter("gossip_messages_received_total", "New messages received from peers", "type"),
+		duplicate:  reg.NewCounter("gossip_messages_duplicate_total", "Messages received from peers which were already known", "type"),
+		valid:      reg.NewCounter("gossip_messages_valid_total", "Messages marked valid by the modules", "type"),
+		invalid:    reg.NewCounter("gossip_messages_invalid_total", "Messages marked invalid by the modules", "type"),
+		announced:  reg.NewCounter("gossip_messages_announced_total", "Messages announced by the modules", "type"),
+		forwarded:  reg.NewCounter("gossip_messages_forwarded_total", "Messages sent to peers (once per peer)", "type"),
+		powSolve:   reg.NewHistogram("gossip_pow_solve_seconds", "Time needed to solve a PoW", []float64{0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5}),
+		queueDepth: reg.NewGauge("gossip_queue_depth", "Amount of messages in the queues of the strategy", "queue"),
+
+		duplicateConns: reg.NewCounter("gossip_connections_duplicate_total", "Connections closed since another connection to the same node exists (or since they lead to the node itself)"),
+		prunes:         reg.NewCounter("gossip_plumtree_prunes_total", "Links removed from the broadcast tree since they delivered a duplicate"),
+		grafts:         reg.NewCounter("gossip_plumtree_grafts_total", "Links added to the broadcast tree to request missing messages"),
+		iwants:         reg.NewCounter("gossip_iwants_total", "Requests of announced messages which did not arrive"),
+		feedback:       reg.NewCounter("gossip_rumor_feedback_total", "Pushed messages which the peer already knew"),
+		rtt:            reg.NewHistogram("gossip_peer_rtt_seconds", "Round trip times to the peers measured with pings", []float64{0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5}),
+	}
+}
+
+// Export the amount of connections in each state of the connection manager
+func registerConnectionMetrics(reg *metrics.Registry, manager *ConnectionManager) {
+	reg.NewGaugeFunc("gossip_connections", "Connections to peers by state", []string{"state"}, func() []metrics.Sample {
+		toBeProved, inProgress, valid := manager.Counts()
+		return []metrics.Sample{
+			{LabelValues: []string{"toBeProved"}, Value: float64(toBeProved)},
+			{LabelValues: []string{"inProgress"}, Value: float64(inProgress)},
+			{LabelValues: []string{"valid"}, Value: float64(valid)},
+		}
+	})
+}
+
+// label value of a gossip type
+func typeLabel(gtype common.GossipType) string {
+	return strconv.FormatUint(uint64(gtype), 10)
+}
@@ -0,0 +1,343 @@
+/*
+* gossip
+* Copyright (C) 2024 Fabio Gaiba and Lukas Heindl
+*
+* This program is free software: you can redistribute it and/or modify
+* it under the terms of the GNU General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* This program is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+* GNU General Public License for more details.
+*
+* You should have received a copy of the GNU General Public License
+* along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package strats
+
+import (
+	horizontalapi "gossip/horizontalAPI"
+	"math"
+	mrand "math/rand"
+	"net"
+	"net/netip"
+	"slices"
+	"time"
+)
+
+var (
+	// how often the amount of connections is checked (and new peers are
+	// connected to if there are too few)
+	OVERLAY_INTERVAL = 5 * time.Second
+)
+
+// Address of a peer which can be connected to if the node has too few
+// connections
+type knownPeer struct {
+	// configured peers (peer list, bootstrapper or added via the admin
+	// interface) are kept even if connecting to them fails
+	configured bool
+	// resolved address, empty as long as it was not resolved
+	id horizontalapi.ConnectionId
+	// set while connecting to the peer
+	dialing bool
+}
+
+// Set the limits of the amount of connections: below minPeers the node looks
+// for new peers until it has targetPeers connections, beyond maxPeers
+// incoming connections are refused (see [ConnectionManager.AdmitInbound]).
+// maxPeers 0 disables the limit.
+//
+// Lowering the limits does not close existing connections.
+func (manager *ConnectionManager) SetPeerLimits(minPeers uint, targetPeers uint, maxPeers uint) {
+	manager.connMutex.Lock()
+	defer manager.connMutex.Unlock()
+
+	manager.minPeers = minPeers
+	manager.targetPeers = targetPeers
+	manager.maxPeers = maxPeers
+}
+
+// Amount of connections in any state, without locking resources
+func (manager *ConnectionManager) unsafeCount() int {
+	return len(manager.toBeProvedConnections) + len(manager.powInProgress) + len(manager.openConnections)
+}
+
+// Returns how many connections are missing to reach the target amount if the
+// node has less than the minimum amount of connections, 0 otherwise.
+func (manager *ConnectionManager) PeersMissing() int {
+	manager.connMutex.RLock()
+	defer manager.connMutex.RUnlock()
+
+	count := manager.unsafeCount()
+	if count >= int(manager.minPeers) {
+		return 0
+	}
+	return int(manager.targetPeers) - count
+}
+
+// Returns how many connections are missing to reach the target amount (0 if
+// it is reached).
+func (manager *ConnectionManager) PeersBelowTarget() int {
+	manager.connMutex.RLock()
+	defer manager.connMutex.RUnlock()
+
+	return max(0, int(manager.targetPeers)-manager.unsafeCount())
+}
+
+// Returns whether there is a connection to the peer which accepts connections
+// at addr (i.e. addr was dialed or the peer advertised it in its Hello).
+func (manager *ConnectionManager) ConnectedTo(addr horizontalapi.ConnectionId) bool {
+	return len(manager.ConnectionsTo(addr)) > 0
+}
+
+// Returns the connections (in any state) to the peer which accepts
+// connections at addr, see [ConnectionManager.ConnectedTo]
+func (manager *ConnectionManager) ConnectionsTo(addr horizontalapi.ConnectionId) []*gossipConnection {
+	manager.connMutex.RLock()
+	defer manager.connMutex.RUnlock()
+
+	var conns []*gossipConnection
+	manager.unsafeAction(func(x *gossipConnection) {
+		if (x.outgoing && x.connection.Id == addr) || x.addr == string(addr) {
+			conns = append(conns, x)
+		}
+	})
+	return conns
+}
+
+// Decide whether an incoming connection from id is accepted.
+//
+// Below the maximum amount of connections every connection is accepted.
+// Otherwise an incoming connection of the subnet with the most incoming
+// connections is evicted to make room, unless the new connection belongs to
+// such a subnet itself (then it is refused). This way the connections are
+// spread over many subnets and an attacker controlling only a few subnets
+// cannot occupy all connections of the node (eclipse attack). Connections
+// which did not provide a PoW yet and peers with a bad reputation are evicted
+// first. Outgoing connections are never evicted.
+//
+// Returns the connection to evict (may be nil) and whether the new connection
+// is accepted.
+func (manager *ConnectionManager) AdmitInbound(id horizontalapi.ConnectionId) (*gossipConnection, bool) {
+	manager.connMutex.RLock()
+	defer manager.connMutex.RUnlock()
+
+	if manager.maxPeers == 0 || manager.unsafeCount() < int(manager.maxPeers) {
+		return nil, true
+	}
+
+	subnets := make(map[string][]*gossipConnection)
+	manager.unsafeAction(func(x *gossipConnection) {
+		if !x.outgoing {
+			s := subnetOf(x.connection.Id)
+			subnets[s] = append(subnets[s], x)
+		}
+	})
+	largest := 0
+	for _, conns := range subnets {
+		largest = max(largest, len(conns))
+	}
+	// accepting the connection would not improve the diversity (this also
+	// covers the case that there are only outgoing connections)
+	if len(subnets[subnetOf(id)])+1 >= largest {
+		return nil, false
+	}
+
+	var evict *gossipConnection
+	evictValid := true
+	evictWeight := math.Inf(1)
+	for _, conns := range subnets {
+		if len(conns) != largest {
+			continue
+		}
+		for _, x := range conns {
+			_, valid := manager.openConnectionsMap[x.connection.Id]
+			weight := manager.reputation.weight(x.connection.Id)
+			if evict == nil || (evictValid && !valid) || (valid == evictValid && weight < evictWeight) {
+				evict, evictValid, evictWeight = x, valid, weight
+			}
+		}
+	}
+	return evict, true
+}
+
+// Returns the subnet of the address of connection id which is used to spread
+// the connections over different networks: /16 for IPv4 and /32 for IPv6
+// addresses. If id is no ip:port, id itself is returned.
+func subnetOf(id horizontalapi.ConnectionId) string {
+	addrPort, err := netip.ParseAddrPort(string(id))
+	if err != nil {
+		return string(id)
+	}
+	addr := addrPort.Addr().Unmap()
+	bits := 32
+	if addr.Is4() {
+		bits = 16
+	}
+	prefix, err := addr.Prefix(bits)
+	if err != nil {
+		return string(id)
+	}
+	return prefix.String()
+}
+
+// Returns the host of connection id. If id is no host:port, id itself is
+// returned.
+func hostOf(id horizontalapi.ConnectionId) string {
+	host, _, err := net.SplitHostPort(string(id))
+	if err != nil {
+		return string(id)
+	}
+	// IPv4 peers on dual-stack listeners show up as ::ffff:a.b.c.d
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return addr.Unmap().String()
+	}
+	return host
+}
+
+// Returns the address under which the peer at the other end of connection id
+// accepts connections, given the address it advertised. If the advertised
+// host is unspecified (e.g. 0.0.0.0 or [::]), the host of the connection is
+// used instead. Returns an empty string if addr is no valid address.
+func advertisedAddr(addr string, id horizontalapi.ConnectionId) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return ""
+	}
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host, _, err = net.SplitHostPort(string(id))
+		if err != nil {
+			return ""
+		}
+	}
+	return net.JoinHostPort(host, port)
+}
+
+// Remember addr as address of a peer which can be connected to later on.
+//
+// At most Passive_view_size learned addresses are remembered, if there are
+// more, a random learned address is replaced (configured ones are kept) if
+// mayReplace holds. Returns whether a learned address was replaced.
+func (dummy *dummyStrat) learnAddr(addr string, mayReplace bool) bool {
+	if addr == "" {
+		return false
+	}
+	if _, ok := dummy.knownPeers[addr]; ok {
+		return false
+	}
+	a := dummy.rootStrat.stratArgs
+	if addr == a.AdvertisedAddr() || slices.Contains(a.HzAddrs(), addr) {
+		return false
+	}
+
+	var replaceable []string
+	learned := 0
+	for known, kp := range dummy.knownPeers {
+		if kp.configured {
+			continue
+		}
+		learned++
+		if !kp.dialing {
+			replaceable = append(replaceable, known)
+		}
+	}
+	replaced := false
+	if learned >= int(a.Passive_view_size) {
+		if !mayReplace || len(replaceable) == 0 || learned > int(a.Passive_view_size) {
+			return false
+		}
+		delete(dummy.knownPeers, replaceable[mrand.Intn(len(replaceable))])
+		replaced = true
+	}
+	dummy.knownPeers[addr] = &knownPeer{id: horizontalapi.ConnectionId(addr)}
+	return replaced
+}
+
+// Addresses of the known peers which are not connected (passive view)
+func (dummy *dummyStrat) passiveView() []string {
+	var addrs []string
+	for addr, kp := range dummy.knownPeers {
+		if dummy.connManager.ConnectedTo(horizontalapi.ConnectionId(addr)) || (kp.id != "" && dummy.connManager.ConnectedTo(kp.id)) {
+			continue
+		}
+		addrs = append(addrs, addr)
+	}
+	return addrs
+}
+
+// Connect to known peers if the node has less than Min_peers connections
+// until it has Target_peers connections.
+func (dummy *dummyStrat) maintainOverlay() {
+	dummy.connectKnownPeers(dummy.connManager.PeersMissing())
+}
+
+// Replace a failed connection by connecting to a known peer right away, if
+// the node has less than Target_peers connections.
+func (dummy *dummyStrat) replacePeer() {
+	if dummy.draining {
+		return
+	}
+	dummy.connectKnownPeers(min(1, dummy.connManager.PeersBelowTarget()))
+}
+
+// Connect to (at most) missing random peers of the passive view.
+//
+// Connecting happens in the background, the result is applied via
+// [dummyStrat.runInLoop]. Learned addresses which cannot be connected to are
+// forgotten.
+func (dummy *dummyStrat) connectKnownPeers(missing int) {
+	if missing <= 0 {
+		return
+	}
+	var candidates []string
+	for _, addr := range dummy.passiveView() {
+		if dummy.knownPeers[addr].dialing {
+			missing--
+			continue
+		}
+		if dummy.rootStrat.reputation.banned(horizontalapi.ConnectionId(addr)) {
+			continue
+		}
+		candidates = append(candidates, addr)
+	}
+	if missing <= 0 || len(candidates) == 0 {
+		return
+	}
+	dummy.rootStrat.log.Info("Too few peers, connecting to known peers", "missing", missing, "candidates", len(candidates))
+
+	mrand.Shuffle(len(candidates), func(i, j int) { candidates[i], candidates[j] = candidates[j], candidates[i] })
+	for _, addr := range candidates[:min(missing, len(candidates))] {
+		kp := dummy.knownPeers[addr]
+		kp.dialing = true
+		go func(addr string) {
+			var err error
+			connected := false
+			// configured addresses might be host names -> resolve them first
+			// to check whether there already is a connection
+			tcpAddr, rerr := net.ResolveTCPAddr("tcp", addr)
+			if rerr == nil {
+				dummy.runInLoop(func() {
+					kp.id = horizontalapi.ConnectionId(tcpAddr.String())
+					connected = dummy.connManager.ConnectedTo(kp.id)
+				})
+			}
+			if !connected {
+				err = dummy.AddPeer(addr)
+			}
+			dummy.runInLoop(func() {
+				kp.dialing = false
+				if err == nil {
+					return
+				}
+				dummy.rootStrat.log.Debug("Connecting to known peer failed", "addr", addr, "err", err)
+				if !kp.configured {
+					delete(dummy.knownPeers, addr)
+				}
+			})
+		}(addr)
+	}
+}
@@ -0,0 +1,122 @@
+/*
+* gossip
+* Copyright (C) 2024 Fabio Gaiba and Lukas Heindl
+*
+* This program is free software: you can redistribute it and/or modify
+* it under the terms of the GNU General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* This program is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+* GNU General Public License for more details.
+*
+* You should have received a copy of the GNU General Public License
+* along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package strats
+
+import (
+	horizontalapi "gossip/horizontalAPI"
+	"testing"
+	"time"
+)
+
+func TestAdmitInbound(test *testing.T) {
+	conn := func(id string) horizontalapi.Conn[chan<- horizontalapi.ToHz] {
+		return horizontalapi.Conn[chan<- horizontalapi.ToHz]{Id: horizontalapi.ConnectionId(id)}
+	}
+	// one outgoing connection and three incoming ones, two of them from the
+	// same subnet
+	manager := NewConnectionManager([]horizontalapi.Conn[chan<- horizontalapi.ToHz]{conn("10.0.0.1:6001")}, nil)
+	manager.AddInProgress(&gossipConnection{connection: conn("10.1.0.1:1000")})
+	manager.AddInProgress(&gossipConnection{connection: conn("10.1.0.2:1000")})
+	manager.AddInProgress(&gossipConnection{connection: conn("10.2.0.1:1000")})
+	manager.MakeValid("10.1.0.1:1000", time.Now())
+
+	if evict, ok := manager.AdmitInbound("10.3.0.1:1000"); !ok || evict != nil {
+		test.Fatalf("Without limits every connection should be accepted (evict: %v)", evict)
+	}
+
+	manager.SetPeerLimits(2, 3, 4)
+	if missing := manager.PeersMissing(); missing != 0 {
+		test.Fatalf("No peers should be missing with 4 connections, but %d are", missing)
+	}
+
+	// the subnet of the new connection already has the most connections
+	if evict, ok := manager.AdmitInbound("10.1.0.3:1000"); ok {
+		test.Fatalf("Connection of the largest subnet should be refused (evict: %v)", evict)
+	}
+	// the subnet of the new connection would be as large as the others
+	if _, ok := manager.AdmitInbound("10.2.0.2:1000"); ok {
+		test.Fatalf("Connection which does not improve the diversity should be refused")
+	}
+
+	// a connection of a new subnet replaces one of the largest subnet, the
+	// one which did not provide a PoW yet is evicted first
+	evict, ok := manager.AdmitInbound("10.3.0.1:1000")
+	if !ok || evict == nil || evict.connection.Id != "10.1.0.2:1000" {
+		test.Fatalf("10.1.0.2:1000 should have been evicted (ok: %v, evict: %v)", ok, evict)
+	}
+
+	// outgoing connections are never evicted
+	manager.Remove("10.1.0.2:1000")
+	manager.Remove("10.2.0.1:1000")
+	manager.AddToBeProved(&gossipConnection{connection: conn("10.4.0.1:6001"), outgoing: true})
+	manager.AddToBeProved(&gossipConnection{connection: conn("10.5.0.1:6001"), outgoing: true})
+	if evict, ok := manager.AdmitInbound("10.6.0.1:1000"); ok {
+		test.Fatalf("Connection should be refused since only one incoming connection exists (evict: %v)", evict)
+	}
+
+	if !manager.ConnectedTo("10.4.0.1:6001") || manager.ConnectedTo("10.6.0.1:6001") {
+		test.Fatalf("ConnectedTo should report the dialed addresses")
+	}
+
+	manager.Remove("10.4.0.1:6001")
+	manager.Remove("10.5.0.1:6001")
+	manager.Remove("10.0.0.1:6001")
+	if missing := manager.PeersMissing(); missing != 2 {
+		test.Fatalf("With one connection, 2 peers should be missing to reach the target, but %d are", missing)
+	}
+}
+
+func TestSubnetOf(test *testing.T) {
+	tests := []struct {
+		a, b string
+		same bool
+	}{
+		{"10.0.1.1:1000", "10.0.2.2:2000", true},
+		{"10.0.1.1:1000", "10.1.1.1:1000", false},
+		{"[::ffff:10.0.1.1]:1000", "10.0.2.2:2000", true},
+		{"[2001:db8:1::1]:1000", "[2001:db8:2::1]:1000", true},
+		{"[2001:db8::1]:1000", "[2001:db9::1]:1000", false},
+		{"node1:6001", "node2:6001", false},
+	}
+	for _, tt := range tests {
+		same := subnetOf(horizontalapi.ConnectionId(tt.a)) == subnetOf(horizontalapi.ConnectionId(tt.b))
+		if same != tt.same {
+			test.Fatalf("%s and %s should be in the same subnet: %v, but are: %v", tt.a, tt.b, tt.same, same)
+		}
+	}
+}
+
+func TestAdvertisedAddr(test *testing.T) {
+	tests := []struct {
+		addr   string
+		id     horizontalapi.ConnectionId
+		should string
+	}{
+		{"10.0.0.1:6001", "10.0.0.1:50000", "10.0.0.1:6001"},
+		{"0.0.0.0:6001", "10.0.0.1:50000", "10.0.0.1:6001"},
+		{"[::]:6001", "[2001:db8::1]:50000", "[2001:db8::1]:6001"},
+		{":6001", "10.0.0.1:50000", "10.0.0.1:6001"},
+		{"", "10.0.0.1:50000", ""},
+	}
+	for _, tt := range tests {
+		if is := advertisedAddr(tt.addr, tt.id); is != tt.should {
+			test.Fatalf("Advertised address %q on %s should be %q but is %q", tt.addr, tt.id, tt.should, is)
+		}
+	}
+}
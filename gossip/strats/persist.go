@@ -0,0 +1,92 @@
+/*
+* gossip
+* Copyright (C) 2024 Fabio Gaiba and Lukas Heindl
+*
+* This program is free software: you can redistribute it and/or modify
+* it under the terms of the GNU General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* This program is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+* GNU General Public License for more details.
+*
+* You should have received a copy of the GNU General Public License
+* along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package strats
+
+import (
+	horizontalapi "gossip/horizontalAPI"
+	"time"
+)
+
+// State of a message as it is recorded in the message store
+type messageState uint8
+
+const (
+	// message was received and passed to the modules for validation
+	messageSeen messageState = iota
+	// message is valid and waits to be forwarded
+	messageValid
+	// message was forwarded (or must not be forwarded anymore)
+	messageSent
+)
+
+// Entry of the message store. The last entry of a message id determines the
+// state of the message.
+type persistedMessage struct {
+	State    messageState       `json:"state"`
+	Message  horizontalapi.Push `json:"message"`
+	Received time.Time          `json:"received"`
+}
+
+// Record the new state of a message in the message store.
+//
+// The payload is not stored for messages which were not validated yet since
+// it is neither forwarded nor replayed to modules after a restart.
+func (dummy *dummyStrat) persist(state messageState, msg *storedMessage) {
+	rec := persistedMessage{State: state, Message: msg.message, Received: msg.timestamp}
+	if state == messageSeen {
+		rec.Message.Payload = nil
+	}
+	if err := dummy.rootStrat.messageStore.Append(rec); err != nil {
+		dummy.rootStrat.log.Warn("Persisting the message failed", "err", err, "Message ID", msg.message.MessageID)
+	}
+}
+
+// Reload the messages from the message store.
+//
+// Valid messages which were not forwarded yet are put in the valid queue
+// again, forwarded ones in the sent messages. Messages which were not
+// validated yet are only used to recognize them if they are received again.
+func (dummy *dummyStrat) restore() {
+	latest := make(map[uint16]persistedMessage)
+	order := make([]uint16, 0)
+	for _, rec := range dummy.rootStrat.messageStore.Values() {
+		if _, ok := latest[rec.Message.MessageID]; !ok {
+			order = append(order, rec.Message.MessageID)
+		}
+		latest[rec.Message.MessageID] = rec
+	}
+
+	for _, id := range order {
+		rec := latest[id]
+		stored := &storedMessage{message: rec.Message, timestamp: rec.Received}
+		switch rec.State {
+		case messageValid:
+			dummy.validMessages.Insert(stored)
+		case messageSent:
+			dummy.sentMessages.Insert(stored)
+		default:
+			// never validated -> must not be replayed to modules
+			stored.unvalidated = true
+			dummy.sentMessages.Insert(stored)
+		}
+	}
+	if len(order) > 0 {
+		dummy.rootStrat.log.Info("Restored messages from the message store", "amount", len(order))
+	}
+}
@@ -0,0 +1,126 @@
+/*
+* gossip
+* Copyright (C) 2024 Fabio Gaiba and Lukas Heindl
+*
+* This program is free software: you can redistribute it and/or modify
+* it under the terms of the GNU General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* This program is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+* GNU General Public License for more details.
+*
+* You should have received a copy of the GNU General Public License
+* along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package strats
+
+import (
+	horizontalapi "gossip/horizontalAPI"
+)
+
+// The plumtree strategy (Epidemic Broadcast Trees, Leitão et al.) uses the
+// same connections, handshake and membership as the dummy strategy but
+// disseminates the messages differently: instead of pushing each message to
+// Degree random peers, it is pushed eagerly along a spanning tree and only
+// announced (IHave) to the remaining (lazy) peers.
+//
+// The tree is built and repaired on the fly:
+//   - receiving a message a second time prunes the link it was received on
+//     (the peer is asked to treat the connection as lazy as well)
+//   - a message which was announced but not received within MISSING_ROUNDS
+//     gossip rounds is requested (Graft), adding the link to the tree again
+
+// This struct contains the fields used by the Plumtree Strategy in addition to
+// the ones of the Dummy Strategy. Whether a link is part of the tree is kept
+// in gossipConnection.lazy.
+type plumtreeStrat struct {
+	*dummyStrat
+}
+
+// Function to instantiate a new Plumtree Strategy.
+//
+// Takes the same arguments as [NewDummy]. All peers start as eager peers
+// (part of the tree), redundant links are pruned with the first messages.
+func NewPlumtree(strategy Strategy, fromHz <-chan horizontalapi.FromHz, connManager *ConnectionManager) *plumtreeStrat {
+	pt := &plumtreeStrat{dummyStrat: NewDummy(strategy, fromHz, connManager)}
+	pt.dissemination = pt
+	return pt
+}
+
+// Push msg to all eager peers and announce it to the lazy ones. The peer the
+// message was received from is skipped.
+func (pt *plumtreeStrat) disseminate(msg *storedMessage) bool {
+	selected, sent := 0, 0
+	pt.connManager.ActionOnValid(func(peer *gossipConnection) {
+		if peer.connection.Id == msg.message.Id {
+			return
+		}
+		selected++
+		if peer.lazy {
+			pt.announce(peer, msg.message.MessageID)
+			sent++
+		} else if pt.push(peer, msg) {
+			sent++
+		}
+	})
+	return selected == 0 || sent > 0
+}
+
+// A new message was received from peer: the link is (again) part of the tree
+func (pt *plumtreeStrat) receivedNew(peer *gossipConnection, msg horizontalapi.Push) {
+	peer.lazy = false
+}
+
+// A known message was received again from peer: the link is redundant, so
+// it is pruned on both ends
+func (pt *plumtreeStrat) receivedDuplicate(peer *gossipConnection, msg horizontalapi.Push) {
+	if peer.lazy {
+		return
+	}
+	pt.rootStrat.log.Debug("Pruning link which delivered a duplicate", "ConnId", peer.connection.Id)
+	peer.lazy = true
+	send(peer.connection, horizontalapi.Prune{})
+	pt.rootStrat.metrics.prunes.Inc()
+}
+
+// Announced messages are only requested if they don't arrive via the tree
+func (pt *plumtreeStrat) requestsAnnounced() bool {
+	return false
+}
+
+// Request the missing messages with msgIds from peer, the link becomes part
+// of the broadcast tree again
+func (pt *plumtreeStrat) requestFrom(peer *gossipConnection, msgIds []uint16) {
+	pt.rootStrat.log.Debug("Grafting link to request missing messages", "ConnId", peer.connection.Id, "msgIds", msgIds)
+	peer.lazy = false
+	send(peer.connection, horizontalapi.Graft{MessageIds: msgIds})
+	pt.rootStrat.metrics.grafts.Inc()
+}
+
+// Handle the Graft and Prune messages
+func (pt *plumtreeStrat) handleMessage(peer *gossipConnection, msg horizontalapi.FromHz) bool {
+	switch msg := msg.(type) {
+	case horizontalapi.Graft:
+		pt.handleGraft(peer, msg)
+	case horizontalapi.Prune:
+		pt.handlePrune(peer)
+	default:
+		return false
+	}
+	return true
+}
+
+// Add the link to the tree again and send the requested messages
+func (pt *plumtreeStrat) handleGraft(peer *gossipConnection, msg horizontalapi.Graft) {
+	peer.lazy = false
+	pt.sendRequested(peer, msg.MessageIds)
+}
+
+// Remove the link from the tree
+func (pt *plumtreeStrat) handlePrune(peer *gossipConnection) {
+	peer.lazy = true
+}
@@ -0,0 +1,76 @@
+/*
+* gossip
+* Copyright (C) 2024 Fabio Gaiba and Lukas Heindl
+*
+* This program is free software: you can redistribute it and/or modify
+* it under the terms of the GNU General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* This program is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+* GNU General Public License for more details.
+*
+* You should have received a copy of the GNU General Public License
+* along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package strats
+
+import (
+	horizontalapi "gossip/horizontalAPI"
+	"gossip/internal/args"
+	"testing"
+)
+
+func TestPlumtree(test *testing.T) {
+	a := args.NewFromDefaults()
+	connManager := NewConnectionManager(nil, nil)
+	dummy := NewPlumtree(testStrategy(test, a), nil, &connManager)
+
+	// sender of the message, eager peer and lazy peer
+	peers := newTestPeers(test, &connManager, "sender", "eager", "lazy")
+	valid, expect := peers.valid, peers.expect
+	dummy.handlePrune(valid("lazy"))
+
+	// pushed to the eager peer, announced to the lazy one
+	msg := horizontalapi.Push{Id: "sender", MessageID: 1, GossipType: 42, TTL: 3}
+	insertValid(dummy.dummyStrat, msg)
+	dummy.forwardValid()
+	expect("sender", nil)
+	expect("eager", msg)
+	expect("lazy", horizontalapi.IHave{MessageIds: []uint16{1}})
+
+	// a duplicate prunes the link
+	eager := valid("eager")
+	dummy.receivedDuplicate(eager, msg)
+	expect("eager", horizontalapi.Prune{})
+	if !eager.lazy {
+		test.Fatalf("link which delivered a duplicate was not pruned")
+	}
+
+	// a grafted peer gets the requested message once if it was announced
+	// to it
+	dummy.handleGraft(valid("lazy"), horizontalapi.Graft{Id: "lazy", MessageIds: []uint16{1, 2}})
+	expect("lazy", msg)
+	expect("lazy", nil)
+	if valid("lazy").lazy {
+		test.Fatalf("grafted link is still lazy")
+	}
+	dummy.handleGraft(valid("lazy"), horizontalapi.Graft{Id: "lazy", MessageIds: []uint16{1}})
+	expect("lazy", nil)
+	dummy.handleGraft(valid("eager"), horizontalapi.Graft{Id: "eager", MessageIds: []uint16{1}})
+	expect("eager", nil)
+
+	// announced messages which don't arrive are requested
+	dummy.handleIHave(valid("lazy"), horizontalapi.IHave{Id: "lazy", MessageIds: []uint16{1, 5}})
+	for i := 0; i < MISSING_ROUNDS; i++ {
+		expect("lazy", nil)
+		dummy.requestMissing()
+	}
+	expect("lazy", horizontalapi.Graft{MessageIds: []uint16{5}})
+	if len(dummy.lazyPush.missing) != 0 {
+		test.Fatalf("message should not be requested again since nobody else announced it")
+	}
+}
@@ -0,0 +1,114 @@
+/*
+* gossip
+* Copyright (C) 2024 Fabio Gaiba and Lukas Heindl
+*
+* This program is free software: you can redistribute it and/or modify
+* it under the terms of the GNU General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* This program is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+* GNU General Public License for more details.
+*
+* You should have received a copy of the GNU General Public License
+* along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package strats
+
+import (
+	"context"
+	"gossip/common"
+	horizontalapi "gossip/horizontalAPI"
+	"gossip/internal/args"
+	"gossip/internal/packetcounter"
+	"gossip/internal/ratelimit"
+	"log/slog"
+	"time"
+)
+
+// This struct bundles the token buckets used to limit the rate of push
+// messages a strategy accepts from and forwards to its peers.
+//
+// Incoming messages are limited per peer connection and per gossip type,
+// outgoing (forwarded) messages as well. Use [newPushLimiter] to instantiate
+// it.
+type pushLimiter struct {
+	inPeer  *ratelimit.Limiter[horizontalapi.ConnectionId]
+	inType  *ratelimit.Limiter[common.GossipType]
+	outPeer *ratelimit.Limiter[horizontalapi.ConnectionId]
+	outType *ratelimit.Limiter[common.GossipType]
+	// keep some stats of dropped packets
+	inDropped  *packetcounter.Counter
+	outDropped *packetcounter.Counter
+}
+
+// Create the rate limiters with the limits configured in args. The stats of
+// the dropped packets are logged on log.
+func newPushLimiter(log *slog.Logger, args args.Args) *pushLimiter {
+	return &pushLimiter{
+		inPeer:  ratelimit.NewLimiter[horizontalapi.ConnectionId](args.In_peer_rate, args.In_peer_burst),
+		inType:  ratelimit.NewLimiter[common.GossipType](args.In_type_rate, args.In_type_burst),
+		outPeer: ratelimit.NewLimiter[horizontalapi.ConnectionId](args.Out_peer_rate, args.Out_peer_burst),
+		outType: ratelimit.NewLimiter[common.GossipType](args.Out_type_rate, args.Out_type_burst),
+		inDropped: packetcounter.NewCounter(func(t time.Time, cnt uint) {
+			log.Log(context.Background(), common.LevelTest, "push dropped incoming", "timeBucket", t, "cnt", cnt)
+		}, 1*time.Second),
+		outDropped: packetcounter.NewCounter(func(t time.Time, cnt uint) {
+			log.Log(context.Background(), common.LevelTest, "push dropped outgoing", "timeBucket", t, "cnt", cnt)
+		}, 1*time.Second),
+	}
+}
+
+// Check if an incoming push message may be processed with regard to the
+// limit of the peer which sent it. If not, the drop is counted.
+func (l *pushLimiter) allowIncomingPeer(msg horizontalapi.Push) bool {
+	if l.inPeer.Allow(msg.Id) {
+		return true
+	}
+	l.inDropped.Add(1)
+	return false
+}
+
+// Check if a new (not yet seen) push message may be passed on to the modules
+// with regard to the limit of its gossip type. If not, the drop is counted.
+//
+// Duplicates are not charged here, so the redundancy of the gossip does not
+// use up the budget of a gossip type.
+func (l *pushLimiter) allowIncomingType(msg horizontalapi.Push) bool {
+	if l.inType.Allow(msg.GossipType) {
+		return true
+	}
+	l.inDropped.Add(1)
+	return false
+}
+
+// Check if a message of gossip type gtype may be forwarded in this round.
+// Messages which are not allowed stay queued (they are not dropped).
+func (l *pushLimiter) allowOutgoingType(gtype common.GossipType) bool {
+	return l.outType.Allow(gtype)
+}
+
+// Check if a push message may be sent to peer id. If not, the drop is
+// counted.
+func (l *pushLimiter) allowOutgoingPeer(id horizontalapi.ConnectionId) bool {
+	if l.outPeer.Allow(id) {
+		return true
+	}
+	l.outDropped.Add(1)
+	return false
+}
+
+// Remove all state kept for the connection id
+func (l *pushLimiter) forget(id horizontalapi.ConnectionId) {
+	l.inPeer.Forget(id)
+	l.outPeer.Forget(id)
+}
+
+// Flush the stats of dropped packets
+func (l *pushLimiter) finalize() {
+	l.inDropped.Finalize()
+	l.outDropped.Finalize()
+}
@@ -0,0 +1,143 @@
+/*
+* gossip
+* Copyright (C) 2024 Fabio Gaiba and Lukas Heindl
+*
+* This program is free software: you can redistribute it and/or modify
+* it under the terms of the GNU General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* This program is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+* GNU General Public License for more details.
+*
+* You should have received a copy of the GNU General Public License
+* along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package strats
+
+import (
+	"errors"
+	horizontalapi "gossip/horizontalAPI"
+	"gossip/internal/args"
+	"net"
+	"slices"
+)
+
+// Strategies implementing this interface can apply a changed configuration
+// while they are running.
+type StrategyReloader interface {
+	// Apply the options of args which can be changed at runtime (degree,
+	// cache size, gossip timer, forwarding, peer limits and the peers). All
+	// other options are ignored.
+	Reload(args args.Args) error
+}
+
+// Apply the options of a which can be changed at runtime.
+//
+// Peers which were added to the peer list are connected to (like via
+// [dummyStrat.AddPeer]), connections to peers which were removed from the list
+// are closed.
+func (dummy *dummyStrat) Reload(a args.Args) error {
+	var added []string
+	err := dummy.runInLoop(func() {
+		log := dummy.rootStrat.log
+		old := dummy.rootStrat.stratArgs
+
+		if a.Degree != old.Degree {
+			log.Info("Changing degree", "old", old.Degree, "new", a.Degree)
+			dummy.rootStrat.stratArgs.Degree = a.Degree
+		}
+
+		if a.Cache_size != old.Cache_size {
+			log.Info("Changing cache size", "old", old.Cache_size, "new", a.Cache_size)
+			dummy.rootStrat.stratArgs.Cache_size = a.Cache_size
+			dummy.invalidMessages.Resize(a.Cache_size)
+			dummy.validMessages.Resize(a.Cache_size)
+			dummy.sentMessages.Resize(a.Cache_size)
+		}
+
+		if a.GossipTimer != old.GossipTimer {
+			if a.GossipTimer <= 0 {
+				log.Warn("Not changing gossip timer, it must be greater than 0")
+			} else {
+				log.Info("Changing gossip timer", "old", old.GossipTimer, "new", a.GossipTimer)
+				dummy.rootStrat.stratArgs.GossipTimer = a.GossipTimer
+				dummy.ticker.Reset(a.GossipTimer)
+			}
+		}
+
+		if a.Eager_forward != old.Eager_forward || a.Forward_window != old.Forward_window {
+			if a.Forward_window < 0 {
+				log.Warn("Not changing forwarding, the forward window must not be negative")
+			} else {
+				log.Info("Changing forwarding", "eager", a.Eager_forward, "window", a.Forward_window)
+				dummy.rootStrat.stratArgs.Eager_forward = a.Eager_forward
+				dummy.rootStrat.stratArgs.Forward_window = a.Forward_window
+			}
+		}
+
+		if a.Min_peers != old.Min_peers || a.Target_peers != old.Target_peers || a.Max_peers != old.Max_peers {
+			log.Info("Changing peer limits", "min", a.Min_peers, "target", a.Target_peers, "max", a.Max_peers)
+			dummy.rootStrat.stratArgs.Min_peers = a.Min_peers
+			dummy.rootStrat.stratArgs.Target_peers = a.Target_peers
+			dummy.rootStrat.stratArgs.Max_peers = a.Max_peers
+			dummy.connManager.SetPeerLimits(a.Min_peers, a.Target_peers, a.Max_peers)
+		}
+
+		for _, addr := range a.Peer_addrs {
+			if !slices.Contains(old.Peer_addrs, addr) {
+				added = append(added, addr)
+			}
+		}
+		for _, addr := range old.Peer_addrs {
+			if slices.Contains(a.Peer_addrs, addr) {
+				continue
+			}
+			// the peer is found by the address it advertised (incoming
+			// connections) or the dialed address, which is resolved in
+			// the connection id
+			ids := []horizontalapi.ConnectionId{horizontalapi.ConnectionId(addr)}
+			if tcpAddr, err := net.ResolveTCPAddr("tcp", addr); err == nil {
+				ids = append(ids, horizontalapi.ConnectionId(tcpAddr.String()))
+			}
+			if kp, ok := dummy.knownPeers[addr]; ok && kp.id != "" {
+				ids = append(ids, kp.id)
+			}
+			// don't connect to the peer again when looking for peers
+			delete(dummy.knownPeers, addr)
+
+			disconnected := false
+			for _, id := range ids {
+				for _, conn := range dummy.connManager.ConnectionsTo(id) {
+					peer, err := dummy.connManager.Remove(conn.connection.Id)
+					if err != nil {
+						continue
+					}
+					log.Info("Disconnecting removed peer", "addr", addr, "ConnId", peer.connection.Id)
+					peer.connection.Cfunc()
+					disconnected = true
+				}
+			}
+			if !disconnected {
+				log.Info("Removed peer was not connected", "addr", addr)
+			}
+		}
+		dummy.rootStrat.stratArgs.Peer_addrs = a.Peer_addrs
+	})
+	if err != nil {
+		return err
+	}
+
+	// dial outside of the loop, the strategy should not block meanwhile
+	var errs []error
+	for _, addr := range added {
+		if err := dummy.AddPeer(addr); err != nil {
+			dummy.rootStrat.log.Warn("Connecting to added peer failed", "addr", addr, "err", err)
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,243 @@
+/*
+* gossip
+* Copyright (C) 2024 Fabio Gaiba and Lukas Heindl
+*
+* This program is free software: you can redistribute it and/or modify
+* it under the terms of the GNU General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* This program is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+* GNU General Public License for more details.
+*
+* You should have received a copy of the GNU General Public License
+* along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package strats
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	horizontalapi "gossip/horizontalAPI"
+	"gossip/internal/args"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Events which influence the reputation of a peer
+type reputationEvent int
+
+const (
+	// a message of the peer was marked valid by the modules
+	reputationValidMessage reputationEvent = iota
+	// a message of the peer was marked invalid by the modules
+	reputationInvalidMessage
+	// the peer provided a valid PoW
+	reputationValidPow
+	// the peer provided an invalid PoW (or an invalid cookie)
+	reputationInvalidPow
+	// the peer provided a PoW too late
+	reputationLatePow
+	// the peer sent a message it was not supposed to send (e.g. an
+	// unsolicited PowChall)
+	reputationUnsolicited
+)
+
+// Change of the score for each event
+var reputationDelta = map[reputationEvent]int{
+	reputationValidMessage:   1,
+	reputationInvalidMessage: -10,
+	reputationValidPow:       1,
+	reputationInvalidPow:     -25,
+	reputationLatePow:        -5,
+	reputationUnsolicited:    -10,
+}
+
+// bounds for the score of a peer
+const (
+	REPUTATION_MIN = -100
+	REPUTATION_MAX = 100
+)
+
+// This struct keeps track of the reputation of the peers and bans peers
+// whose score drops to (or below) the configured threshold.
+//
+// Peers are identified by their host (the connection id without the port)
+// since the port of incoming connections changes with every reconnect.
+//
+// The ban list is persisted to a file (if configured), so bans survive a
+// restart. Use [newReputationBook] to instantiate it. All methods are
+// nil-safe, a nil book never bans anybody.
+type reputationBook struct {
+	scores map[string]int
+	// peers which are banned until the respective point in time
+	bans map[string]time.Time
+	// score at which a peer gets banned
+	banScore int
+	// how long a ban lasts
+	banTime time.Duration
+	// where to persist the ban list, empty if the list should not be persisted
+	banFile string
+	log     *slog.Logger
+	mutex   sync.Mutex
+}
+
+// Entry of the persisted ban list
+type banEntry struct {
+	Peer  string    `json:"peer"`
+	Until time.Time `json:"until"`
+}
+
+// Create a new reputation book with the thresholds configured in args. If a
+// ban file is configured, the bans stored there are loaded.
+func newReputationBook(log *slog.Logger, args args.Args) (*reputationBook, error) {
+	book := &reputationBook{
+		scores:   make(map[string]int),
+		bans:     make(map[string]time.Time),
+		banScore: args.Ban_score,
+		banTime:  time.Duration(args.Ban_time) * time.Second,
+		banFile:  args.Ban_file,
+		log:      log,
+	}
+	if err := book.load(); err != nil {
+		return nil, err
+	}
+	return book, nil
+}
+
+// obtain the identity of a peer from the connection id.
+//
+// Peers are identified by their host only, so all nodes behind the same
+// address (e.g. a NAT) share one score and are banned together. The node id
+// is not used on purpose: banned peers are refused before the handshake which
+// reveals it, and a peer may choose a new node id at will.
+func reputationKey(id horizontalapi.ConnectionId) string {
+	return hostOf(id)
+}
+
+// Record an event for the peer with connection id.
+//
+// Returns true if the peer got banned because of this event.
+func (book *reputationBook) record(id horizontalapi.ConnectionId, event reputationEvent) bool {
+	if book == nil {
+		return false
+	}
+	book.mutex.Lock()
+	defer book.mutex.Unlock()
+
+	key := reputationKey(id)
+	score := min(REPUTATION_MAX, max(REPUTATION_MIN, book.scores[key]+reputationDelta[event]))
+	if score > book.banScore {
+		book.scores[key] = score
+		return false
+	}
+
+	// peer starts with a clean slate after the ban
+	delete(book.scores, key)
+	book.bans[key] = time.Now().Add(book.banTime)
+	book.log.Info("Banning peer", "peer", key, "until", book.bans[key])
+	if err := book.unsafeSave(); err != nil {
+		book.log.Warn("Persisting the ban list failed", "err", err)
+	}
+	return true
+}
+
+// Returns weather the peer with connection id is currently banned
+func (book *reputationBook) banned(id horizontalapi.ConnectionId) bool {
+	if book == nil {
+		return false
+	}
+	book.mutex.Lock()
+	defer book.mutex.Unlock()
+
+	key := reputationKey(id)
+	until, ok := book.bans[key]
+	if !ok {
+		return false
+	}
+	if time.Now().After(until) {
+		delete(book.bans, key)
+		return false
+	}
+	return true
+}
+
+// Weight of the peer with connection id when selecting peers. The weight is
+// always > 0 and grows with the score of the peer.
+func (book *reputationBook) weight(id horizontalapi.ConnectionId) float64 {
+	if book == nil {
+		return 1
+	}
+	book.mutex.Lock()
+	defer book.mutex.Unlock()
+
+	return float64(max(1, book.scores[reputationKey(id)]-book.banScore))
+}
+
+// load the ban list from the ban file, expired bans are skipped
+func (book *reputationBook) load() error {
+	if book.banFile == "" {
+		return nil
+	}
+	data, err := os.ReadFile(book.banFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("reading the ban list failed: %w", err)
+	}
+
+	var entries []banEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return fmt.Errorf("parsing the ban list %s failed: %w", book.banFile, err)
+	}
+	now := time.Now()
+	for _, e := range entries {
+		if e.Until.After(now) {
+			book.bans[e.Peer] = e.Until
+		}
+	}
+	return nil
+}
+
+// Write the ban list to the ban file (without locking the mutex).
+//
+// The list is written to a temporary file first which is then moved, so the
+// ban file is never only partially written.
+func (book *reputationBook) unsafeSave() error {
+	if book.banFile == "" {
+		return nil
+	}
+	now := time.Now()
+	entries := make([]banEntry, 0, len(book.bans))
+	for peer, until := range book.bans {
+		if until.After(now) {
+			entries = append(entries, banEntry{Peer: peer, Until: until})
+		}
+	}
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(book.banFile), ".bans-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), book.banFile)
+}
@@ -0,0 +1,79 @@
+/*
+* gossip
+* Copyright (C) 2024 Fabio Gaiba and Lukas Heindl
+*
+* This program is free software: you can redistribute it and/or modify
+* it under the terms of the GNU General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* This program is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+* GNU General Public License for more details.
+*
+* You should have received a copy of the GNU General Public License
+* along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package strats
+
+import (
+	"gossip/internal/args"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/neilotoole/slogt"
+)
+
+func TestReputation(test *testing.T) {
+	a := args.NewFromDefaults()
+	a.Ban_file = filepath.Join(test.TempDir(), "bans.json")
+
+	book, err := newReputationBook(slogt.New(test), a)
+	if err != nil {
+		test.Fatalf("Creating the reputation book failed: %v", err)
+	}
+
+	// peers are identified by their host, the port does not matter
+	if book.record("10.0.0.1:1234", reputationInvalidPow) {
+		test.Fatalf("Peer should not be banned after a single invalid pow")
+	}
+	if !book.record("10.0.0.1:4321", reputationInvalidPow) {
+		test.Fatalf("Peer should be banned after reaching the ban score")
+	}
+	if !book.banned("10.0.0.1:5555") {
+		test.Fatalf("Peer should be banned independent of the port")
+	}
+	if book.banned("10.0.0.2:1234") {
+		test.Fatalf("Other peers should not be banned")
+	}
+
+	// well behaving peers are preferred
+	book.record("10.0.0.3:1234", reputationValidMessage)
+	if book.weight("10.0.0.3:1234") <= book.weight("10.0.0.2:1234") {
+		test.Fatalf("Peer with higher score should have a higher weight")
+	}
+
+	// the ban list survives a restart
+	book, err = newReputationBook(slogt.New(test), a)
+	if err != nil {
+		test.Fatalf("Loading the ban list failed: %v", err)
+	}
+	if !book.banned("10.0.0.1:1234") {
+		test.Fatalf("Ban was not persisted")
+	}
+
+	// bans expire
+	book.bans["10.0.0.1"] = time.Now().Add(-time.Second)
+	if book.banned("10.0.0.1:1234") {
+		test.Fatalf("Ban should have expired")
+	}
+
+	// a nil book never bans
+	var nilBook *reputationBook
+	if nilBook.record("10.0.0.1:1234", reputationInvalidPow) || nilBook.banned("10.0.0.1:1234") {
+		test.Fatalf("nil book should never ban")
+	}
+}
@@ -0,0 +1,164 @@
+/*
+* gossip
+* Copyright (C) 2024 Fabio Gaiba and Lukas Heindl
+*
+* This program is free software: you can redistribute it and/or modify
+* it under the terms of the GNU General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* This program is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+* GNU General Public License for more details.
+*
+* You should have received a copy of the GNU General Public License
+* along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package strats
+
+import (
+	"encoding/binary"
+	"fmt"
+	horizontalapi "gossip/horizontalAPI"
+	mrand "math/rand"
+)
+
+// The rumor strategy (rumor mongering with feedback and coin, Demers et al.)
+// uses the same connections, handshake and membership as the dummy strategy
+// but keeps pushing each message to Degree random peers in every gossip round
+// as long as the message is a hot rumor.
+//
+// Peers which receive a message they already know tell the sender
+// (Feedback). For each such feedback the sender loses interest in the rumor
+// with probability 1/Rumor_k and stops spreading it. This way a message stops
+// spreading once most of the network knows it, without a tuned TTL.
+
+// Feedback message (extension of kind EXTENSION_FEEDBACK). It tells the
+// receiver that the pushed messages with messageIds were already known.
+type feedbackMsg struct {
+	messageIds []uint16
+}
+
+func (feedbackMsg) Kind() horizontalapi.ExtensionKind { return EXTENSION_FEEDBACK }
+
+// the message ids are sent one after another (2 bytes each)
+func (m feedbackMsg) MarshalBinary() ([]byte, error) {
+	data := make([]byte, 0, 2*len(m.messageIds))
+	for _, id := range m.messageIds {
+		data = binary.BigEndian.AppendUint16(data, id)
+	}
+	return data, nil
+}
+
+func decodeFeedback(data []byte) (horizontalapi.ExtensionMessage, error) {
+	if len(data)%2 != 0 {
+		return nil, fmt.Errorf("feedback of %d bytes is no list of message ids", len(data))
+	}
+	m := feedbackMsg{messageIds: make([]uint16, 0, len(data)/2)}
+	for i := 0; i < len(data); i += 2 {
+		m.messageIds = append(m.messageIds, binary.BigEndian.Uint16(data[i:]))
+	}
+	return m, nil
+}
+
+// A message which is still spread
+type hotRumor struct {
+	msg *storedMessage
+	// peers the message was pushed to since their last feedback, only one
+	// feedback per push counts
+	recipients map[horizontalapi.ConnectionId]bool
+}
+
+// This struct contains the fields used by the Rumor Strategy in addition to
+// the ones of the Dummy Strategy.
+type rumorStrat struct {
+	*dummyStrat
+	// messages which are still spread (hot rumors) by their message id
+	hot map[uint16]*hotRumor
+	// a hot rumor is dropped with probability 1/k on each feedback
+	k uint
+}
+
+// Function to instantiate a new Rumor Strategy.
+//
+// Takes the same arguments as [NewDummy].
+func NewRumor(strategy Strategy, fromHz <-chan horizontalapi.FromHz, connManager *ConnectionManager) *rumorStrat {
+	rumor := &rumorStrat{
+		dummyStrat: NewDummy(strategy, fromHz, connManager),
+		hot:        make(map[uint16]*hotRumor),
+		k:          strategy.stratArgs.Rumor_k,
+	}
+	rumor.dissemination = rumor
+	rumor.mustRegisterExtension(EXTENSION_FEEDBACK, decodeFeedback, func(peer *gossipConnection, msg horizontalapi.ExtensionMessage) {
+		rumor.handleFeedback(peer, msg.(feedbackMsg))
+	})
+	return rumor
+}
+
+// Push msg to Degree random peers and keep spreading it in the next gossip
+// rounds
+func (rumor *rumorStrat) disseminate(msg *storedMessage) bool {
+	hot := &hotRumor{msg: msg, recipients: make(map[horizontalapi.ConnectionId]bool)}
+	if !rumor.pushRumor(hot) {
+		return false
+	}
+	rumor.hot[msg.message.MessageID] = hot
+	return true
+}
+
+// Push the hot rumors again. Rumors which are no longer in the cache are
+// dropped.
+func (rumor *rumorStrat) gossipRound() {
+	for id, hot := range rumor.hot {
+		if _, err := findFirstMessage(rumor.sentMessages, id); err != nil {
+			delete(rumor.hot, id)
+			continue
+		}
+		rumor.pushRumor(hot)
+	}
+}
+
+// Push the rumor to Degree peers chosen by the policy of its gossip type and
+// remember them as recipients. Returns false if the rate limits of the peers
+// prevented pushing it to any of them.
+func (rumor *rumorStrat) pushRumor(hot *hotRumor) bool {
+	selected, sent := 0, 0
+	rumor.connManager.ActionOnSelectedValid(rumor.selection.forType(hot.msg.message.GossipType), func(peer *gossipConnection) {
+		selected++
+		if rumor.push(peer, hot.msg) {
+			hot.recipients[peer.connection.Id] = true
+			sent++
+		}
+	}, int(rumor.rootStrat.stratArgs.Degree))
+	return selected == 0 || sent > 0
+}
+
+// A known message was received again from peer: tell the peer so it can lose
+// interest in spreading it
+func (rumor *rumorStrat) receivedDuplicate(peer *gossipConnection, msg horizontalapi.Push) {
+	send(peer.connection, horizontalapi.Extension{Message: feedbackMsg{messageIds: []uint16{msg.MessageID}}})
+}
+
+// The peer already knew the messages: lose interest in each of them with
+// probability 1/k. Only one feedback per push to the peer counts, so a single
+// peer cannot stop the dissemination.
+func (rumor *rumorStrat) handleFeedback(peer *gossipConnection, msg feedbackMsg) {
+	for _, msgId := range msg.messageIds {
+		hot, ok := rumor.hot[msgId]
+		if !ok {
+			continue
+		}
+		if !hot.recipients[peer.connection.Id] {
+			rumor.rootStrat.log.Debug("Ignoring feedback for rumor which was not pushed to the peer", "ConnId", peer.connection.Id, "msgId", msgId)
+			continue
+		}
+		delete(hot.recipients, peer.connection.Id)
+		rumor.rootStrat.metrics.feedback.Inc()
+		if mrand.Intn(int(rumor.k)) == 0 {
+			rumor.rootStrat.log.Debug("Lost interest in rumor", "msgId", msgId)
+			delete(rumor.hot, msgId)
+		}
+	}
+}
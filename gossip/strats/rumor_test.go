@@ -0,0 +1,65 @@
+/*
+* gossip
+* Copyright (C) 2024 Fabio Gaiba and Lukas Heindl
+*
+* This program is free software: you can redistribute it and/or modify
+* it under the terms of the GNU General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* This program is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+* GNU General Public License for more details.
+*
+* You should have received a copy of the GNU General Public License
+* along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package strats
+
+import (
+	horizontalapi "gossip/horizontalAPI"
+	"gossip/internal/args"
+	"reflect"
+	"testing"
+)
+
+func TestRumor(test *testing.T) {
+	a := args.NewFromDefaults()
+	// lose interest with the first feedback
+	a.Rumor_k = 1
+	connManager := NewConnectionManager(nil, nil)
+	dummy := NewRumor(testStrategy(test, a), nil, &connManager)
+	peers := newTestPeers(test, &connManager, "a")
+	valid, expect := peers.valid, peers.expect
+
+	// a hot rumor is pushed in every round
+	msg := horizontalapi.Push{MessageID: 1, GossipType: 42, TTL: 3}
+	insertValid(dummy.dummyStrat, msg)
+	dummy.forwardValid()
+	expect("a", msg)
+	dummy.gossipRound()
+	expect("a", msg)
+
+	// feedback of peers the rumor was not pushed to does not count
+	other := &gossipConnection{connection: horizontalapi.Conn[chan<- horizontalapi.ToHz]{Id: "b"}}
+	dummy.handleFeedback(other, feedbackMsg{messageIds: []uint16{1}})
+	dummy.gossipRound()
+	expect("a", msg)
+
+	// until the peer already knew it
+	dummy.handleFeedback(valid("a"), feedbackMsg{messageIds: []uint16{1}})
+	dummy.gossipRound()
+	expect("a", nil)
+
+	// known messages are reported to the sender
+	dummy.receivedDuplicate(valid("a"), msg)
+	expect("a", horizontalapi.Extension{Message: feedbackMsg{messageIds: []uint16{1}}})
+
+	// the message ids survive the encoding
+	data, _ := feedbackMsg{messageIds: []uint16{1, 65535}}.MarshalBinary()
+	if m, err := decodeFeedback(data); err != nil || !reflect.DeepEqual(m, feedbackMsg{messageIds: []uint16{1, 65535}}) {
+		test.Fatalf("decoded %+v (err %v) instead of the feedback", m, err)
+	}
+}
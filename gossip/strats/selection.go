@@ -0,0 +1,170 @@
+/*
+* gossip
+* Copyright (C) 2024 Fabio Gaiba and Lukas Heindl
+*
+* This program is free software: you can redistribute it and/or modify
+* it under the terms of the GNU General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* This program is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+* GNU General Public License for more details.
+*
+* You should have received a copy of the GNU General Public License
+* along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package strats
+
+import (
+	"cmp"
+	"gossip/common"
+	"gossip/internal/args"
+	"math"
+	mrand "math/rand"
+	"slices"
+	"time"
+)
+
+// Orders the valid peers if only some of them are used (e.g. the Degree peers
+// a message is forwarded to). Returns a permutation of the indices of peers,
+// preferred peers first.
+//
+// Policies are called with the connection manager locked, so they must not
+// use the connection manager themselves.
+type selectionPolicy func(peers []*gossipConnection) []int
+
+// The policies of the node, the same for whichever strategy is used (see
+// [args.SelectionPolicies])
+type peerSelection struct {
+	// policy used for gossip types without an own policy
+	def selectionPolicy
+	// policies of single gossip types
+	types map[common.GossipType]selectionPolicy
+}
+
+// Create the policies configured in a. reputation and limiter are used by the
+// reputation and bandwidth policies.
+func newPeerSelection(a args.Args, reputation *reputationBook, limiter *pushLimiter) *peerSelection {
+	policy := func(name string) selectionPolicy {
+		switch name {
+		case "random":
+			return randomPolicy
+		case "rtt":
+			return rttPolicy
+		case "lru":
+			return lruPolicy
+		case "bandwidth":
+			return bandwidthPolicy(limiter)
+		default:
+			return reputationPolicy(reputation)
+		}
+	}
+
+	// already validated
+	types, _ := a.PeerSelectionTypes()
+	sel := &peerSelection{
+		def:   policy(a.Peer_selection),
+		types: make(map[common.GossipType]selectionPolicy, len(types)),
+	}
+	for gtype, name := range types {
+		sel.types[gtype] = policy(name)
+	}
+	return sel
+}
+
+// The policy used to select the peers for messages of gossip type gtype
+func (sel *peerSelection) forType(gtype common.GossipType) selectionPolicy {
+	if p, ok := sel.types[gtype]; ok {
+		return p
+	}
+	return sel.def
+}
+
+// Uniformly random order
+func randomPolicy(peers []*gossipConnection) []int {
+	return mrand.Perm(len(peers))
+}
+
+// Random order where peers with a higher reputation are more likely to come
+// first. Without a reputation book this is the same as [randomPolicy].
+func reputationPolicy(book *reputationBook) selectionPolicy {
+	if book == nil {
+		return randomPolicy
+	}
+	return func(peers []*gossipConnection) []int {
+		return weightedPerm(peers, func(x *gossipConnection) float64 {
+			return book.weight(x.connection.Id)
+		})
+	}
+}
+
+// Random order where peers are weighted by the inverse of their round trip
+// time, so slow links are mostly avoided but still used sometimes. Peers whose
+// RTT was not measured yet are weighted like a peer with the average RTT.
+func rttPolicy(peers []*gossipConnection) []int {
+	var sum time.Duration
+	measured := 0
+	for _, x := range peers {
+		if x.rtt > 0 {
+			sum += x.rtt
+			measured++
+		}
+	}
+	if measured == 0 {
+		return randomPolicy(peers)
+	}
+	avg := sum / time.Duration(measured)
+	return weightedPerm(peers, func(x *gossipConnection) float64 {
+		rtt := x.rtt
+		if rtt == 0 {
+			rtt = avg
+		}
+		return 1 / rtt.Seconds()
+	})
+}
+
+// The peers which were used least recently (see gossipConnection.lastUsed)
+// come first, peers used at the same time are ordered randomly. This spreads
+// the load evenly among the peers.
+func lruPolicy(peers []*gossipConnection) []int {
+	perm := mrand.Perm(len(peers))
+	slices.SortStableFunc(perm, func(a, b int) int {
+		return peers[a].lastUsed.Compare(peers[b].lastUsed)
+	})
+	return perm
+}
+
+// Random order where peers are weighted by the fraction of their outgoing
+// rate limit (out_peer_rate) which is left, so peers which were sent a lot
+// recently are less likely to come first. Without a limit this is the same
+// as [randomPolicy].
+func bandwidthPolicy(limiter *pushLimiter) selectionPolicy {
+	return func(peers []*gossipConnection) []int {
+		return weightedPerm(peers, func(x *gossipConnection) float64 {
+			// exhausted peers still get a small chance
+			return max(0.01, limiter.outPeer.Available(x.connection.Id))
+		})
+	}
+}
+
+// Draw a random permutation of the indices of peers where each peer is
+// weighted by weight (which must be > 0).
+//
+// Uses the algorithm of Efraimidis and Spirakis: each element gets the key
+// u^(1/w) with u uniformly drawn from [0,1), the permutation is obtained by
+// sorting the keys in descending order.
+func weightedPerm(peers []*gossipConnection, weight func(x *gossipConnection) float64) []int {
+	perm := make([]int, len(peers))
+	keys := make([]float64, len(peers))
+	for i, x := range peers {
+		perm[i] = i
+		keys[i] = math.Pow(mrand.Float64(), 1/weight(x))
+	}
+	slices.SortFunc(perm, func(a, b int) int {
+		return cmp.Compare(keys[b], keys[a])
+	})
+	return perm
+}
@@ -0,0 +1,79 @@
+/*
+* gossip
+* Copyright (C) 2024 Fabio Gaiba and Lukas Heindl
+*
+* This program is free software: you can redistribute it and/or modify
+* it under the terms of the GNU General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* This program is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+* GNU General Public License for more details.
+*
+* You should have received a copy of the GNU General Public License
+* along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package strats
+
+import (
+	horizontalapi "gossip/horizontalAPI"
+	"gossip/internal/args"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+// how often peers[0] comes first in n orders drawn with policy
+func countFirst(policy selectionPolicy, peers []*gossipConnection, n int) int {
+	first := 0
+	for i := 0; i < n; i++ {
+		if policy(peers)[0] == 0 {
+			first++
+		}
+	}
+	return first
+}
+
+func TestSelectionPolicies(test *testing.T) {
+	peers := []*gossipConnection{
+		{connection: horizontalapi.Conn[chan<- horizontalapi.ToHz]{Id: "a"}},
+		{connection: horizontalapi.Conn[chan<- horizontalapi.ToHz]{Id: "b"}},
+	}
+
+	// fast links are preferred
+	peers[0].rtt = time.Millisecond
+	peers[1].rtt = time.Second
+	if n := countFirst(rttPolicy, peers, 1000); n < 900 {
+		test.Fatalf("peer with the lower RTT came first only %d of 1000 times", n)
+	}
+
+	// peers with exhausted outgoing limits are avoided
+	a := args.NewFromDefaults()
+	a.Out_peer_rate = 0.001
+	a.Out_peer_burst = 10
+	limiter := newPushLimiter(slog.Default(), a)
+	for i := 0; i < 10; i++ {
+		limiter.allowOutgoingPeer("b")
+	}
+	if n := countFirst(bandwidthPolicy(limiter), peers, 1000); n < 900 {
+		test.Fatalf("peer with the unused limit came first only %d of 1000 times", n)
+	}
+
+	// the policy of a gossip type overrides Peer_selection
+	a.Peer_selection = "random"
+	a.Peer_selection_types = "5=lru"
+	sel := newPeerSelection(a, nil, limiter)
+	peers[0].lastUsed = time.Now()
+	peers[1].lastUsed = time.Now().Add(-time.Second)
+	for i := 0; i < 10; i++ {
+		if perm := sel.forType(5)(peers); perm[0] != 1 {
+			test.Fatalf("least recently used peer should come first, order was %v", perm)
+		}
+	}
+	if n := countFirst(sel.forType(1), peers, 1000); n < 400 || n > 600 {
+		test.Fatalf("random policy put a peer first %d of 1000 times", n)
+	}
+}
@@ -48,6 +48,8 @@ const (
 	GossipNotifyType = 501
 	// MessageType for the [GossipValidation] packet.
 	GossipValidationType = 503
+	// MessageType for the [GossipCatchup] packet.
+	GossipCatchupType = 504
 )
 
 type VertType interface {
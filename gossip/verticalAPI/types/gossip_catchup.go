@@ -0,0 +1,97 @@
+/*
+ * gossip
+ * Copyright (C) 2024 Fabio Gaiba and Lukas Heindl
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package verticalapi
+
+import (
+	"encoding/binary"
+	"errors"
+	"gossip/common"
+	"slices"
+)
+
+// This type represents a GossipCatchup packet in the verticalApi.
+type GossipCatchup struct {
+	Gc            common.GossipCatchup
+	MessageHeader MessageHeader
+}
+
+// Unmarshals the GossipCatchup packet from the provided buffer.
+//
+// Returns the number of bytes read from the buffer.
+func (e *GossipCatchup) Unmarshal(buf []byte) (int, error) {
+	if e.MessageHeader.Type != GossipCatchupType {
+		return 0, errors.New("wrong type")
+	}
+
+	if len(buf) < e.CalcSize() {
+		return 0, ErrNotEnoughData
+	}
+
+	idx := e.MessageHeader.CalcSize()
+
+	e.Gc.Count = binary.BigEndian.Uint16(buf[idx:])
+	idx += 2
+
+	e.Gc.DataType = common.GossipType(binary.BigEndian.Uint16(buf[idx:]))
+	idx += 2
+
+	e.Gc.MaxAge = binary.BigEndian.Uint32(buf[idx:])
+	idx += 4
+
+	return idx, nil
+}
+
+// Marshals the GossipCatchup packet to the provided buffer.
+func (e *GossipCatchup) Marshal(buf []byte) ([]byte, error) {
+	if e.MessageHeader.Type != GossipCatchupType {
+		return nil, errors.New("wrong type")
+	}
+
+	buf = slices.Grow(buf, e.CalcSize())
+	buf = buf[:e.CalcSize()]
+
+	if err := e.MessageHeader.Marshal(buf); err != nil {
+		return nil, err
+	}
+
+	idx := e.MessageHeader.CalcSize()
+
+	binary.BigEndian.PutUint16(buf[idx:], e.Gc.Count)
+	idx += 2
+
+	binary.BigEndian.PutUint16(buf[idx:], uint16(e.Gc.DataType))
+	idx += 2
+
+	binary.BigEndian.PutUint32(buf[idx:], e.Gc.MaxAge)
+	idx += 4
+
+	return buf, nil
+}
+
+// Returns the size of the GossipCatchup packet.
+func (e *GossipCatchup) CalcSize() int {
+	s := e.MessageHeader.CalcSize()
+	s += binary.Size(e.Gc.Count)
+	s += binary.Size(e.Gc.DataType)
+	s += binary.Size(e.Gc.MaxAge)
+	return s
+}
+
+// Mark this type as vertical type
+func (e *GossipCatchup) isVertType() {}
@@ -0,0 +1,72 @@
+/*
+ * gossip
+ * Copyright (C) 2024 Fabio Gaiba and Lukas Heindl
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package verticalapi
+
+import (
+	"gossip/common"
+	"slices"
+	"testing"
+)
+
+func TestUnmarshalGossipCatchup(t *testing.T) {
+	result := GossipCatchup{
+		common.GossipCatchup{
+			Count:    10,
+			DataType: common.GossipType(17477),
+			MaxAge:   300,
+		},
+		MessageHeader{12, MessageType(504)},
+	}
+	sample := []byte{0, 12, 1, 248, 0, 10, 68, 69, 0, 0, 1, 44}
+	wrongType := []byte{0, 12, 1, 245, 0, 10, 68, 69, 0, 0, 1, 44}
+	smallBuf := []byte{0, 12, 1, 248, 0, 10, 68, 69, 0, 0, 1}
+	var e GossipCatchup
+
+	e.MessageHeader.Unmarshal(wrongType)
+	_, err := e.Unmarshal(wrongType)
+	if err == nil {
+		t.Fatalf("Unmarshal did not detect wrong message type")
+	}
+
+	e.MessageHeader.Unmarshal(smallBuf)
+	_, err = e.Unmarshal(smallBuf)
+
+	if err != ErrNotEnoughData {
+		t.Fatalf("Unmarshal did not detect to small buffer")
+	}
+
+	e.MessageHeader.Unmarshal(sample)
+	_, err = e.Unmarshal(sample)
+
+	if err != nil {
+		t.Fatalf("Unmarshal threw an error on a valid input")
+	}
+
+	if result != e {
+		t.Fatal("Unmarshal result different than expected")
+	}
+
+	buf, err := e.Marshal(nil)
+	if err != nil {
+		t.Fatalf("Marshal threw an error on a valid input")
+	}
+	if !slices.Equal(buf, sample) {
+		t.Fatalf("Marshal result different than expected: %v", buf)
+	}
+}
@@ -47,8 +47,8 @@ type VerticalApi struct {
 	cancel context.CancelFunc
 	// internally uses a context to signal when the goroutines shall terminate
 	ctx context.Context
-	// store the listener so that it can be closed in the end
-	ln net.Listener
+	// store the listeners so that they can be closed in the end
+	lns []net.Listener
 	// store all open connections so that they can be closed in the end
 	conns      map[net.Conn]struct{}
 	connsMutex sync.Mutex
@@ -75,7 +75,7 @@ func NewVerticalApi(log *slog.Logger, vertToMainChan chan<- common.FromVert) *Ve
 	return &VerticalApi{
 		cancel:         cancel,
 		ctx:            ctx,
-		ln:             nil,
+		lns:            nil,
 		conns:          make(map[net.Conn]struct{}, 0),
 		vertToMainChan: vertToMainChan,
 		log:            log.With("module", "vertAPI"),
@@ -85,13 +85,14 @@ func NewVerticalApi(log *slog.Logger, vertToMainChan chan<- common.FromVert) *Ve
 // Listen on the specified address for incoming vertical api connections.
 //
 // This function spawns a new goroutine accepting new connections and
-// terminates afterwards.
+// terminates afterwards. It can be called multiple times to listen on multiple
+// addresses (e.g. IPv4 and IPv6).
 func (v *VerticalApi) Listen(addr string, initFinished chan<- struct{}) error {
-	var err error
-	v.ln, err = net.Listen("tcp", addr)
+	ln, err := net.Listen("tcp", addr)
 	if err != nil {
 		return fmt.Errorf("listen to port for vertical API: %w", err)
 	}
+	v.lns = append(v.lns, ln)
 
 	initFinished <- struct{}{}
 
@@ -100,9 +101,12 @@ func (v *VerticalApi) Listen(addr string, initFinished chan<- struct{}) error {
 	go func() {
 		defer v.wg.Done()
 		for {
-			conn, err := v.ln.Accept()
+			conn, err := ln.Accept()
 			if err != nil {
 				// check if shall terminate
+				if errors.Is(err, net.ErrClosed) {
+					return
+				}
 				select {
 				case <-v.ctx.Done():
 					return
@@ -236,6 +240,20 @@ func (v *VerticalApi) handleConnection(conn net.Conn, regMod common.Conn[common.
 				}
 			}
 
+		case vertTypes.GossipCatchupType:
+			var gc vertTypes.GossipCatchup
+			gc.MessageHeader = msgHdr
+			_, err = gc.Unmarshal(buf)
+			if err != nil {
+				v.log.Warn("Invalid GossipCatchup read", "err", err)
+				continue
+			} else {
+				v.vertToMainChan <- common.GossipCatchupRequest{
+					Data:   gc.Gc,
+					Module: &regMod,
+				}
+			}
+
 		case vertTypes.GossipValidationType:
 			var gv vertTypes.GossipValidation
 			gv.MessageHeader = msgHdr
@@ -299,6 +317,25 @@ func (v *VerticalApi) writeToConnection(conn net.Conn, cData common.Conn[<-chan
 	}
 }
 
+// Returns the amount of currently connected modules
+func (v *VerticalApi) Connections() int {
+	v.connsMutex.Lock()
+	defer v.connsMutex.Unlock()
+	return len(v.conns)
+}
+
+// Stop accepting new connections. The existing connections are kept until
+// [VerticalApi.Close] is called.
+func (v *VerticalApi) StopListening() error {
+	var err error
+	for _, ln := range v.lns {
+		if e := ln.Close(); e != nil && !errors.Is(e, net.ErrClosed) {
+			err = e
+		}
+	}
+	return err
+}
+
 // Close the vertical api
 //
 // Always tries to close the listener and all the connections. If multiple
@@ -307,8 +344,8 @@ func (v *VerticalApi) Close() error {
 	var err error
 	// signal to listener and connection goroutines that they should terminate
 	v.cancel()
-	// interrupt accept of the listener routine
-	if e := v.ln.Close(); e != nil {
+	// interrupt accept of the listener routines
+	if e := v.StopListening(); e != nil {
 		err = e
 	}
 	v.connsMutex.Lock()
@@ -84,6 +84,15 @@ func TestHandleConnection(test *testing.T) {
 			buf:  []byte{0x0, 0x08, 0x01, 0xf7, 0x05, 0x39, 0, 0},
 			name: "validation",
 		},
+		{
+			msg: common.GossipCatchup{
+				Count:    5,
+				DataType: 42,
+				MaxAge:   60,
+			},
+			buf:  []byte{0x0, 0x0c, 0x01, 0xf8, 0x0, 0x05, 0x0, 0x2a, 0, 0, 0, 60},
+			name: "catchup",
+		},
 	}
 
 	// run one test for each message that should be received -> those can run
@@ -146,6 +155,14 @@ func TestHandleConnection(test *testing.T) {
 					if !reflect.DeepEqual(x, t) {
 						test.Fatalf("handler didn't receive the sent message correctly. Was %+v should %+v", x, t)
 					}
+				case common.GossipCatchupRequest:
+					t, ok := t.msg.(common.GossipCatchup)
+					if !ok {
+						test.Fatalf("handler sent to wrong channel")
+					}
+					if !reflect.DeepEqual(x.Data, t) {
+						test.Fatalf("handler didn't receive the sent message correctly. Was %+v should %+v", x.Data, t)
+					}
 				case common.GossipRegister:
 					t, ok := t.msg.(common.GossipNotify)
 					if !ok {